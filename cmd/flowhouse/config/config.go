@@ -6,6 +6,9 @@ import (
 	"github.com/bio-routing/bio-rd/routingtable/vrf"
 	"github.com/bio-routing/flowhouse/pkg/clickhousegw"
 	"github.com/bio-routing/flowhouse/pkg/frontend"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 
@@ -15,21 +18,61 @@ import (
 const (
 	listenSFlowDefault = ":6343"
 	listenHTTPDefault  = ":9991"
+
+	// ipfixTemplateTimeoutDefault is the IPFIX template lifetime in seconds
+	ipfixTemplateTimeoutDefault = 1800
+
+	// aggregationWindowDefault is the length of aggregation windows in seconds
+	aggregationWindowDefault = 10
+
+	// TunnelPrimaryKeyInner makes the inner packet of decapsulated tunnels the primary flow key
+	TunnelPrimaryKeyInner = "inner"
+
+	// TunnelPrimaryKeyOuter makes the outer packet of decapsulated tunnels the primary flow key
+	TunnelPrimaryKeyOuter = "outer"
 )
 
 // Config represents a config file
 type Config struct {
-	RISTimeout         uint64      `yaml:"ris_timeout"`
-	SNMP               *SNMPConfig `yaml:"snmp"`
-	DefaultVRF         string      `yaml:"default_vrf"`
-	defaultVRF         uint64
-	ListenSFlow        string                         `yaml:"listen_sflow"`
-	ListenIPFIX        string                         `yaml:"listen_ipfix"`
-	ListenHTTP         string                         `yaml:"listen_http"`
-	Dicts              frontend.Dicts                 `yaml:"dicts"`
-	Clickhouse         *clickhousegw.ClickhouseConfig `yaml:"clickhouse"`
-	Routers            []*Router                      `yaml:"routers"`
-	DisableIPAnnotator bool                           `yaml:"disable_ip_annotator"`
+	RISTimeout           uint64      `yaml:"ris_timeout"`
+	SNMP                 *SNMPConfig `yaml:"snmp"`
+	DefaultVRF           string      `yaml:"default_vrf"`
+	defaultVRF           uint64
+	ListenSFlow          string                         `yaml:"listen_sflow"`
+	ListenIPFIX          string                         `yaml:"listen_ipfix"`
+	ListenNetflowV9      string                         `yaml:"listen_netflow_v9"`
+	ListenNetflowV5      string                         `yaml:"listen_netflow_v5"`
+	ListenHTTP           string                         `yaml:"listen_http"`
+	Dicts                frontend.Dicts                 `yaml:"dicts"`
+	Clickhouse           *clickhousegw.ClickhouseConfig `yaml:"clickhouse"`
+	Routers              []*Router                      `yaml:"routers"`
+	DisableIPAnnotator   bool                           `yaml:"disable_ip_annotator"`
+	IPFIXElements        []*ipfix.EnterpriseElement     `yaml:"ipfix_enterprise_elements"`
+	IPFIXTemplateTimeout uint64                         `yaml:"ipfix_template_timeout"`
+	IPFIXSnapshotFile    string                         `yaml:"ipfix_snapshot_file"`
+	IPFIXSnapshotMaxAge  uint64                         `yaml:"ipfix_snapshot_max_age"`
+	SflowDecapTunnels    bool                           `yaml:"sflow_tunnel_decapsulation"`
+	SflowTunnelPrimary   string                         `yaml:"sflow_tunnel_primary_key"`
+	SflowAgentIdentity   string                         `yaml:"sflow_agent_identity"`
+	StoreLossRatio       bool                           `yaml:"store_loss_ratio"`
+	KnownAgentsOnly      bool                           `yaml:"known_agents_only"`
+	AgentRateLimit       float64                        `yaml:"agent_rate_limit"`
+	AgentRateLimitBurst  float64                        `yaml:"agent_rate_limit_burst"`
+	UDPSockets           int                            `yaml:"udp_sockets"`
+	UDPReceiveBuffer     int                            `yaml:"udp_receive_buffer"`
+	UDPBatchSize         int                            `yaml:"udp_batch_size"`
+	AggregationWindow    uint64                         `yaml:"aggregation_window"`
+	AggregationKey       []string                       `yaml:"aggregation_key"`
+	AggregationLateness  uint64                         `yaml:"aggregation_lateness"`
+	DropLateFlows        bool                           `yaml:"drop_late_flows"`
+	AggregationShards    int                            `yaml:"aggregation_shards"`
+	AnnotationWorkers    int                            `yaml:"annotation_workers"`
+	QueueLength          int                            `yaml:"queue_length"`
+	InsertBatchSize      int                            `yaml:"insert_batch_size"`
+	InsertBatchMaxAge    uint64                         `yaml:"insert_batch_max_age"`
+	SpoolDir             string                         `yaml:"spool_dir"`
+	SpoolMaxBytes        int64                          `yaml:"spool_max_bytes"`
+	SpoolMaxAge          uint64                         `yaml:"spool_max_age"`
 }
 
 type SNMPConfig struct {
@@ -53,6 +96,30 @@ func (c *Config) load() error {
 		c.ListenHTTP = listenHTTPDefault
 	}
 
+	if c.IPFIXTemplateTimeout == 0 {
+		c.IPFIXTemplateTimeout = ipfixTemplateTimeoutDefault
+	}
+
+	if c.IPFIXSnapshotMaxAge == 0 {
+		c.IPFIXSnapshotMaxAge = c.IPFIXTemplateTimeout
+	}
+
+	if c.AggregationWindow == 0 {
+		c.AggregationWindow = aggregationWindowDefault
+	}
+
+	if c.AggregationKey == nil {
+		c.AggregationKey = aggregator.DefaultKeyFields
+	}
+
+	if c.SflowTunnelPrimary == "" {
+		c.SflowTunnelPrimary = TunnelPrimaryKeyInner
+	}
+
+	if c.SflowAgentIdentity == "" {
+		c.SflowAgentIdentity = sflow.AgentIdentitySource
+	}
+
 	if c.DefaultVRF != "" {
 		vrfID, err := vrf.ParseHumanReadableRouteDistinguisher(c.DefaultVRF)
 		if err != nil {
@@ -143,6 +210,24 @@ func (c *Config) Validate() error {
 	if c.Clickhouse.Sharded && c.Clickhouse.Cluster == "" {
 		return errors.New("cluster must be set when Clickhouse is replicated")
 	}
+
+	switch c.SflowTunnelPrimary {
+	case "", TunnelPrimaryKeyInner, TunnelPrimaryKeyOuter:
+	default:
+		return errors.Errorf("sflow_tunnel_primary_key must be %q or %q", TunnelPrimaryKeyInner, TunnelPrimaryKeyOuter)
+	}
+
+	switch c.SflowAgentIdentity {
+	case "", sflow.AgentIdentitySource, sflow.AgentIdentityAgentAddress:
+	default:
+		return errors.Errorf("sflow_agent_identity must be %q or %q", sflow.AgentIdentitySource, sflow.AgentIdentityAgentAddress)
+	}
+
+	_, err := aggregator.ParseKeyFields(c.AggregationKey)
+	if err != nil {
+		return errors.Wrap(err, "Invalid aggregation_key")
+	}
+
 	return nil
 }
 
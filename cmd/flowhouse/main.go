@@ -2,7 +2,10 @@ package main
 
 import (
 	"flag"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/bio-routing/flowhouse/cmd/flowhouse/config"
@@ -32,15 +35,43 @@ func main() {
 	}
 
 	fhcfg := &flowhouse.Config{
-		ChCfg:              cfg.Clickhouse,
-		SNMP:               cfg.SNMP,
-		RISTimeout:         time.Duration(cfg.RISTimeout) * time.Second,
-		ListenSflow:        cfg.ListenSFlow,
-		ListenIPFIX:        cfg.ListenIPFIX,
-		ListenHTTP:         cfg.ListenHTTP,
-		DefaultVRF:         cfg.GetDefaultVRF(),
-		Dicts:              cfg.Dicts,
-		DisableIPAnnotator: cfg.DisableIPAnnotator,
+		ChCfg:                cfg.Clickhouse,
+		SNMP:                 cfg.SNMP,
+		RISTimeout:           time.Duration(cfg.RISTimeout) * time.Second,
+		ListenSflow:          cfg.ListenSFlow,
+		ListenIPFIX:          cfg.ListenIPFIX,
+		ListenNetflowV9:      cfg.ListenNetflowV9,
+		ListenNetflowV5:      cfg.ListenNetflowV5,
+		ListenHTTP:           cfg.ListenHTTP,
+		DefaultVRF:           cfg.GetDefaultVRF(),
+		Dicts:                cfg.Dicts,
+		DisableIPAnnotator:   cfg.DisableIPAnnotator,
+		IPFIXElements:        cfg.IPFIXElements,
+		IPFIXTemplateTimeout: time.Duration(cfg.IPFIXTemplateTimeout) * time.Second,
+		IPFIXSnapshotFile:    cfg.IPFIXSnapshotFile,
+		IPFIXSnapshotMaxAge:  time.Duration(cfg.IPFIXSnapshotMaxAge) * time.Second,
+		SflowDecapTunnels:    cfg.SflowDecapTunnels,
+		SflowTunnelInner:     cfg.SflowTunnelPrimary == config.TunnelPrimaryKeyInner,
+		SflowAgentIdentity:   cfg.SflowAgentIdentity,
+		StoreLossRatio:       cfg.StoreLossRatio,
+		KnownAgentsOnly:      cfg.KnownAgentsOnly,
+		AgentRateLimit:       cfg.AgentRateLimit,
+		AgentRateLimitBurst:  cfg.AgentRateLimitBurst,
+		UDPSockets:           cfg.UDPSockets,
+		UDPReceiveBuffer:     cfg.UDPReceiveBuffer,
+		UDPBatchSize:         cfg.UDPBatchSize,
+		AggregationWindow:    time.Duration(cfg.AggregationWindow) * time.Second,
+		AggregationKey:       cfg.AggregationKey,
+		AggregationLateness:  time.Duration(cfg.AggregationLateness) * time.Second,
+		DropLateFlows:        cfg.DropLateFlows,
+		AggregationShards:    cfg.AggregationShards,
+		AnnotationWorkers:    cfg.AnnotationWorkers,
+		QueueLength:          cfg.QueueLength,
+		InsertBatchSize:      cfg.InsertBatchSize,
+		InsertBatchMaxAge:    time.Duration(cfg.InsertBatchMaxAge) * time.Second,
+		SpoolDir:             cfg.SpoolDir,
+		SpoolMaxBytes:        cfg.SpoolMaxBytes,
+		SpoolMaxAge:          time.Duration(cfg.SpoolMaxAge) * time.Second,
 	}
 
 	fh, err := flowhouse.New(fhcfg)
@@ -59,5 +90,11 @@ func main() {
 		fh.Run()
 	}()
 
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+	log.WithField("signal", sig.String()).Info("Shutting down")
+
+	fh.Stop()
 	wg.Wait()
 }
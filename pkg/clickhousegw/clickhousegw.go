@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
 	"github.com/pkg/errors"
 
@@ -16,7 +17,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-const tableName = "flows"
+const (
+	tableName                  = "flows"
+	interfaceCountersTableName = "interface_counters"
+)
 
 // ClickHouseGateway is a wrapper for Clickhouse
 type ClickHouseGateway struct {
@@ -64,6 +68,11 @@ func New(cfg *ClickhouseConfig) (*ClickHouseGateway, error) {
 		log.Errorf("Unable to create flows schema: %v", err)
 	}
 
+	err = chgw.createInterfaceCountersSchemaIfNotExists()
+	if err != nil {
+		log.Errorf("Unable to create interface counters schema: %v", err)
+	}
+
 	return chgw, nil
 }
 
@@ -82,9 +91,71 @@ func (c *ClickHouseGateway) createFlowsSchemaIfNotExists() error {
 		return errors.Wrap(err, "Query failed")
 	}
 
+	for _, q := range c.getAddColumnsDDL() {
+		_, err = c.db.Exec(q)
+		if err != nil {
+			return errors.Wrap(err, "Unable to add column")
+		}
+	}
+
 	return nil
 }
 
+// addedColumns are columns that were added to the flows table after its initial release
+var addedColumns = []struct {
+	name    string
+	colType string
+}{
+	{name: "application", colType: "String"},
+	{name: "vrf_name_in", colType: "String"},
+	{name: "vrf_name_out", colType: "String"},
+	{name: "as_path", colType: "Array(UInt32)"},
+	{name: "communities", colType: "Array(UInt32)"},
+	{name: "mpls_top_label", colType: "UInt32"},
+	{name: "mpls_bos_label", colType: "UInt32"},
+	{name: "tunnel_type", colType: "UInt8"},
+	{name: "tunnel_key", colType: "UInt32"},
+	{name: "outer_src_addr", colType: "IPv6"},
+	{name: "outer_dst_addr", colType: "IPv6"},
+	{name: "outer_src_port", colType: "UInt16"},
+	{name: "outer_dst_port", colType: "UInt16"},
+	{name: "outer_protocol", colType: "UInt8"},
+	{name: "inner_src_addr", colType: "IPv6"},
+	{name: "inner_dst_addr", colType: "IPv6"},
+	{name: "inner_src_port", colType: "UInt16"},
+	{name: "inner_dst_port", colType: "UInt16"},
+	{name: "inner_protocol", colType: "UInt8"},
+	{name: "tcp_flags", colType: "UInt8"},
+	{name: "icmp_type", colType: "UInt8"},
+	{name: "icmp_code", colType: "UInt8"},
+	{name: "fragment", colType: "UInt8"},
+	{name: "vlan_in", colType: "UInt16"},
+	{name: "vlan_out", colType: "UInt16"},
+	{name: "vlan_outer", colType: "UInt16"},
+	{name: "vlan_inner", colType: "UInt16"},
+	{name: "loss_ratio", colType: "Float32"},
+	{name: "sub_agent_id", colType: "UInt32"},
+}
+
+// getAddColumnsDDL gets the statements to add missing columns to flows tables created by earlier versions
+func (c *ClickHouseGateway) getAddColumnsDDL() []string {
+	tables := []string{c.getBaseTableName(tableName)}
+	onClusterStatement := ""
+	if c.cfg.Sharded {
+		tables = append(tables, tableName)
+		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
+	}
+
+	res := make([]string, 0, len(tables)*len(addedColumns))
+	for _, t := range tables {
+		for _, col := range addedColumns {
+			res = append(res, fmt.Sprintf("ALTER TABLE %s%s ADD COLUMN IF NOT EXISTS %s %s", t, onClusterStatement, col.name, col.colType))
+		}
+	}
+
+	return res
+}
+
 func (c *ClickHouseGateway) getCreateTableSchemaDDL(isBaseTable bool, zookeeperPathPrefix int64) string {
 	tableDDl := `
 		CREATE TABLE IF NOT EXISTS %s%s (
@@ -109,7 +180,36 @@ func (c *ClickHouseGateway) getCreateTableSchemaDDL(isBaseTable bool, zookeeperP
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			application     String,
+			vrf_name_in     String,
+			vrf_name_out    String,
+			as_path         Array(UInt32),
+			communities     Array(UInt32),
+			mpls_top_label  UInt32,
+			mpls_bos_label  UInt32,
+			tunnel_type     UInt8,
+			tunnel_key      UInt32,
+			outer_src_addr  IPv6,
+			outer_dst_addr  IPv6,
+			outer_src_port  UInt16,
+			outer_dst_port  UInt16,
+			outer_protocol  UInt8,
+			inner_src_addr  IPv6,
+			inner_dst_addr  IPv6,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			inner_protocol  UInt8,
+			tcp_flags       UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			fragment        UInt8,
+			vlan_in         UInt16,
+			vlan_out        UInt16,
+			vlan_outer      UInt16,
+			vlan_inner      UInt16,
+			loss_ratio      Float32,
+			sub_agent_id    UInt32
 		) ENGINE = %s
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -123,38 +223,105 @@ func (c *ClickHouseGateway) getCreateTableSchemaDDL(isBaseTable bool, zookeeperP
 	}
 
 	if isBaseTable {
-		return fmt.Sprintf(tableDDl, c.getBaseTableName(), onClusterStatement, c.getBaseTableEngineDDL(zookeeperPathPrefix), ttl)
+		return fmt.Sprintf(tableDDl, c.getBaseTableName(tableName), onClusterStatement, c.getBaseTableEngineDDL(tableName, zookeeperPathPrefix), ttl)
 	} else {
-		return fmt.Sprintf(tableDDl, tableName, onClusterStatement, c.getDistributedTableDDl(), "")
+		return fmt.Sprintf(tableDDl, tableName, onClusterStatement, c.getDistributedTableDDl(tableName), "")
+	}
+}
+
+func (c *ClickHouseGateway) createInterfaceCountersSchemaIfNotExists() error {
+	zookeeperPathTimestamp := time.Now().Unix()
+	_, err := c.db.Exec(c.getCreateInterfaceCountersTableDDL(true, zookeeperPathTimestamp))
+	if err != nil {
+		return errors.Wrap(err, "Query failed")
+	}
+
+	if c.cfg.Sharded {
+		_, err = c.db.Exec(c.getCreateInterfaceCountersTableDDL(false, zookeeperPathTimestamp))
+	}
+	if err != nil {
+		return errors.Wrap(err, "Query failed")
+	}
+
+	return nil
+}
+
+func (c *ClickHouseGateway) getCreateInterfaceCountersTableDDL(isBaseTable bool, zookeeperPathPrefix int64) string {
+	tableDDl := `
+		CREATE TABLE IF NOT EXISTS %s%s (
+			agent                 IPv6,
+			timestamp             DateTime,
+			if_index              UInt32,
+			int_name              String,
+			if_type               UInt32,
+			if_speed              UInt64,
+			if_direction          UInt32,
+			if_status             UInt32,
+			in_octets             UInt64,
+			in_ucast_pkts         UInt32,
+			in_multicast_pkts     UInt32,
+			in_broadcast_pkts     UInt32,
+			in_discards           UInt32,
+			in_errors             UInt32,
+			in_unknown_protos     UInt32,
+			out_octets            UInt64,
+			out_ucast_pkts        UInt32,
+			out_multicast_pkts    UInt32,
+			out_broadcast_pkts    UInt32,
+			out_discards          UInt32,
+			out_errors            UInt32,
+			alignment_errors      UInt32,
+			fcs_errors            UInt32,
+			single_collisions     UInt32,
+			multiple_collisions   UInt32,
+			late_collisions       UInt32,
+			frame_too_longs       UInt32,
+			symbol_errors         UInt32
+		) ENGINE = %s
+		PARTITION BY toDate(timestamp)
+		ORDER BY (agent, if_index, timestamp)
+		%s
+		SETTINGS index_granularity = 8192
+	`
+	ttl := "TTL timestamp + INTERVAL 14 DAY"
+	onClusterStatement := ""
+	if c.cfg.Sharded {
+		onClusterStatement = " ON CLUSTER " + c.cfg.Cluster
+	}
+
+	if isBaseTable {
+		return fmt.Sprintf(tableDDl, c.getBaseTableName(interfaceCountersTableName), onClusterStatement, c.getBaseTableEngineDDL(interfaceCountersTableName, zookeeperPathPrefix), ttl)
 	}
+
+	return fmt.Sprintf(tableDDl, interfaceCountersTableName, onClusterStatement, c.getDistributedTableDDl(interfaceCountersTableName), "")
 }
 
-func (c *ClickHouseGateway) getBaseTableName() string {
+func (c *ClickHouseGateway) getBaseTableName(table string) string {
 	if c.cfg.Sharded {
-		return "_" + c.cfg.Database + "." + tableName + "_base"
+		return "_" + c.cfg.Database + "." + table + "_base"
 	}
 
-	return tableName
+	return table
 }
 
-func (c *ClickHouseGateway) getBaseTableEngineDDL(zookeeperPathPrefix int64) string {
+func (c *ClickHouseGateway) getBaseTableEngineDDL(table string, zookeeperPathPrefix int64) string {
 	if c.cfg.Sharded {
 		// TODO: make zookeeper path configurable
 		return fmt.Sprintf(
 			"ReplicatedMergeTree('/clickhouse/tables/{shard}/%s/%s_%d', '{replica}')",
 			c.cfg.Database,
-			tableName,
+			table,
 			zookeeperPathPrefix)
 	}
 
 	return "MergeTree()"
 }
-func (c *ClickHouseGateway) getDistributedTableDDl() string {
+func (c *ClickHouseGateway) getDistributedTableDDl(table string) string {
 	return fmt.Sprintf(
 		"Distributed(%s, %s, %s, %s)",
 		c.cfg.Cluster,
 		"_"+c.cfg.Database,
-		tableName+"_base",
+		table+"_base",
 		"rand()")
 }
 
@@ -187,8 +354,37 @@ func (c *ClickHouseGateway) InsertFlows(flows []*flow.Flow) error {
 		timestamp, 
 		size, 
 		packets, 
-		samplerate
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? , ?, ?, ?)`)
+		samplerate,
+		application,
+		vrf_name_in,
+		vrf_name_out,
+		as_path,
+		communities,
+		mpls_top_label,
+		mpls_bos_label,
+		tunnel_type,
+		tunnel_key,
+		outer_src_addr,
+		outer_dst_addr,
+		outer_src_port,
+		outer_dst_port,
+		outer_protocol,
+		inner_src_addr,
+		inner_dst_addr,
+		inner_src_port,
+		inner_dst_port,
+		inner_protocol,
+		tcp_flags,
+		icmp_type,
+		icmp_code,
+		fragment,
+		vlan_in,
+		vlan_out,
+		vlan_outer,
+		vlan_inner,
+		loss_ratio,
+		sub_agent_id
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? , ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	defer stmt.Close()
 	if err != nil {
 		return errors.Wrap(err, "Prepare failed")
@@ -218,6 +414,121 @@ func (c *ClickHouseGateway) InsertFlows(flows []*flow.Flow) error {
 			fl.Size,
 			fl.Packets,
 			fl.Samplerate,
+			fl.Application,
+			fl.VRFNameIn,
+			fl.VRFNameOut,
+			fl.ASPath,
+			fl.Communities,
+			fl.MPLSTopLabel,
+			fl.MPLSBottomLabel,
+			fl.TunnelType,
+			fl.TunnelKey,
+			fl.Outer.SrcAddr.ToNetIP(),
+			fl.Outer.DstAddr.ToNetIP(),
+			fl.Outer.SrcPort,
+			fl.Outer.DstPort,
+			fl.Outer.Protocol,
+			fl.Inner.SrcAddr.ToNetIP(),
+			fl.Inner.DstAddr.ToNetIP(),
+			fl.Inner.SrcPort,
+			fl.Inner.DstPort,
+			fl.Inner.Protocol,
+			fl.TCPFlags,
+			fl.ICMPType,
+			fl.ICMPCode,
+			boolToUInt8(fl.Fragment),
+			fl.VLANIn,
+			fl.VLANOut,
+			fl.VLANOuter,
+			fl.VLANInner,
+			fl.LossRatio,
+			fl.SubAgentID,
+		)
+		if err != nil {
+			return errors.Wrap(err, "Exec failed")
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "Commit failed")
+	}
+
+	return nil
+}
+
+// InsertInterfaceCounters inserts interface counters into clickhouse
+func (c *ClickHouseGateway) InsertInterfaceCounters(ifCounters []*counters.InterfaceCounters) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Begin failed")
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO interface_counters (
+		agent,
+		timestamp,
+		if_index,
+		int_name,
+		if_type,
+		if_speed,
+		if_direction,
+		if_status,
+		in_octets,
+		in_ucast_pkts,
+		in_multicast_pkts,
+		in_broadcast_pkts,
+		in_discards,
+		in_errors,
+		in_unknown_protos,
+		out_octets,
+		out_ucast_pkts,
+		out_multicast_pkts,
+		out_broadcast_pkts,
+		out_discards,
+		out_errors,
+		alignment_errors,
+		fcs_errors,
+		single_collisions,
+		multiple_collisions,
+		late_collisions,
+		frame_too_longs,
+		symbol_errors
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return errors.Wrap(err, "Prepare failed")
+	}
+	defer stmt.Close()
+
+	for _, ic := range ifCounters {
+		_, err := stmt.Exec(
+			ic.Agent.ToNetIP(),
+			ic.Timestamp,
+			ic.IfIndex,
+			ic.IntName,
+			ic.IfType,
+			ic.IfSpeed,
+			ic.IfDirection,
+			ic.IfStatus,
+			ic.InOctets,
+			ic.InUcastPkts,
+			ic.InMulticastPkts,
+			ic.InBroadcastPkts,
+			ic.InDiscards,
+			ic.InErrors,
+			ic.InUnknownProtos,
+			ic.OutOctets,
+			ic.OutUcastPkts,
+			ic.OutMulticastPkts,
+			ic.OutBroadcastPkts,
+			ic.OutDiscards,
+			ic.OutErrors,
+			ic.AlignmentErrors,
+			ic.FCSErrors,
+			ic.SingleCollisions,
+			ic.MultipleCollisions,
+			ic.LateCollisions,
+			ic.FrameTooLongs,
+			ic.SymbolErrors,
 		)
 		if err != nil {
 			return errors.Wrap(err, "Exec failed")
@@ -238,6 +549,14 @@ func dscp(tos uint8) uint8 {
 	return tos >> 2
 }
 
+func boolToUInt8(b bool) uint8 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
 func addrToNetIP(addr *bnet.IP) net.IP {
 	if addr == nil {
 		return net.IP([]byte{0, 0, 0, 0})
@@ -3,6 +3,7 @@ package clickhousegw
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,6 +41,8 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			agent           IPv6,
 			int_in          String,
 			int_out         String,
+			tos             UInt8,
+			dscp            UInt8,
 			src_ip_addr     IPv6,
 			dst_ip_addr     IPv6,
 			src_ip_pfx_addr IPv6,
@@ -56,7 +59,36 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			application     String,
+			vrf_name_in     String,
+			vrf_name_out    String,
+			as_path         Array(UInt32),
+			communities     Array(UInt32),
+			mpls_top_label  UInt32,
+			mpls_bos_label  UInt32,
+			tunnel_type     UInt8,
+			tunnel_key      UInt32,
+			outer_src_addr  IPv6,
+			outer_dst_addr  IPv6,
+			outer_src_port  UInt16,
+			outer_dst_port  UInt16,
+			outer_protocol  UInt8,
+			inner_src_addr  IPv6,
+			inner_dst_addr  IPv6,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			inner_protocol  UInt8,
+			tcp_flags       UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			fragment        UInt8,
+			vlan_in         UInt16,
+			vlan_out        UInt16,
+			vlan_outer      UInt16,
+			vlan_inner      UInt16,
+			loss_ratio      Float32,
+			sub_agent_id    UInt32
 		) ENGINE = MergeTree()
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -82,6 +114,8 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			agent           IPv6,
 			int_in          String,
 			int_out         String,
+			tos             UInt8,
+			dscp            UInt8,
 			src_ip_addr     IPv6,
 			dst_ip_addr     IPv6,
 			src_ip_pfx_addr IPv6,
@@ -98,7 +132,36 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			application     String,
+			vrf_name_in     String,
+			vrf_name_out    String,
+			as_path         Array(UInt32),
+			communities     Array(UInt32),
+			mpls_top_label  UInt32,
+			mpls_bos_label  UInt32,
+			tunnel_type     UInt8,
+			tunnel_key      UInt32,
+			outer_src_addr  IPv6,
+			outer_dst_addr  IPv6,
+			outer_src_port  UInt16,
+			outer_dst_port  UInt16,
+			outer_protocol  UInt8,
+			inner_src_addr  IPv6,
+			inner_dst_addr  IPv6,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			inner_protocol  UInt8,
+			tcp_flags       UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			fragment        UInt8,
+			vlan_in         UInt16,
+			vlan_out        UInt16,
+			vlan_outer      UInt16,
+			vlan_inner      UInt16,
+			loss_ratio      Float32,
+			sub_agent_id    UInt32
 		) ENGINE = ReplicatedMergeTree('/clickhouse/tables/{shard}/test/flows_%d', '{replica}')
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -124,6 +187,8 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			agent           IPv6,
 			int_in          String,
 			int_out         String,
+			tos             UInt8,
+			dscp            UInt8,
 			src_ip_addr     IPv6,
 			dst_ip_addr     IPv6,
 			src_ip_pfx_addr IPv6,
@@ -140,7 +205,36 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 			timestamp       DateTime,
 			size            UInt64,
 			packets         UInt64,
-			samplerate      UInt64
+			samplerate      UInt64,
+			application     String,
+			vrf_name_in     String,
+			vrf_name_out    String,
+			as_path         Array(UInt32),
+			communities     Array(UInt32),
+			mpls_top_label  UInt32,
+			mpls_bos_label  UInt32,
+			tunnel_type     UInt8,
+			tunnel_key      UInt32,
+			outer_src_addr  IPv6,
+			outer_dst_addr  IPv6,
+			outer_src_port  UInt16,
+			outer_dst_port  UInt16,
+			outer_protocol  UInt8,
+			inner_src_addr  IPv6,
+			inner_dst_addr  IPv6,
+			inner_src_port  UInt16,
+			inner_dst_port  UInt16,
+			inner_protocol  UInt8,
+			tcp_flags       UInt8,
+			icmp_type       UInt8,
+			icmp_code       UInt8,
+			fragment        UInt8,
+			vlan_in         UInt16,
+			vlan_out        UInt16,
+			vlan_outer      UInt16,
+			vlan_inner      UInt16,
+			loss_ratio      Float32,
+			sub_agent_id    UInt32
 		) ENGINE = Distributed(test_cluster, _test, flows_base, rand())
 		PARTITION BY toStartOfTenMinutes(timestamp)
 		ORDER BY (timestamp)
@@ -161,3 +255,54 @@ func TestClickHouseGateway_getCreateTableSchemaDDL(t *testing.T) {
 		})
 	}
 }
+
+func TestClickHouseGateway_getAddColumnsDDL(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Database: "test",
+			Cluster:  "test_cluster",
+			Sharded:  true,
+		},
+	}
+
+	got := c.getAddColumnsDDL()
+	if len(got) != 2*len(addedColumns) {
+		t.Fatalf("unexpected number of statements: %d", len(got))
+	}
+
+	want := "ALTER TABLE _test.flows_base ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS application String"
+	if got[0] != want {
+		t.Errorf("getAddColumnsDDL()[0] = %v, want %v", got[0], want)
+	}
+
+	want = "ALTER TABLE flows ON CLUSTER test_cluster ADD COLUMN IF NOT EXISTS application String"
+	if got[len(addedColumns)] != want {
+		t.Errorf("getAddColumnsDDL()[%d] = %v, want %v", len(addedColumns), got[len(addedColumns)], want)
+	}
+}
+
+func TestClickHouseGateway_getCreateInterfaceCountersTableDDL(t *testing.T) {
+	c := &ClickHouseGateway{
+		cfg: &ClickhouseConfig{
+			Database: "test",
+			Cluster:  "test_cluster",
+			Sharded:  true,
+		},
+	}
+
+	base := c.getCreateInterfaceCountersTableDDL(true, 42)
+	if !strings.Contains(base, "CREATE TABLE IF NOT EXISTS _test.interface_counters_base ON CLUSTER test_cluster (") {
+		t.Errorf("unexpected base table DDL: %v", base)
+	}
+	if !strings.Contains(base, "ReplicatedMergeTree('/clickhouse/tables/{shard}/test/interface_counters_42', '{replica}')") {
+		t.Errorf("unexpected base table engine: %v", base)
+	}
+
+	dist := c.getCreateInterfaceCountersTableDDL(false, 42)
+	if !strings.Contains(dist, "CREATE TABLE IF NOT EXISTS interface_counters ON CLUSTER test_cluster (") {
+		t.Errorf("unexpected distributed table DDL: %v", dist)
+	}
+	if !strings.Contains(dist, "Distributed(test_cluster, _test, interface_counters_base, rand())") {
+		t.Errorf("unexpected distributed table engine: %v", dist)
+	}
+}
@@ -13,11 +13,19 @@ import (
 	"github.com/bio-routing/flowhouse/pkg/frontend"
 	"github.com/bio-routing/flowhouse/pkg/intfmapper"
 	"github.com/bio-routing/flowhouse/pkg/ipannotator"
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
 	"github.com/bio-routing/flowhouse/pkg/routemirror"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
 	"github.com/bio-routing/flowhouse/pkg/servers/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/nf5"
+	"github.com/bio-routing/flowhouse/pkg/servers/nf9"
 	"github.com/bio-routing/flowhouse/pkg/servers/sflow"
+	"github.com/bio-routing/flowhouse/pkg/servers/udpreceiver"
+	"github.com/bio-routing/flowhouse/pkg/spool"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -33,24 +41,92 @@ type Flowhouse struct {
 	routeMirror       *routemirror.RouteMirror
 	grpcClientManager *clientmanager.ClientManager
 	ipa               *ipannotator.IPAnnotator
+	agentFilter       *agentfilter.Filter
 	sfs               *sflow.SflowServer
 	ifxs              *ipfix.IPFIXServer
+	nf9s              *nf9.NetflowV9Server
+	nf5s              *nf5.NetflowV5Server
 	chgw              *clickhousegw.ClickHouseGateway
 	fe                *frontend.Frontend
+	pipeline          *pipeline
 	flowsRX           chan []*flow.Flow
+	countersRX        chan []*counters.InterfaceCounters
 }
 
 // Config is flow house instances configuration
 type Config struct {
-	ChCfg              *clickhousegw.ClickhouseConfig
-	SNMP               *config.SNMPConfig
-	RISTimeout         time.Duration
-	ListenSflow        string
-	ListenIPFIX        string
-	ListenHTTP         string
-	DefaultVRF         uint64
-	Dicts              frontend.Dicts
-	DisableIPAnnotator bool
+	ChCfg                *clickhousegw.ClickhouseConfig
+	SNMP                 *config.SNMPConfig
+	RISTimeout           time.Duration
+	ListenSflow          string
+	ListenIPFIX          string
+	ListenNetflowV9      string
+	ListenNetflowV5      string
+	ListenHTTP           string
+	DefaultVRF           uint64
+	Dicts                frontend.Dicts
+	DisableIPAnnotator   bool
+	IPFIXElements        []*ipfix.EnterpriseElement
+	IPFIXTemplateTimeout time.Duration
+	IPFIXSnapshotFile    string
+	IPFIXSnapshotMaxAge  time.Duration
+	SflowDecapTunnels    bool
+	SflowTunnelInner     bool
+	SflowAgentIdentity   string
+	StoreLossRatio       bool
+
+	// KnownAgentsOnly rejects datagrams of agents that have not been added using AddAgent
+	KnownAgentsOnly bool
+
+	// AgentRateLimit is the number of datagrams per second accepted per agent. 0 disables rate limiting.
+	AgentRateLimit      float64
+	AgentRateLimitBurst float64
+
+	// UDPSockets is the number of SO_REUSEPORT sockets opened per listener. Defaults to the number of CPUs.
+	UDPSockets int
+
+	// UDPReceiveBuffer is the receive buffer size of each socket in bytes. 0 keeps the system default.
+	UDPReceiveBuffer int
+
+	// UDPBatchSize is the number of datagrams read per system call
+	UDPBatchSize int
+
+	// AggregationWindow is the length of aggregation windows. Defaults to 10 seconds.
+	AggregationWindow time.Duration
+
+	// AggregationKey are the names of the optional fields flows are aggregated by.
+	// nil selects interfaces, TOS and VLAN.
+	AggregationKey []string
+
+	// AggregationLateness is the time aggregation windows are kept open for flows exported late
+	AggregationLateness time.Duration
+
+	// DropLateFlows drops flows arriving after their aggregation window was closed
+	DropLateFlows bool
+
+	// AggregationShards is the number of goroutines aggregating flows per collector. Defaults to the number of CPUs.
+	AggregationShards int
+
+	// AnnotationWorkers is the number of goroutines annotating flows. Defaults to the number of CPUs.
+	AnnotationWorkers int
+
+	// QueueLength is the number of batches of flows queued for annotation and for insertion each
+	QueueLength int
+
+	// InsertBatchSize is the number of flows inserted at once
+	InsertBatchSize int
+
+	// InsertBatchMaxAge is the time after which a batch is inserted regardless of its size
+	InsertBatchMaxAge time.Duration
+
+	// SpoolDir is the directory batches failing to insert are buffered in. Empty disables spooling.
+	SpoolDir string
+
+	// SpoolMaxBytes is the maximum size of the spool. The oldest batches are dropped once it's exceeded.
+	SpoolMaxBytes int64
+
+	// SpoolMaxAge is the time after which spooled batches are dropped
+	SpoolMaxAge time.Duration
 }
 
 // ClickhouseConfig represents a clickhouse client config
@@ -69,37 +145,152 @@ func New(cfg *Config) (*Flowhouse, error) {
 		ifMapper:          intfmapper.New(),
 		routeMirror:       routemirror.New(),
 		grpcClientManager: clientmanager.New(),
-		flowsRX:           make(chan []*flow.Flow, 1024),
 	}
 
+	pcfg := pipelineConfig{
+		workers:     cfg.AnnotationWorkers,
+		queueLength: cfg.QueueLength,
+		batchSize:   cfg.InsertBatchSize,
+		batchMaxAge: cfg.InsertBatchMaxAge,
+	}
+
+	if pcfg.workers <= 0 {
+		pcfg.workers = runtime.NumCPU()
+	}
+
+	if pcfg.queueLength <= 0 {
+		pcfg.queueLength = defaultQueueLength
+	}
+
+	if pcfg.batchSize <= 0 {
+		pcfg.batchSize = defaultBatchSize
+	}
+
+	if pcfg.batchMaxAge <= 0 {
+		pcfg.batchMaxAge = defaultBatchMaxAge
+	}
+
+	var sp *spool.Spool
+	if cfg.SpoolDir != "" {
+		var err error
+		sp, err = spool.New(spool.Config{
+			Dir:      cfg.SpoolDir,
+			MaxBytes: cfg.SpoolMaxBytes,
+			MaxAge:   cfg.SpoolMaxAge,
+		}, prometheus.DefaultRegisterer)
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to create spool")
+		}
+	}
+
+	fh.pipeline = newPipeline(pcfg, fh.annotate, fh.insertFlows, fh.insertInterfaceCounters, sp, prometheus.DefaultRegisterer)
+	fh.flowsRX = fh.pipeline.input
+	fh.countersRX = fh.pipeline.counters
+
 	if !cfg.DisableIPAnnotator {
 		fh.ipa = ipannotator.New(fh.routeMirror)
 	}
 
-	sfs, err := sflow.New(fh.cfg.ListenSflow, runtime.NumCPU(), fh.flowsRX, fh.ifMapper)
+	if cfg.KnownAgentsOnly || cfg.AgentRateLimit > 0 {
+		fh.agentFilter = agentfilter.New(agentfilter.Config{
+			KnownAgentsOnly: cfg.KnownAgentsOnly,
+			RateLimit:       cfg.AgentRateLimit,
+			Burst:           cfg.AgentRateLimitBurst,
+		})
+	}
+
+	numSockets := cfg.UDPSockets
+	if numSockets <= 0 {
+		numSockets = runtime.NumCPU()
+	}
+
+	rcvCfg := udpreceiver.Config{
+		ReceiveBuffer: cfg.UDPReceiveBuffer,
+		BatchSize:     cfg.UDPBatchSize,
+	}
+
+	window := cfg.AggregationWindow
+	if window == 0 {
+		window = aggregator.DefaultWindow
+	}
+
+	aggCfg := aggregator.Config{
+		Window:        window,
+		KeyFields:     cfg.AggregationKey,
+		Lateness:      cfg.AggregationLateness,
+		DropLateFlows: cfg.DropLateFlows,
+		Shards:        cfg.AggregationShards,
+	}
+
+	sfs, err := sflow.New(fh.cfg.ListenSflow, numSockets, fh.flowsRX, fh.countersRX, fh.ifMapper, &sflow.Config{
+		DecapsulateTunnels: fh.cfg.SflowDecapTunnels,
+		TunnelPrimaryInner: fh.cfg.SflowTunnelInner,
+		StoreLossRatio:     fh.cfg.StoreLossRatio,
+		AgentFilter:        fh.agentFilter,
+		AgentIdentity:      fh.cfg.SflowAgentIdentity,
+		Receiver:           rcvCfg,
+		Aggregator:         aggCfg,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to start sflow server")
 	}
 	fh.sfs = sfs
 
-	ifxs, err := ipfix.New(fh.cfg.ListenIPFIX, runtime.NumCPU(), fh.flowsRX, fh.ifMapper)
+	ifxs, err := ipfix.New(fh.cfg.ListenIPFIX, numSockets, fh.flowsRX, fh.ifMapper, &ipfix.Config{
+		EnterpriseElements: fh.cfg.IPFIXElements,
+		TemplateTimeout:    fh.cfg.IPFIXTemplateTimeout,
+		SnapshotFile:       fh.cfg.IPFIXSnapshotFile,
+		SnapshotMaxAge:     fh.cfg.IPFIXSnapshotMaxAge,
+		StoreLossRatio:     fh.cfg.StoreLossRatio,
+		AgentFilter:        fh.agentFilter,
+		Receiver:           rcvCfg,
+		Aggregator:         aggCfg,
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to start IPFIX server")
 	}
 	fh.ifxs = ifxs
 
+	if fh.cfg.ListenNetflowV9 != "" {
+		nf9s, err := nf9.New(fh.cfg.ListenNetflowV9, numSockets, fh.flowsRX, fh.ifMapper, &nf9.Config{
+			AgentFilter: fh.agentFilter,
+			Receiver:    rcvCfg,
+			Aggregator:  aggCfg,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to start NetFlow v9 server")
+		}
+		fh.nf9s = nf9s
+	}
+
+	if fh.cfg.ListenNetflowV5 != "" {
+		nf5s, err := nf5.New(fh.cfg.ListenNetflowV5, numSockets, fh.flowsRX, fh.ifMapper, &nf5.Config{
+			AgentFilter: fh.agentFilter,
+			Receiver:    rcvCfg,
+			Aggregator:  aggCfg,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "Unable to start NetFlow v5 server")
+		}
+		fh.nf5s = nf5s
+	}
+
 	chgw, err := clickhousegw.New(fh.cfg.ChCfg)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to create clickhouse wrapper")
 	}
 	fh.chgw = chgw
 
-	fh.fe = frontend.New(fh.chgw, cfg.Dicts)
+	fh.fe = frontend.New(fh.chgw, cfg.Dicts, window)
 	return fh, nil
 }
 
 // AddAgent adds an agent
 func (f *Flowhouse) AddAgent(name string, addr bnet.IP, risAddrs []string, vrfs []uint64) {
+	if f.agentFilter != nil {
+		f.agentFilter.Add(addr)
+	}
+
 	if f.cfg.SNMP != nil {
 		f.ifMapper.AddDevice(addr, f.cfg.SNMP)
 	}
@@ -126,26 +317,47 @@ func (f *Flowhouse) Run() {
 	go http.ListenAndServe(f.cfg.ListenHTTP, nil)
 	log.WithField("address", f.cfg.ListenHTTP).Info("Listening for HTTP requests")
 
-	for {
-		flows := <-f.flowsRX
+	f.pipeline.run()
+}
 
-		if f.ipa != nil {
-			for _, fl := range flows {
-				fl.VRFIn = f.cfg.DefaultVRF
-				fl.VRFOut = f.cfg.DefaultVRF
+// annotate adds routing information to fl
+func (f *Flowhouse) annotate(fl *flow.Flow) {
+	if f.ipa == nil {
+		return
+	}
 
-				err := f.ipa.Annotate(fl)
-				if err != nil {
-					log.WithError(err).Info("Annotating failed")
-				}
-			}
-		}
+	fl.VRFIn = f.cfg.DefaultVRF
+	fl.VRFOut = f.cfg.DefaultVRF
 
-		err := f.chgw.InsertFlows(flows)
-		if err != nil {
-			log.WithError(err).Error("Insert failed")
-		}
+	err := f.ipa.Annotate(fl)
+	if err != nil {
+		log.WithError(err).Info("Annotating failed")
+	}
+}
+
+func (f *Flowhouse) insertFlows(flows []*flow.Flow) error {
+	return f.chgw.InsertFlows(flows)
+}
+
+// Stop stops the collectors and waits for their aggregators to flush. Run returns once the remaining flows are written.
+func (f *Flowhouse) Stop() {
+	f.sfs.Stop()
+	f.ifxs.Stop()
+
+	if f.nf9s != nil {
+		f.nf9s.Stop()
 	}
+
+	if f.nf5s != nil {
+		f.nf5s.Stop()
+	}
+
+	close(f.flowsRX)
+	close(f.countersRX)
+}
+
+func (f *Flowhouse) insertInterfaceCounters(ifCounters []*counters.InterfaceCounters) error {
+	return f.chgw.InsertInterfaceCounters(ifCounters)
 }
 
 func recoveryMiddleware(next http.Handler) http.Handler {
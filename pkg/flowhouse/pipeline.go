@@ -0,0 +1,380 @@
+package flowhouse
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/spool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQueueLength = 1024
+	defaultBatchSize   = 50000
+	defaultBatchMaxAge = 5 * time.Second
+
+	// replayInterval is the interval spooled batches are retried at in the absence of new batches
+	replayInterval = 10 * time.Second
+)
+
+// pipelineConfig is the configuration of a `pipeline`
+type pipelineConfig struct {
+	workers     int
+	queueLength int
+	batchSize   int
+	batchMaxAge time.Duration
+}
+
+// pipeline annotates the flows emitted by the aggregators using a pool of workers and inserts
+// them in batches. A batch is inserted once it holds batchSize flows or its first flows are
+// batchMaxAge old. Interface counters are batched the same way. Batches failing to insert are
+// written to the spool, if configured, and inserted before any newer batch once the database
+// is reachable again. Spooled batches are replayed by a single goroutine, so the batchers never
+// wait for each other's inserts.
+type pipeline struct {
+	cfg            pipelineConfig
+	input          chan []*flow.Flow
+	annotated      chan []*flow.Flow
+	counters       chan []*counters.InterfaceCounters
+	annotate       func(fl *flow.Flow)
+	insert         func(flows []*flow.Flow) error
+	insertCounters func(ifCounters []*counters.InterfaceCounters) error
+	spool          *spool.Spool
+	replayCh       chan struct{}
+
+	batchSizes          prometheus.Histogram
+	insertDuration      prometheus.Histogram
+	insertErrors        prometheus.Counter
+	counterInsertErrors prometheus.Counter
+}
+
+// newPipeline creates a pipeline. sp may be nil to discard batches failing to insert.
+func newPipeline(cfg pipelineConfig, annotate func(fl *flow.Flow), insert func(flows []*flow.Flow) error, insertCounters func(ifCounters []*counters.InterfaceCounters) error, sp *spool.Spool, reg prometheus.Registerer) *pipeline {
+	p := &pipeline{
+		cfg:            cfg,
+		input:          make(chan []*flow.Flow, cfg.queueLength),
+		annotated:      make(chan []*flow.Flow, cfg.queueLength),
+		counters:       make(chan []*counters.InterfaceCounters, cfg.queueLength),
+		annotate:       annotate,
+		insert:         insert,
+		insertCounters: insertCounters,
+		spool:          sp,
+		replayCh:       make(chan struct{}, 1),
+	}
+
+	f := promauto.With(reg)
+	for name, queue := range map[string]func() (int, int){
+		"received": func() (int, int) {
+			return len(p.input), cap(p.input)
+		},
+		"annotated": func() (int, int) {
+			return len(p.annotated), cap(p.annotated)
+		},
+		"interface_counters": func() (int, int) {
+			return len(p.counters), cap(p.counters)
+		},
+	} {
+		queue := queue
+		f.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "flowhouse",
+			Subsystem:   "pipeline",
+			Name:        "queue_length",
+			Help:        "Batches waiting in the queue",
+			ConstLabels: prometheus.Labels{"queue": name},
+		}, func() float64 {
+			l, _ := queue()
+			return float64(l)
+		})
+		f.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   "flowhouse",
+			Subsystem:   "pipeline",
+			Name:        "queue_capacity",
+			Help:        "Capacity of the queue in batches",
+			ConstLabels: prometheus.Labels{"queue": name},
+		}, func() float64 {
+			_, c := queue()
+			return float64(c)
+		})
+	}
+
+	p.batchSizes = f.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "insert_batch_size",
+		Help:      "Number of flows per insert",
+		Buckets:   prometheus.ExponentialBuckets(10, 4, 8),
+	})
+	p.insertDuration = f.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "insert_duration_seconds",
+		Help:      "Latency of inserts",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+	p.insertErrors = f.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "insert_errors",
+		Help:      "Failed inserts",
+	})
+	p.counterInsertErrors = f.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "pipeline",
+		Name:      "interface_counter_insert_errors",
+		Help:      "Failed inserts of interface counters",
+	})
+
+	return p
+}
+
+// run processes flows and interface counters until the input channels are closed and the remaining
+// flows and interface counters are inserted
+func (p *pipeline) run() {
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.annotationWorker()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.batcher()
+	}()
+
+	countersDone := make(chan struct{})
+	go func() {
+		defer close(countersDone)
+		p.counterBatcher()
+	}()
+
+	stopReplayer := make(chan struct{})
+	replayerDone := make(chan struct{})
+	go func() {
+		defer close(replayerDone)
+		p.replayer(stopReplayer)
+	}()
+
+	wg.Wait()
+	close(p.annotated)
+	<-done
+	<-countersDone
+	close(stopReplayer)
+	<-replayerDone
+}
+
+func (p *pipeline) annotationWorker() {
+	for flows := range p.input {
+		for _, fl := range flows {
+			p.annotate(fl)
+		}
+
+		p.annotated <- flows
+	}
+}
+
+// batcher collects annotated flows into batches and inserts them
+func (p *pipeline) batcher() {
+	batch := make([]*flow.Flow, 0, p.cfg.batchSize)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	for {
+		select {
+		case flows, ok := <-p.annotated:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+
+			if len(batch) == 0 {
+				timer = time.NewTimer(p.cfg.batchMaxAge)
+				timeout = timer.C
+			}
+
+			batch = append(batch, flows...)
+			if len(batch) < p.cfg.batchSize {
+				continue
+			}
+
+			timer.Stop()
+		case <-timeout:
+		}
+
+		p.flush(batch)
+		batch = make([]*flow.Flow, 0, p.cfg.batchSize)
+		timeout = nil
+	}
+}
+
+// counterBatcher collects interface counters into batches and inserts them
+func (p *pipeline) counterBatcher() {
+	batch := make([]*counters.InterfaceCounters, 0, p.cfg.batchSize)
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	for {
+		select {
+		case ifCounters, ok := <-p.counters:
+			if !ok {
+				p.flushCounters(batch)
+				return
+			}
+
+			if len(batch) == 0 {
+				timer = time.NewTimer(p.cfg.batchMaxAge)
+				timeout = timer.C
+			}
+
+			batch = append(batch, ifCounters...)
+			if len(batch) < p.cfg.batchSize {
+				continue
+			}
+
+			timer.Stop()
+		case <-timeout:
+		}
+
+		p.flushCounters(batch)
+		batch = make([]*counters.InterfaceCounters, 0, p.cfg.batchSize)
+		timeout = nil
+	}
+}
+
+func (p *pipeline) flush(batch []*flow.Flow) {
+	if len(batch) == 0 {
+		return
+	}
+
+	p.batchSizes.Observe(float64(len(batch)))
+
+	// Spooled batches are inserted first to keep the order
+	if p.spooled() {
+		p.spoolBatch(batch)
+		p.triggerReplay()
+		return
+	}
+
+	err := p.timedInsert(batch)
+	if err != nil {
+		log.WithError(err).Error("Insert failed")
+		p.spoolBatch(batch)
+	}
+}
+
+func (p *pipeline) timedInsert(batch []*flow.Flow) error {
+	start := time.Now()
+	err := p.insert(batch)
+	p.insertDuration.Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		p.insertErrors.Inc()
+	}
+
+	return err
+}
+
+func (p *pipeline) flushCounters(batch []*counters.InterfaceCounters) {
+	if len(batch) == 0 {
+		return
+	}
+
+	// Spooled batches are inserted first to keep the order
+	if p.spooled() {
+		p.spoolCounters(batch)
+		p.triggerReplay()
+		return
+	}
+
+	err := p.countedInsertCounters(batch)
+	if err != nil {
+		log.WithError(err).Error("Insert of interface counters failed")
+		p.spoolCounters(batch)
+	}
+}
+
+func (p *pipeline) countedInsertCounters(batch []*counters.InterfaceCounters) error {
+	err := p.insertCounters(batch)
+	if err != nil {
+		p.counterInsertErrors.Inc()
+	}
+
+	return err
+}
+
+// spooled checks if there are batches waiting in the spool
+func (p *pipeline) spooled() bool {
+	return p.spool != nil && !p.spool.Empty()
+}
+
+// triggerReplay wakes up the replayer
+func (p *pipeline) triggerReplay() {
+	select {
+	case p.replayCh <- struct{}{}:
+	default:
+	}
+}
+
+// replayer replays the spool when triggered and periodically until stopCh is closed
+func (p *pipeline) replayer(stopCh chan struct{}) {
+	t := time.NewTicker(replayInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			p.replay()
+		case <-p.replayCh:
+			p.replay()
+		case <-stopCh:
+			p.replay()
+			return
+		}
+	}
+}
+
+// replay inserts the spooled batches. Returns false if batches are left in the spool.
+func (p *pipeline) replay() bool {
+	if p.spool == nil || p.spool.Empty() {
+		return true
+	}
+
+	err := p.spool.Replay(p.timedInsert, p.countedInsertCounters)
+	if err != nil {
+		log.WithError(err).Warning("Unable to replay spooled batches")
+		return false
+	}
+
+	log.Info("Replayed spooled batches")
+	return true
+}
+
+func (p *pipeline) spoolBatch(batch []*flow.Flow) {
+	if p.spool == nil {
+		return
+	}
+
+	err := p.spool.Write(batch)
+	if err != nil {
+		log.WithError(err).Error("Unable to spool batch. Flows are lost.")
+	}
+}
+
+func (p *pipeline) spoolCounters(batch []*counters.InterfaceCounters) {
+	if p.spool == nil {
+		return
+	}
+
+	err := p.spool.WriteCounters(batch)
+	if err != nil {
+		log.WithError(err).Error("Unable to spool batch. Interface counters are lost.")
+	}
+}
@@ -0,0 +1,212 @@
+package flowhouse
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/spool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type insertRecorder struct {
+	mu             sync.Mutex
+	batches        [][]*flow.Flow
+	counterBatches [][]*counters.InterfaceCounters
+	fail           bool
+}
+
+func (r *insertRecorder) insert(flows []*flow.Flow) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fail {
+		return fmt.Errorf("connection refused")
+	}
+
+	r.batches = append(r.batches, flows)
+	return nil
+}
+
+func (r *insertRecorder) insertCounters(ifCounters []*counters.InterfaceCounters) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.fail {
+		return fmt.Errorf("connection refused")
+	}
+
+	r.counterBatches = append(r.counterBatches, ifCounters)
+	return nil
+}
+
+func (r *insertRecorder) counterSizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ret := make([]int, 0, len(r.counterBatches))
+	for _, b := range r.counterBatches {
+		ret = append(ret, len(b))
+	}
+
+	return ret
+}
+
+func (r *insertRecorder) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fail = fail
+}
+
+func (r *insertRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ret := make([]int, 0, len(r.batches))
+	for _, b := range r.batches {
+		ret = append(ret, len(b))
+	}
+
+	return ret
+}
+
+func TestPipeline(t *testing.T) {
+	r := &insertRecorder{}
+	p := newPipeline(pipelineConfig{
+		workers:     4,
+		queueLength: 16,
+		batchSize:   10,
+		batchMaxAge: 50 * time.Millisecond,
+	}, func(fl *flow.Flow) {
+		fl.VRFIn = 1
+	}, r.insert, r.insertCounters, nil, prometheus.NewRegistry())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.run()
+	}()
+
+	// Batches are inserted once they reached batchSize flows
+	for i := 0; i < 4; i++ {
+		p.input <- []*flow.Flow{{}, {}, {}, {}, {}}
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(r.sizes()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []int{10, 10}, r.sizes())
+
+	// Smaller batches are inserted once batchMaxAge has passed
+	p.input <- []*flow.Flow{{}, {}}
+	assert.Eventually(t, func() bool {
+		return len(r.sizes()) == 3
+	}, time.Second, time.Millisecond)
+
+	// Remaining flows are inserted once the input is closed
+	p.input <- []*flow.Flow{{}}
+	close(p.input)
+	close(p.counters)
+	<-done
+
+	assert.Equal(t, []int{10, 10, 2, 1}, r.sizes())
+	for _, b := range r.batches {
+		for _, fl := range b {
+			assert.Equal(t, uint64(1), fl.VRFIn)
+		}
+	}
+	assert.Equal(t, float64(0), testutil.ToFloat64(p.insertErrors))
+}
+
+func TestPipelineSpool(t *testing.T) {
+	sp, err := spool.New(spool.Config{
+		Dir: t.TempDir(),
+	}, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unable to create spool: %v", err)
+	}
+
+	r := &insertRecorder{}
+	p := newPipeline(pipelineConfig{
+		workers:     1,
+		queueLength: 16,
+		batchSize:   100,
+		batchMaxAge: time.Hour,
+	}, func(fl *flow.Flow) {}, r.insert, r.insertCounters, sp, prometheus.NewRegistry())
+
+	// Failed batches are spooled
+	r.setFail(true)
+	p.flush([]*flow.Flow{{Packets: 1}})
+	p.flush([]*flow.Flow{{Packets: 2}, {Packets: 2}})
+	assert.False(t, sp.Empty())
+	assert.Empty(t, r.sizes())
+
+	// Newer batches are spooled behind the spooled ones and inserted in order by the replayer
+	r.setFail(false)
+	p.flush([]*flow.Flow{{Packets: 3}})
+	assert.Empty(t, r.sizes())
+	assert.Len(t, p.replayCh, 1)
+	assert.True(t, p.replay())
+	assert.True(t, sp.Empty())
+	assert.Equal(t, []int{1, 2, 1}, r.sizes())
+	for i, b := range r.batches {
+		assert.Equal(t, uint64(i+1), b[0].Packets)
+	}
+	// The second batch was spooled without attempting to insert it
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.insertErrors))
+
+	// Interface counters share the spool
+	r.setFail(true)
+	p.flushCounters([]*counters.InterfaceCounters{{}, {}})
+	assert.False(t, sp.Empty())
+
+	r.setFail(false)
+	p.flush([]*flow.Flow{{Packets: 4}})
+	assert.True(t, p.replay())
+	assert.True(t, sp.Empty())
+	assert.Equal(t, []int{2}, r.counterSizes())
+	assert.Equal(t, []int{1, 2, 1, 1}, r.sizes())
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.counterInsertErrors))
+}
+
+func TestPipelineCounters(t *testing.T) {
+	r := &insertRecorder{}
+	p := newPipeline(pipelineConfig{
+		workers:     1,
+		queueLength: 16,
+		batchSize:   4,
+		batchMaxAge: 50 * time.Millisecond,
+	}, func(fl *flow.Flow) {}, r.insert, r.insertCounters, nil, prometheus.NewRegistry())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.run()
+	}()
+
+	// Interface counters of several datagrams are inserted at once
+	for i := 0; i < 3; i++ {
+		p.counters <- []*counters.InterfaceCounters{{}, {}}
+	}
+
+	assert.Eventually(t, func() bool {
+		return len(r.counterSizes()) == 1
+	}, time.Second, time.Millisecond)
+
+	// Smaller batches are inserted once batchMaxAge has passed
+	assert.Eventually(t, func() bool {
+		return len(r.counterSizes()) == 2
+	}, time.Second, time.Millisecond)
+
+	close(p.input)
+	close(p.counters)
+	<-done
+
+	assert.Equal(t, []int{4, 2}, r.counterSizes())
+}
@@ -134,6 +134,7 @@ function drawChart() {
         return;
       }
       renderChart(rdata);
+      warnLossRatio(xhr);
     },
     error: function(xhr) {
       showPopup(
@@ -424,6 +425,21 @@ function renderChart(rdata) {
   }
 }
 
+// lossRatioWarningThreshold is the share of lost samples above which results are considered incomplete
+const lossRatioWarningThreshold = 0.01;
+
+function warnLossRatio(xhr) {
+  const lossRatio = parseFloat(xhr.getResponseHeader("X-Flowhouse-Loss-Ratio"));
+  if (isNaN(lossRatio) || lossRatio < lossRatioWarningThreshold) {
+    return;
+  }
+
+  showPopup(
+    "Up to " + (lossRatio * 100).toFixed(1) + "% of the samples of this time range were lost in transit. Traffic may be undercounted.",
+    "warning"
+  );
+}
+
 function formatTimestamp(date) {
   return date.toISOString().substr(0, 16)
 }
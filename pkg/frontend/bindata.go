@@ -78,7 +78,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _assetsFlowhouseJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x3b\xeb\x72\x1b\xb7\x7a\xff\xf5\x14\xdf\x59\xe9\x64\x77\x23\x6a\x49\xca\xce\xc5\x94\xa9\x8c\x23\xc7\x27\x6e\xe3\x4b\x63\x27\xa7\x53\x9a\x75\xc0\xdd\x8f\x24\x2c\x2c\xb0\x07\x00\x29\x2a\xb6\x66\xfa\x14\x7d\x80\x3e\x42\x7f\xf7\x69\xce\x93\x74\x70\xd9\x0b\x28\x52\xb6\x9b\xfe\xe9\x4c\x93\x19\x65\x09\x7c\x77\x7c\xf8\x2e\x00\xb2\x26\x12\xe6\x94\x69\x94\xea\x42\xac\xb8\x86\x31\x0c\xce\x0e\x0e\x8e\x92\x42\xe4\xab\x12\xb9\x4e\x33\x89\xa4\xb8\x4e\xe6\x2b\x9e\x6b\x2a\x78\x92\xc2\xfb\x03\x00\x83\xa7\x34\x91\x06\x61\x2e\x64\x49\xf4\x6b\x5a\xa2\xd2\xa4\xac\x12\x8e\x57\xf0\x98\x68\x4c\x92\xf6\x33\x85\x3e\x0c\x07\x83\x41\x0a\x27\xf0\x60\x30\x80\x13\x68\xa7\xb2\x05\x5a\xec\xdf\x05\xc7\x17\xf3\xb9\x42\x9d\xa4\xf0\x25\x7c\x3d\x48\xbf\x74\x38\xe9\xd9\x01\x00\x9d\x43\x72\x94\x44\x87\x9a\x96\xf8\xd6\xb2\x8e\xd2\x6c\x4d\x58\x92\xc2\x78\x0c\x51\xe4\xe4\x02\xd8\x09\x63\xbf\x2d\x99\x9b\x03\x2f\x3d\xf2\xe2\x7f\x20\xfb\x1f\x92\x1a\x79\xf1\x11\x99\x5b\x08\xe4\x45\x2b\xaf\x99\x77\xab\xf4\x92\xad\x54\x94\x66\x39\xa3\xf9\x65\x42\x8a\xe2\x89\x1d\xb5\x90\x47\x49\x64\xb4\x89\xd2\x4c\xf0\x24\x56\xab\x59\x49\x75\xdc\x03\xf7\xf1\x4f\x2b\x94\xd7\xe9\x99\x21\xb6\x10\x62\xc1\x30\xcb\x97\x44\x6a\x95\x31\x41\x8a\x24\xce\x57\x52\x22\x37\xe0\x56\xa0\xb8\x22\xf9\x25\x59\xa0\x8a\x47\x30\x89\x73\x21\xd1\x42\xc7\x53\x23\x90\x65\x06\x07\x00\x57\x94\x17\xe2\x2a\x13\x7c\x49\xd4\x32\x5f\x12\xbe\x40\x63\x51\xef\x28\x90\xd4\xda\x85\x0c\x15\xea\x17\xfc\x27\x41\x8a\x0b\xc2\xd8\x8c\xe4\x97\x49\x21\xc9\xd5\xc5\x32\x58\xa0\xcf\x41\x39\x00\xa8\x44\xb5\x62\x44\xe3\x13\x8a\xac\x50\x49\x7a\x76\x60\xa4\x3c\x68\x44\x69\x0c\xe5\x65\xca\x05\x57\xda\xfb\xfd\x6b\x2c\x2b\x83\x0b\xe3\x8e\x99\xeb\xc1\x28\xcd\x96\xba\x64\x89\xd9\x05\x15\x23\x39\x26\xfd\xb7\x6f\x9f\xff\xf2\xec\xed\xdb\xfe\xa2\x17\x6c\x9c\x7a\x09\x3c\x01\xb3\x48\xa4\xaa\x90\x17\x49\x48\xd1\x09\xec\x04\x38\x72\x53\x56\x6a\xcb\xfe\x37\x8f\xfd\x76\x6e\x86\xde\xbc\x99\x1c\xbd\xef\xf2\xb8\x79\xf3\x66\xfa\x9b\x65\x14\xe0\xff\x4a\xd8\x0a\x43\xfc\xb5\x19\xfa\x54\xfc\x9f\xb1\x14\xeb\x2d\x02\xd2\x8e\xed\xa5\x60\x74\xed\x08\x9f\xb9\xd5\xdf\x0e\x12\xad\xa1\x91\x15\xcf\x49\xe9\x78\xe8\x25\x55\x7e\x17\x9c\x05\x40\x86\xdc\xf3\x55\xd9\x01\x22\x5a\xcb\x24\xa2\x66\x4f\x94\x44\xe7\xcb\xa4\xff\xa6\x38\xee\xa7\x93\xc1\xd4\x61\x76\x0d\xe0\x81\x39\x29\x31\xea\xb5\x2c\x3d\x0f\xe3\xe6\x16\x4c\x25\x0d\xa3\x6d\xa8\x9b\x40\x31\x67\x15\xbf\xd3\x6e\x29\x56\x4b\x98\x33\xa1\x50\xe9\x24\xce\xa4\xb8\x8a\x8d\x9b\x18\xac\xa4\x4b\xaf\x6b\xbf\xe3\xe3\xb3\x83\x9b\x83\x8e\x67\x56\x44\x2a\x7c\x49\x24\x29\x55\xa2\xb4\x74\xe4\x25\xea\x95\xe4\xa0\xb4\xcc\x54\xc5\xa8\x4e\xe2\x2f\x2c\xe9\x62\x95\x77\x6c\x5c\x59\xac\x1e\xd8\xff\x86\x06\x9f\x5c\xe2\x75\x0f\xac\x13\x4c\x61\xec\x20\x6a\x52\xe3\xd8\x18\xb3\x4a\x0a\xcc\x45\x81\xbf\xfc\xfc\xf4\x42\x94\x95\xe0\xe8\x5d\x18\x1c\xb4\x32\x24\x0c\xae\x25\xd2\x7a\xff\x9b\x63\xe3\xf8\x31\xc4\x1e\xd8\x8b\xea\x70\xac\xd2\x3d\x78\x6f\x14\xbf\xe9\x2a\xb9\xb5\x3d\x9b\x0c\xf2\x37\x13\x95\x60\x0c\x4c\xe4\xc4\x40\x66\x4b\x89\x73\x2f\x68\x74\x18\xa5\x93\xe1\xb4\x0e\xa2\x7f\xb2\xb0\xb5\x9a\x8e\x6d\x10\xcf\xed\xfc\x0f\x7f\x5b\x59\x42\x0a\xc6\x6e\xa0\x63\x40\x03\x3d\x17\x12\x12\x03\x4d\x6d\xa2\x03\x0a\x0f\xb7\x10\x33\x86\x7c\xa1\x97\x67\x40\x8f\x8f\x6b\x6e\x36\x5d\xd4\x14\x1b\xc8\x09\x9d\x76\x6c\x7a\xd6\x40\x5e\xc2\x18\xb0\x71\x51\x33\xb2\x86\x31\xdc\x36\x77\x82\x93\xe1\xd4\xb9\x88\x53\xf1\xd2\x26\x85\x99\x44\x72\x59\x88\x2b\xde\x64\x07\x17\x57\x9a\x71\x10\x95\xe1\x3f\xb1\x2b\x33\x8e\xe0\x18\xd6\x70\x0c\xd1\x34\xf2\xfb\x25\x56\xc8\x30\xd7\x58\xc4\x3d\x68\xbf\xbd\x80\xd6\x43\x34\xe5\x2b\x74\xbf\x6f\xec\xdf\x2d\x11\xba\xe9\x33\x90\xe1\x56\x5e\x5d\x7f\x36\x59\x9b\xe1\x6e\x13\x6d\x13\xdf\x7e\x92\xdb\xe4\x44\xf5\x84\x89\x2b\xb5\x4d\xae\x19\xfe\x64\x72\x75\x70\xf9\xd7\x6e\xf4\xed\xa7\x2d\xdd\x9d\xa8\xae\x74\x42\x56\x3c\xe5\x05\x6e\x60\xdc\xcd\x32\x67\x4d\x5a\xdf\x0a\xe8\x66\xb9\x3a\x48\xc7\x10\xbd\x79\x33\xf5\xa2\x5e\x7e\x3e\x9a\x96\x74\xb1\x40\x99\x44\x2e\x00\x47\xb7\x29\xd4\x99\xe0\x2e\xc6\x6b\x9f\x78\xbb\x7b\xb6\x53\x36\xf8\x0d\x8b\x6b\xe4\x3a\xab\xa4\xfd\xef\x63\x9c\x93\x15\xd3\x89\xf3\xdf\x7e\x1f\x7e\x25\x8c\x16\x26\x8d\xc6\xf5\x02\xc4\x30\x13\x9b\x26\xd7\xd4\xa3\x36\xba\xc7\xcd\x2a\xc5\x9d\x4c\x10\x02\x3e\xb5\xc5\xa8\x8d\x8f\x4f\xb9\x4e\xea\xe1\x1e\x0c\x07\x4d\x65\x45\xd5\x73\xf2\x3c\xe9\x60\xa4\xf0\xe1\x43\x40\xe1\x21\x0c\xb7\x87\xce\x6d\x79\x36\xa8\x57\x97\x30\x94\x3a\x89\x9e\xf2\x5c\x48\x89\xb9\x86\xf8\xb5\xa8\xc0\x09\x37\x82\x8a\x21\x51\x08\xc8\x35\x4a\x20\x26\x1a\xd2\x02\x28\xd7\xb8\x40\x09\x33\xd4\x57\x88\x1c\x86\x40\x78\xe1\xa8\x66\x51\x18\x18\xe7\x84\x29\x6c\xe2\x94\x0b\x93\xce\x04\xa6\x56\x8b\xd3\x4c\xa1\xa4\x84\xd1\xdf\x7d\xce\xf0\x10\xc7\x63\x88\xbf\xa8\x85\x1e\xc7\x70\x0c\xc8\x6f\xc5\x8f\x7a\xde\x22\x06\x21\x14\xc6\x10\x1d\x9a\x15\x77\xe4\x0e\x42\x71\x82\x75\x6e\x2a\xa9\xcf\x0b\xcb\x9f\x12\x95\x8f\x32\xf2\x8e\x6c\x12\x37\xa7\xaf\x2b\x1c\x41\xf4\x97\x1f\x5e\x47\x3d\x3b\xb0\x92\x6c\x04\x51\xdf\x12\xf8\xce\xc8\x6a\xbf\xdc\x5c\x41\x34\x79\xed\x10\x34\x6e\xb4\xc7\x50\xab\x3c\x47\xa5\x46\x4d\x89\x99\x48\x03\xd8\x33\x8d\x88\x5e\xa9\x1e\x6c\x96\xb2\xdd\xb3\x46\x3c\x3b\x6f\x82\xc5\x8a\x17\x38\xa7\x1c\x8b\x76\xde\xed\x12\x5b\x61\xbe\x2d\xe8\xda\x6c\x26\xdc\xe8\x24\x7a\x2e\x2c\x7b\x98\x8b\x95\x89\x49\x67\x0d\x78\xab\x5d\x1b\xe1\xcc\x28\x2f\x50\x3a\x0b\x5a\x76\x1e\xe3\xc6\xc9\x8c\x52\x0a\xd9\x91\x38\x10\x51\x2d\xc5\xd5\x4b\x51\xad\xaa\xa4\x61\x12\x3d\x35\x9e\xc6\x09\x03\x85\x72\x8d\xd2\x11\xf0\x06\xb0\x00\x85\xd9\xe8\xdd\x91\x53\xe3\x77\xed\xcf\xcd\x52\x66\x12\x55\x25\xb8\xc2\xd7\xb8\xd1\x7e\xa2\x51\x64\x5b\x6b\x2c\x2b\x7d\x9d\x34\xd3\x75\xdf\x67\xda\x9b\x1f\x18\x9a\xcf\xef\xaf\x9f\xda\x16\x41\x69\x51\xbe\x65\xb8\x40\x5e\xc4\x69\x46\x39\x47\xf9\xe3\xeb\x67\x3f\xc1\x18\xe2\xb8\x0d\xfb\xdb\xc9\xff\xb6\x81\xac\xfe\x95\x44\xb3\x11\x5e\x92\x8a\x64\x76\x93\xbb\x49\x13\xd1\xca\x24\x75\x61\xa5\x6d\x4e\xd1\xd4\xc7\x13\x83\x93\x19\xa8\xc9\x60\x3a\x6d\xd2\xb9\x0b\x1b\x52\x5c\x81\x98\x43\x03\x12\x16\x44\x4b\xa2\x9e\x0b\xfe\x2f\x28\x05\x8c\x0d\x68\xa6\x18\xcd\x31\x19\xa6\x99\x12\x25\x26\x6b\xc2\x60\x7c\xde\x0d\xf7\x4a\x03\xb7\xa5\xa8\x95\xed\x09\x13\x44\x27\x16\xec\xc3\x07\x88\xe3\xb4\x15\xb3\xeb\x1b\xf0\x27\x17\x90\xf8\xaa\x4c\xe1\x8b\x2f\x2c\x85\x3f\x8d\x6d\x47\x6d\xad\xe3\xc1\x8d\x67\xb6\x02\xb5\xee\x50\xab\x9a\x55\x2b\xb5\x4c\xa4\xb8\x4a\x3b\x56\xad\xed\xe1\x3c\x1a\x26\xd3\x7d\xe5\x4c\x43\x65\x47\x21\x63\x6d\x47\xa7\x0d\x7e\x87\xc2\x3b\x47\xe1\x5d\x87\x82\x29\x70\x6a\x22\xef\x5a\x22\x4e\x0e\x93\xf0\x3a\x80\x93\x77\xd3\xb3\xce\xc6\xa3\x4e\x6f\x63\x84\x77\xee\xb3\xbb\xf1\x36\x5b\x76\xdd\xec\xb1\x6a\x37\xc8\x6f\xd2\xd4\xe2\x0d\xb6\x37\xa0\xd7\x69\xf2\xce\xa8\xb5\xd9\xb2\x98\x8d\x51\xbe\x4f\x35\x91\x16\xd5\xaf\x54\xd1\x19\x65\x54\x5f\x1b\xa6\x7b\xa6\xbc\xda\x56\x72\xef\x6f\xf5\xd0\x09\x0c\x6b\x55\xf6\xd1\x1d\xc3\x23\x29\xc9\x75\xb2\x03\x33\x9b\x53\xc6\x12\x2d\x57\x18\x1e\x46\xd4\x96\x7c\xfc\xb1\xd5\xb5\x7b\x64\x4f\x89\x6a\xb6\xc0\x18\x26\xb5\x41\xfc\x1e\xd9\x5a\xe3\xa1\x5b\x63\x0f\xb4\x7b\x7d\x8d\xd5\xf6\x28\x37\x79\x67\xf4\x98\x76\x57\xd3\x6c\x27\xeb\xb1\xed\x4a\xa4\xe1\x22\xb9\xbf\x5d\x1d\x43\x17\x6f\x96\xaa\x0b\xd2\xb1\xdc\x43\x38\xad\x19\xb6\x21\xd3\x04\x6a\x27\x1d\xd4\x45\x6e\x06\x2f\x5d\xba\x76\x03\x40\x34\x98\xdf\x1a\x04\x47\x98\x33\x71\x05\x5a\x40\x41\x55\xc5\xc8\x35\xe8\x25\x82\x8d\x83\x59\xd4\x6b\xa2\x6a\xa7\x7e\xda\x1b\x23\xff\x50\x84\x0c\x52\xa4\x5f\x7c\xcb\xc9\xaf\xbc\x3f\xf8\x58\x53\xb5\x32\x55\x81\x4b\xbf\xc4\xb8\xd3\x6b\x61\x40\x5e\x93\x19\xc3\xc0\x4e\x6d\xa8\x74\x7d\x81\x89\xaa\xce\x56\x54\xbd\xd2\x24\xbf\xc4\x62\xe4\xf2\xbe\xcb\x11\x9a\x6a\x86\x23\x88\x4d\xe9\x00\xcf\x66\x95\x8a\x3b\xe3\x26\x61\xbc\xd2\xd7\x06\xa0\x89\x4a\x82\xeb\x57\xf4\x77\x1c\xc1\xe9\xfd\x3a\xcb\xcc\x04\x2b\x46\x60\xdc\xb8\xd7\x84\x4c\x66\xf2\x5c\x7c\x78\xef\xde\xbd\xb8\x9b\x00\x97\x8f\x36\x54\xb5\xd4\x6a\xee\xaf\x69\x89\x71\x8d\xac\x18\xe1\x1a\x0b\xc3\x3c\xa4\xda\x99\x78\xc4\x17\x06\xf1\xeb\x26\xd3\x19\x4f\x30\x13\x3f\xac\x51\x5e\x8f\x60\xd8\x4c\xec\x53\x64\x4b\xc8\x36\x63\x52\x4d\x18\xcd\x03\x23\xed\x56\xb2\x6b\x8c\xe1\xb7\xb5\x8b\xd7\xb3\x0b\x49\x0b\x46\x39\xaa\x9d\x2c\xe7\xf7\xcc\xbf\x1d\xae\xb9\x58\x71\x6d\xe4\xde\xa6\x53\x52\x2e\xe4\x5f\xee\x24\x86\x0f\xcc\xbf\xf1\x36\xa6\xfe\x0c\xa5\x3b\x9a\x9c\x86\x9b\xd5\xc1\xac\xc3\x75\x2b\x29\xb7\xc7\x27\x23\x08\xed\x3c\x82\xf8\x19\x2e\xc8\x8c\x6a\x05\x15\x4a\x50\x98\x0b\x5e\xc4\xff\xbf\x16\xff\x9b\x6b\xb1\x44\xba\x58\xea\x11\xa8\x5c\x22\xf2\xcc\xfd\x84\x2f\x61\x90\x7d\xe3\x00\x6c\x04\x79\x24\x91\xb4\xcc\xae\x68\xa1\x97\x23\x88\x1f\x0c\xfe\x1c\xf7\xc0\x0f\xd6\x84\xe2\x6f\xcc\x68\x2d\xa9\xa8\x46\x10\x7f\xd5\x0e\xcc\x48\x7e\xb9\x90\xa6\xfc\xbd\x70\xf2\xb6\x0a\x28\x2d\xc5\xa5\x59\xf4\xc3\x3c\xcf\x3b\x1a\xb8\xf1\xbf\x3a\x9e\xc3\x5d\x3a\xdc\x22\x1a\x1f\xce\xed\x3f\x9e\x8a\x35\x8d\x1a\xc1\x24\x3e\x3c\x1d\x3e\xf8\xfa\xc9\xbd\xb8\x07\xf1\xe1\xfd\x8b\x47\x4f\xbe\x1a\xd8\xcf\x27\x4f\x2e\x86\x83\x6f\xfc\xe7\x57\xdf\x9c\x9e\xda\xcf\x07\x17\xa7\xdf\x7c\x3f\x88\xa7\x8e\x08\xe1\xb4\xb4\x21\xb3\x95\xd9\x9e\x56\xac\xaa\xd0\x79\x8a\x95\xf4\x60\xc3\x4e\xed\x8c\x44\x51\xbe\x18\x41\x2c\x56\x3a\x88\x61\x2e\xa2\xb7\x34\x2b\xa1\xa8\x43\x8f\xb9\xe0\x18\xc0\x6a\x21\x98\xa6\x55\x27\xe2\xfd\x41\x47\xe8\xc6\x3b\x6b\xba\x0b\x51\xa0\x53\x27\x90\x91\xf2\xda\xfe\xa7\x6e\xa4\x12\xb4\xa1\xe6\x1b\x28\x9b\x2e\x5b\x39\x06\x23\x78\x6f\x11\x1f\x13\xb5\xf4\x32\x4e\xee\xf7\xe0\xfe\xb4\x65\x3b\xdc\x05\x73\xda\x83\xd3\x0e\xcc\xe9\x1e\x3a\x5d\x98\x7b\x7b\xe8\x74\x79\xdd\xdf\x05\x33\xec\xc1\x70\xda\x29\x24\x6e\xce\x82\xbc\x09\x63\x7b\x27\xb3\x33\x6f\x9a\x1d\xe1\x1a\x8f\xfd\x39\xbb\xce\xf2\xb1\x2b\x3b\x5d\x45\x60\x9a\xe2\xa4\x49\xcb\xbd\x3a\xb3\xba\x54\xeb\x1a\x9a\x9f\xac\x4f\xb8\x74\xec\xcf\x79\xc3\x8e\x27\x00\x08\x8f\xc3\x99\xb8\x7a\xa5\x89\x56\xdb\x75\x38\x43\x6d\x6b\xbd\x61\x5b\xeb\xb5\x55\x50\x50\xee\x19\x9f\xd4\x50\x12\x53\x11\x9f\x3c\xe5\x73\xca\xa9\xbe\x3e\x6b\x32\xb6\xa7\xb5\x55\xef\xed\x2e\xf6\x6a\xa9\x6c\x0f\xe4\x78\xbe\x9b\x4e\xe8\x34\x2c\xc2\x4d\xfb\x2e\xe6\x0e\x68\x3c\x86\x88\xaf\xca\x19\xca\xc8\x14\xf8\xbe\xe9\x59\x13\x96\x76\xa9\x3a\x3c\x83\x70\x6e\x04\x4d\xbd\xb4\x6b\xc2\x5a\xca\x37\x5b\xb5\x7c\x63\x19\x57\x1f\xb6\xc4\x28\x2f\x70\x33\x02\xda\x6e\x15\x46\x66\xc8\x46\xb5\x8d\x26\x74\xda\x4e\x95\x64\x33\x72\xdc\xc6\x1d\xeb\xc0\x77\x30\x00\x3b\x5e\x33\x4d\x83\xe3\xbc\x7e\x1f\x5e\x09\xa9\x29\x5f\x00\x13\x0b\x9a\x37\xad\x5a\xdd\x3f\xb8\x28\x60\x60\x52\xb8\x35\x64\x8a\x2e\xb8\xc4\xeb\x11\x44\x56\xb2\xa8\x07\x44\xe5\x6e\x9b\x1a\x97\x6d\xed\xac\x84\xd4\xff\x88\xa6\x4b\xb8\x45\x24\xbb\xc4\xeb\x6d\xd0\x47\x2a\xdf\x09\x4a\x54\xee\x8f\x8e\x5b\xa3\x19\xf8\x24\x21\x3d\x98\xa5\xdd\x76\x56\x5d\x51\x9d\x2f\x21\xf1\x9c\x83\xa5\x37\xd5\xb2\x97\x78\xd4\x59\xba\xfa\x06\xc2\x09\xd0\x99\x00\xf8\x0e\x48\x66\x11\x32\x26\x72\xc2\xf0\x42\x94\x15\x91\x98\xcc\xdc\x68\x1a\x00\x8f\x60\xb6\x13\xd8\x93\xe8\x74\x7a\x4e\x92\x92\x6c\x3e\x47\x0e\xb3\xc8\x27\x30\xcb\xda\x45\x6d\xd9\xba\x39\x0b\xd3\x72\x29\xdc\xb1\xe5\x0e\x16\x5b\x7d\xe5\x4d\x7d\x30\xef\x56\xc2\x95\xf6\x6e\x4b\x3f\xa6\xf6\x3c\xff\x13\xbb\x00\xbf\x9e\x21\x7e\xd0\x1a\xfc\x66\x21\x1e\x16\x74\x0d\x39\x23\x4a\x8d\x23\x87\x7b\xb2\x44\x56\x9d\x68\x5a\x45\xe7\x5e\xb4\x87\x26\xcd\xf2\xc5\xf9\x2f\x8a\x2c\x70\xf4\xb0\xef\x7f\x3e\x9c\xc9\x16\xa2\x22\x1c\x94\x09\x9d\xe3\xc8\x25\x19\x9f\x4e\xcf\x4c\x6e\x39\xb9\x72\x05\x80\x29\xa7\xce\xa2\xf3\xbf\xff\xdb\x7f\xc0\x05\xa3\xf9\x25\x10\xeb\x45\x0f\xfb\x06\xfd\xdc\x74\x49\x26\xdb\x80\xe0\xcc\xf4\x49\xc4\x85\xac\xac\x86\x5d\x10\xca\x1b\x18\xc2\x58\xf6\x11\x01\x5c\x12\xdf\x2b\x80\x96\xac\x7f\x51\x16\xfd\x17\x36\xc8\xc2\xb1\x63\xd3\x91\x85\x14\x05\x08\x09\xee\x6a\xcd\x8a\xa2\x3e\xc6\xd2\x55\x08\x1f\xd3\x39\x17\x6c\x55\x72\x58\x22\x29\x50\x76\x95\x37\x1b\xda\xf4\x87\x6e\x21\x32\xb7\x40\xfd\x82\xae\x1d\xcf\xdf\x42\xdf\xb0\x95\x0b\x8c\xeb\x2c\x91\xb9\x81\xee\x46\xd6\x26\x0b\x74\x9d\x26\x97\x48\x34\x7a\xbf\x49\x62\x3b\x5f\xfb\x8a\xfd\x91\x59\x57\xf8\x89\x2a\x9d\x91\xa2\xa8\x21\x7a\xe0\x3e\x4e\x54\xd9\x7e\xcf\x84\x2c\x4c\x33\x18\x07\xd7\xa9\xda\x68\x75\x17\x4b\x33\x1f\xa2\x98\x91\x9f\xed\x19\xc2\x5e\x24\x19\xd7\xfb\xa2\xc9\x76\x25\xb9\xc4\x1f\xad\x05\x2f\x90\xb1\xc4\x94\x3b\x3d\x13\x09\xd3\xad\xe3\x34\xbd\xbc\x53\x9a\xf6\x82\x4a\x2f\xed\x49\xec\x85\xe0\x1a\xed\x1d\x80\xf9\xd5\x99\xb4\x2b\x9d\xe5\x2b\xa9\x84\x34\x9d\xb5\xad\x73\x50\xc6\xb7\x40\x56\x0a\xe5\x2b\x77\x12\x30\xf6\x95\x5a\x07\x86\x14\xc5\x0f\x6b\xe4\xda\xd8\x18\x39\xca\x24\xb6\x57\xbd\x71\x0f\x12\x0c\xc2\x67\x70\x1e\x12\x06\x6b\x9b\x61\x2e\xc3\x90\x0a\xbb\x83\x35\x8c\xe1\x76\x22\x71\x51\xbc\xc6\xbb\x01\x64\x0a\x3f\x42\xca\x72\x85\x26\x51\xb8\x7f\xfa\x7d\xf0\x17\x32\x23\xe7\xbc\xb3\x6b\x78\xf6\xe8\x9f\x6d\x7b\x0f\x05\xaa\x1c\x79\x41\xf9\xa2\x07\x4f\x7e\x7a\xf1\x57\x93\x98\xdc\xef\x4f\x90\x39\xa9\xb5\x8c\x6d\xc0\x8e\xd3\xdb\xc9\x7b\x4f\x45\x54\x4f\x62\xa6\xb4\xa8\x5e\x4a\x51\x91\x05\x71\x77\xe8\x67\x5b\x19\xf8\x33\x2d\xbc\xe5\x20\xc7\x7b\xd2\x23\x7c\x07\x31\xfc\xfd\xdf\xff\x33\x86\x91\xfd\xf8\xaf\xf8\xf6\xd9\xbd\x0d\xfd\x7a\x19\x14\x02\x7e\x1b\xf8\xe7\x1b\x17\x4b\xca\x8a\xe4\x2e\xbf\x4d\xcf\x8c\xfd\xed\xa6\x87\x1c\x19\x83\xc4\x1e\x17\xa5\x7b\x89\x6d\xed\x98\xd8\x2c\x8a\xd9\xce\xde\xc2\xde\x28\x9f\x82\x59\xaf\xb1\xc1\x2e\xc9\xa6\xc1\xb5\x9b\x3b\xc0\xf4\xd4\xc2\xac\xa6\x67\xa2\xb8\xbe\x6b\x57\x9a\xf9\x76\xc7\xb7\x87\xed\x4a\x13\x0d\x62\xde\x96\x1e\xdb\x9b\xdd\x54\xb1\x06\x28\xb3\x85\xdb\x59\x30\x27\x3f\x21\xc4\x74\xe1\xad\x65\x8d\xbe\x77\x61\x05\x17\xdc\x1e\xde\x87\x81\xad\xce\x13\xc6\x3e\x62\x4f\x12\xea\x4e\x73\xff\xec\x07\x7c\x7d\x3c\xdd\x47\xc8\xb6\xd4\x26\x94\x9c\x0e\xaa\x4d\x1c\x0a\x69\xd7\xee\x33\x84\x6c\xe0\xb7\x62\x9d\x35\x9a\x9d\x0c\xe9\x97\x64\xf3\x19\xd4\x3d\xf4\x2e\xda\x25\xd9\x64\x5a\x3c\xa1\x1b\x2c\x92\x61\x7b\x7f\xb1\xe5\x68\x8d\xea\x7b\x21\x1a\x05\xf6\x42\x78\x21\xda\xb8\x6e\xbc\x29\x80\x90\xad\x43\xde\x7d\x54\x3f\xa1\x3b\x4f\x9d\xdd\xb2\x88\x8a\xe4\xee\xd8\x3d\x1e\x64\xf7\xdb\x4d\xbe\x1d\x4d\x77\x62\x0c\xb3\x41\x27\x2c\x74\x35\xd9\x9b\x1e\x9a\xbb\x3c\x7b\x11\xbe\xa3\x91\x32\x62\x9b\x52\xd7\x3d\xa7\xdc\x77\xc5\xe0\xce\x70\x93\xef\x85\x60\x48\x78\x5a\x37\x67\x41\xd2\x71\x57\xee\xb9\x96\xcc\x74\x0c\x1f\x3e\xf8\x3b\xf8\x12\x35\x09\x06\x08\xdb\x2e\xec\xef\x3e\xc4\x77\xe6\x34\xcd\x5b\x28\xec\x78\xdc\xde\x6e\x6c\x65\x86\x3f\x70\xcb\xd1\x24\x8c\xdb\xf9\x6d\x2f\xf9\x5a\xc4\x36\x6b\xee\x81\x08\x18\xdc\x95\x4a\xff\x6f\xdb\xe3\xe3\xe4\xed\x89\x69\x40\xff\x13\x8c\x6b\x64\xda\x63\xc1\xad\xec\x7e\xfb\x86\xfb\x56\x07\xed\x0a\xd7\xee\x06\xb7\x79\x28\x28\x6b\x83\x59\x13\x10\xf6\x34\x48\x01\x9c\xc1\xdc\xf1\x44\x65\xfb\xed\x6e\x41\x34\x06\x0f\xe8\xcc\x40\xa6\xc5\xd3\x57\x2f\x5e\x69\x49\xf9\x22\x49\x33\xb5\x9a\x29\x2d\x93\x41\x0f\x86\x5f\xa7\xe1\x83\x97\xe6\x66\xa9\x44\x65\x1a\xac\x9e\x7d\xb3\x30\x6e\x2e\xdc\x41\xd3\x12\xc5\x4a\x8f\x87\x5f\x0d\x06\x83\x1e\x14\xa8\x09\x65\x6a\xcc\x57\x8c\x75\x9f\x95\xe6\x82\x6b\x42\x39\x4a\xff\xa2\xb4\x32\x44\x4f\x9a\xd1\xa8\xf3\xd6\xc5\xbe\x3d\x79\x6a\xca\xf4\xc8\x41\x45\x70\x6c\x9f\x17\x67\x5c\x5c\x25\x29\x1c\xc3\x33\xa2\x97\xd9\x9c\x09\x21\x13\xfb\x29\x09\x2f\x44\x99\xa4\x5f\xba\x37\x2c\xf6\xe5\x07\xea\x5a\x96\x1f\x75\x69\x52\x44\x14\xd5\x0f\x64\xfc\x78\x78\xfe\xe4\x07\x2d\xdf\x5a\x82\x63\x88\x4e\xfc\x78\xe4\xaf\xb9\x02\x92\xbf\xd5\xcd\x56\xd3\x04\xd9\x5f\x04\x96\x12\xe7\xe3\xe8\x30\xaa\x1b\x30\xdb\x72\x29\xfa\x3b\x8e\x86\xa7\xd5\xe6\x2c\x02\xc1\x6d\xe0\x1c\x47\x47\x49\x7c\x78\xf4\xbe\x61\x7e\x13\xa7\x99\x16\x8b\x85\xad\x16\xc3\xe7\x32\xd1\xf9\x2b\xd3\x5a\x3e\x76\xa0\x0f\xfb\xa4\xc3\xb1\x92\x08\xb4\x18\x47\x5d\x4a\x0d\xf3\x8e\x27\xfb\x6b\xbe\x91\x29\xfb\xbb\x1e\xde\x56\x03\xa3\xc3\xf9\xb7\xf3\x07\x73\x12\xcc\xda\x5e\x6a\x34\xac\x36\xa0\x04\xa3\x05\x1c\x22\x06\xe8\x15\x29\x4c\xe5\xec\x94\xeb\x8c\x97\x44\x2e\x28\x3f\xd1\xa2\x1a\xdd\x0f\x67\x3a\x06\xb9\xb7\x63\x66\x4e\x4a\xca\xae\x47\x50\x0a\x2e\x54\x45\x72\xec\xb5\x9f\x21\x83\xcd\x89\x3f\xbe\x3f\xfd\x6a\x10\x12\x12\x6b\x94\xa6\x14\x1b\x91\x95\x16\xdd\x89\xab\x25\xd5\x78\x62\x69\x8d\x2a\x89\x27\x57\x92\x54\xc1\xbc\x90\xc5\x89\x7d\x58\x38\xb2\x7f\x4f\x08\x63\xdd\x79\x46\x39\xd6\x5c\x87\xd9\xfd\xdb\x96\x3a\x91\xa4\xa0\x2b\x15\xea\x1c\x9d\x1f\xbd\x3f\x4a\x62\xeb\x2a\xb1\x7f\x56\x53\x3b\xa2\x7f\x4e\x7d\xf3\xb0\x5f\x49\x6c\x3a\xf8\xa0\xb5\x76\xb1\xa7\xb3\x41\xdc\xcb\xe4\xf6\x94\xc4\x2d\xbf\xf7\xdc\x9b\xa8\x3e\x35\x71\xb0\xf6\xef\xc9\xd1\x7b\xb3\x77\x6f\xfc\xaf\x82\xaa\x92\x2a\x1b\xd8\x61\x4e\x0a\xb4\x7b\x3d\x02\x29\x8c\xcf\x58\x90\xc6\x83\xfc\x3a\xce\x84\xd6\xa2\x1c\x7d\x5b\x6d\xce\xc0\xf7\x97\x27\x36\xd5\x2a\x67\xe3\xf6\x48\xc6\x48\x7e\xf4\xde\x87\x8c\x9b\x8e\x26\x00\x8d\x8b\x9a\x3d\x54\xc7\xd3\x87\xb3\x95\xd6\x82\xfb\xd8\xe2\x7e\x34\x2a\xd8\xd7\xc4\x91\x3d\xd4\xac\x85\x6e\x04\x24\x92\x92\x13\x5b\x75\x8d\xa3\x0b\x07\xe7\x65\xbe\x53\xc0\xfa\x7c\xc4\xa2\x2f\x69\x51\x20\x1f\x47\x26\xec\x47\xe7\x5f\x98\x80\xa6\xce\xfc\xc1\x47\xb3\x16\x4e\xa6\xf3\xf0\xd0\xa3\x79\x36\xee\x82\x58\xfd\xb6\xdd\xca\x66\xa7\x94\xfb\xbf\x21\xc4\x4a\x27\x49\xa7\x7d\xb6\x00\x99\x7b\x63\x17\x5b\xf5\x5c\x95\x7a\xd3\x84\xd3\xad\xe7\x42\x77\x3c\xd4\x0e\x9f\x99\xc1\x51\xb6\x40\xfd\x0f\xaf\x5e\x3c\x4f\xa2\x7e\x41\x73\xed\x9e\x3a\xaa\x7e\x74\x6c\xa1\x3b\x85\x5a\xf7\x3d\x10\xdc\xf5\x3c\xb2\x7e\x81\xde\xbc\x8e\x34\xb6\xcc\x45\x59\x31\xd4\x98\x84\x89\x5a\x89\x95\xcc\xd1\x9d\x40\xb7\x67\xcf\x4d\x4a\xb4\x6a\xfd\x77\x00\x00\x00\xff\xff\x15\xf7\x46\xf7\x5e\x33\x00\x00")
+var _assetsFlowhouseJs = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xec\x3b\xdb\x72\x1b\xb7\x92\xef\xfa\x8a\xce\x58\xf1\xcc\x44\xe4\x90\x94\x9d\x8b\x49\x8d\x52\x8e\x1c\x9f\x68\xd7\xb7\x8d\x95\xe4\xd4\xca\x5c\x07\x9c\x01\x49\x58\x33\xc0\x1c\x00\x23\x52\xb1\x59\xb5\x5f\xb1\x1f\xb0\x9f\xb0\xcf\xfb\x35\xe7\x4b\xb6\x1a\xc0\xdc\x28\x52\xb6\x37\xfb\xb2\x55\xc7\x72\xc9\x24\xd0\xdd\x68\x74\x37\xfa\x06\xf8\x9a\x48\x98\xb3\x4c\x53\xa9\xce\x44\xc9\x35\xc4\x30\x9c\x1c\x1c\x1c\x06\xa9\x48\xca\x9c\x72\x1d\x46\x92\x92\xf4\x26\x98\x97\x3c\xd1\x4c\xf0\x20\x84\xf7\x07\x00\x88\xa7\x34\x91\x88\x30\x17\x32\x27\xfa\x82\xe5\x54\x69\x92\x17\x01\xa7\x2b\x78\x42\x34\x0d\x82\xe6\x63\x08\x03\x18\x0d\x87\xc3\x10\xfa\xf0\x68\x38\x84\x3e\x34\x53\xd1\x82\x1a\xec\x3f\x04\xa7\x2f\xe7\x73\x45\x75\x10\xc2\x57\xf0\xcd\x30\xfc\xca\xe2\x84\x93\x03\x00\x36\x87\xe0\x30\xf0\xee\x69\x96\xd3\xb7\x66\x69\x2f\x8c\xae\x49\x16\x84\x10\xc7\xe0\x79\x96\x2f\x80\x9d\x30\x06\xde\x90\xd9\x1c\x38\xee\x29\x4f\xff\x17\xbc\xff\x29\xae\x29\x4f\x3f\xc2\x73\x03\x41\x79\xda\xf0\x8b\xf3\x56\x4b\xaf\xb2\x52\x79\x61\x94\x64\x2c\xb9\x0a\x48\x9a\x3e\x35\xa3\x06\xf2\x30\xf0\x50\x13\x5e\x18\x09\x1e\xf8\xaa\x9c\xe5\x4c\xfb\x3d\xb0\x1f\xfe\xa5\xa4\xf2\x26\x9c\x20\xb1\x85\x10\x8b\x8c\x46\xc9\x92\x48\xad\xa2\x4c\x90\x34\xf0\x93\x52\x4a\xca\x11\xdc\x30\xe4\x17\x24\xb9\x22\x0b\xaa\xfc\x31\x5c\xfa\x89\x90\xd4\x40\xfb\x53\x64\xc8\x2c\x06\x07\x00\x2b\xc6\x53\xb1\x8a\x04\x5f\x12\xb5\x4c\x96\x84\x2f\x28\x4a\xd4\x19\x0a\x38\x4b\xd9\x5e\x50\x51\xfd\x92\x3f\x13\x24\x3d\x23\x59\x36\x23\xc9\x55\x90\x4a\xb2\x3a\x5b\x76\x14\xf4\x39\x28\x07\x00\x85\x28\xca\x8c\x68\xfa\x94\xd1\x2c\x55\x41\x38\x39\xd8\xe0\x5e\x6b\x56\x6a\x41\x39\x9e\x12\xc1\x95\x76\x76\x7f\x41\xf3\x02\x71\x21\x6e\x89\xb9\x1a\xf4\xc2\x68\xa9\xf3\x2c\xc0\x53\x50\x64\x24\xa1\xc1\xe0\xed\xdb\x17\xbf\x3c\x7f\xfb\x76\xb0\xe8\x75\x0e\x4e\x38\xe9\xe8\x09\x95\x44\x8a\x82\xf2\x34\xe8\x52\x44\xbe\x2a\x06\x0e\xed\x94\xe1\xda\x2c\xff\xbb\xc3\x7e\x3b\xc7\xa1\x37\x6f\x2e\x0f\xdf\xb7\xd7\xd8\xbc\x79\x33\xfd\x3d\x9c\x6c\xe3\xff\x4a\xb2\x92\x76\xf1\xaf\x71\xe8\x53\xf1\x7f\xa6\xb9\xb8\xde\x22\x20\xcd\xd8\x5e\x0a\x07\xd0\x61\x1e\x15\xc5\x17\x74\xdb\x49\x54\xeb\x98\xdd\xbc\x20\xb9\x5d\x43\x2f\x99\x72\xa7\x60\xd2\x01\x42\x72\x2f\xca\xbc\x05\x44\xb4\x96\x81\xc7\xf0\x4c\xe4\x44\x27\xcb\x60\xf0\x26\x3d\x1a\x84\x97\xc3\xa9\xc5\x74\x3c\x18\x01\x38\x60\x4e\x72\xea\xf5\x9a\x25\xcd\x76\x01\xd0\xcc\x0d\x98\x72\xfa\x78\x51\xe6\xdb\x50\x9b\xce\xc6\xac\x54\xdc\x49\xbb\xb5\xb1\x8a\xc3\x24\x13\x8a\x2a\x1d\xf8\x91\x14\x2b\x1f\xcd\x04\xb1\x82\x36\xbd\xb6\xfc\x8e\x8e\x26\x07\x9b\x83\x96\x65\x16\x44\x2a\xfa\x8a\x48\x92\xab\x40\x69\x69\xe5\x26\xa9\x2e\x25\x07\xa5\x65\xa4\x8a\x8c\xe9\xc0\xbf\x6f\x48\xa7\x65\xd2\x92\x71\x61\xb0\x7a\x60\xfe\xed\x0a\xfc\xf2\x8a\xde\xf4\xc0\x18\xc1\x14\x62\x0b\x51\x91\x8a\x7d\x14\x66\x11\xa4\x34\x11\x29\xfd\xe5\xe7\xf3\x33\x91\x17\x82\x53\x67\xc2\x60\xa1\x15\x92\x40\x5c\x43\xa4\xb1\xfe\x37\x47\x68\xf8\x3e\xf8\x0e\xd8\xb1\x6a\x71\x70\x68\xd3\x83\xf7\xb8\xf1\x4d\x7b\x93\x5b\xc7\xb3\x8e\x20\x7f\x43\xaf\x04\x31\x64\x22\x21\x08\x19\x2d\x25\x9d\x3b\x46\xbd\x7b\x5e\x78\x39\x9a\x56\x4e\xf4\x0b\x03\x5b\x6d\xd3\x2e\x5b\xbb\x8b\x9a\xd6\x8f\x7f\x2b\x0d\x21\x05\xb1\x1d\xa8\x76\x7d\xdf\x32\x3c\x17\x12\x02\x84\x66\x26\xd0\x01\x83\x93\x2d\xc4\x28\xa3\x7c\xa1\x97\x13\x60\x47\x47\xd5\x6a\x88\x40\x21\xde\x82\xbc\x64\xd3\x8a\x7a\x5c\x89\x03\x21\xaf\x20\x06\x5a\x9b\x28\x8e\x5c\x43\x0c\xb7\xc5\x1d\xd0\xcb\xd1\xd4\x9a\x9c\x8d\x13\x57\x26\x28\xcc\x24\x25\x57\xa9\x58\xf1\x3a\x3a\x58\xff\x5f\x8f\x83\x28\x90\xd3\x4b\xa3\x99\xd8\x83\x23\xb8\x86\x23\xf0\xa6\x9e\x3b\x2f\xbe\xa2\x19\x4d\x34\x4d\xfd\x1e\x34\x9f\x1d\x83\xc6\x42\x34\xe3\x25\xb5\xdf\x37\xe6\xf7\x16\x0b\xed\xf0\xd9\xe1\xe1\x56\x5c\xbd\xfe\x6c\xb2\x26\xc2\xdd\x26\xda\x04\xbe\xfd\x24\xb7\xc9\x89\xe2\x69\x26\x56\x6a\x9b\x5c\x3d\xfc\xc9\xe4\x2a\xe7\xf2\x6f\xce\xf9\x19\xb7\x30\x08\x1b\xba\x3b\x51\x51\xb3\x06\xf2\x9c\xa7\x74\x0d\x71\x3b\xca\x38\xf7\x14\x78\xdb\x0e\x1d\xd5\xd5\x42\x3a\x02\xef\xcd\x9b\xa9\x63\xf5\xca\xb1\xfa\x19\x68\x5a\xb2\xc5\x82\xca\xc0\xb3\x0e\xd8\xbb\x4d\xa1\x8a\x04\x77\x2d\x7c\xed\x02\x6f\xfb\xcc\xb6\xd2\x06\x77\x60\xe9\x35\xe5\x3a\x2a\xa4\xf9\xf7\x09\x9d\x93\x32\xd3\xe8\xe8\x0e\x00\x06\x03\xf8\x95\x64\x2c\xc5\x30\xea\x57\x0a\xf0\x61\x26\xd6\x75\xac\xa9\x46\x8d\x77\xf7\x6b\x2d\xf9\xad\x48\xd0\x05\x3c\xe7\x98\x5b\x1a\xff\x78\xce\x75\x50\x0d\xf7\x60\x34\xac\x33\x2b\xa6\x5e\x90\x17\xf5\xd4\x39\xd7\x21\x7c\xf8\xd0\xa1\x70\x02\xa3\xed\xa1\x53\x93\xcc\x0d\x2b\xed\x92\x8c\x4a\x1d\x78\xe7\x3c\x11\x52\xd2\x44\x83\x7f\x21\x0a\x30\x04\xfc\x31\x14\x19\x25\x8a\x02\xe5\x9a\x4a\x20\xe8\x52\x59\x0a\x8c\x6b\xba\xa0\x12\x66\x54\xaf\x28\xe5\x30\x02\xc2\x53\x4b\x35\xf2\xba\x8e\x71\x4e\x32\x45\x6b\x3f\x65\xdd\xa4\x15\x01\xe6\x6a\x7e\x18\x29\x2a\x19\xc9\xd8\x1f\x2e\x66\x38\x88\xa3\x18\xfc\xfb\x15\xd3\xb1\x0f\x47\x40\xf9\x2d\xff\x51\xcd\x1b\xc4\x8e\x0b\x85\x18\xbc\x7b\xa8\x71\x4b\xae\x09\x29\x86\x9d\x8e\x9e\xeb\x4c\xea\xf3\xdc\xf2\xa7\x78\xe5\xc3\x88\xbc\x23\xeb\xc0\x8a\x59\xdf\x14\x74\x0c\xde\x5f\x7e\xbc\xf0\x7a\x66\xa0\x94\xd9\x18\xbc\x81\x21\xf0\x3d\xf2\x6a\x3e\xd9\xb9\x94\x68\x72\x61\x11\x34\x5d\x6b\x87\xa1\xca\x24\xa1\x4a\x8d\xeb\x14\x33\x90\x08\xd8\x03\xa5\x89\x2e\x55\x0f\xd6\x4b\x17\x40\xab\x93\x6d\xe6\xd1\x59\x94\x3c\xa5\x73\xc6\x69\xda\xcc\xdb\xe3\x69\xf2\xda\xb7\x29\xbb\xf6\xc2\x08\x97\x0a\xbc\x17\x02\x0c\xd6\x5c\x94\xe8\x93\x2a\xef\xd1\xde\x5d\xe3\xe1\x70\x94\xa7\x54\x5a\x09\x9a\xe5\x6a\x8c\x15\x91\xfc\x99\x50\xea\x67\x94\x61\x80\xbc\x39\xf7\x61\x77\x43\xa5\x14\xb2\xb5\x97\x0e\xf3\x6a\x29\x56\xaf\x44\x51\x16\x81\x1b\x00\xf0\xce\xd1\x06\x39\xc9\x40\x51\x79\x4d\xa5\x25\xe0\x44\x83\x7f\xbd\x14\x5d\x40\x7b\xe4\x18\x2d\xb2\xf9\xba\x5e\xca\x48\x52\x55\x08\xae\xe8\x05\x5d\x6b\x37\x51\x33\xbc\x2d\x0f\x9a\x17\xfa\xa6\xf2\x65\x00\x55\x45\x88\x85\xcf\x8f\x19\xc5\x8f\x3f\xdc\x9c\x9b\xe2\x41\x69\x91\xbf\xcd\xe8\x82\xf2\xd4\x0f\x23\xc6\x39\x95\x3f\x5d\x3c\x7f\x06\x31\xf8\x7e\xe5\x33\x6d\x3e\xd4\x36\xbd\xdb\xa2\x33\xfb\x2f\x24\xc5\xf0\xfd\x8a\x14\x24\x32\xc7\xdf\xaa\x39\xd2\x92\xe5\x01\x56\x55\xce\x4a\xad\x8b\xa4\x58\xc5\x5d\x22\x4e\x84\x50\x97\xc3\xe9\xb4\x0e\xf4\xd6\xa1\x48\xb1\x02\x31\x87\x1a\xa4\x92\xb2\x9d\x5d\x12\xf5\x42\xf0\x7f\xa5\x52\x40\x0c\x52\xac\x22\x95\xb1\x84\x06\xa3\x30\x52\x22\xa7\xc1\x35\xc9\x20\x3e\xad\xf5\x62\x71\xb8\x49\x52\x0d\x6f\x4f\x33\x41\x74\x60\xc0\x3e\x7c\x00\xdf\x0f\x1b\x36\xdb\x56\x03\x5f\x58\x57\xc5\xcb\x3c\x84\xfb\xf7\x0d\x85\x2f\x62\x4c\x41\x0c\x61\x57\x53\x99\x23\xd5\x30\xd4\x98\x43\xb5\xd5\xa8\x28\xd5\x32\x90\x62\xe5\xe0\x37\xd5\x51\xc3\x58\x84\xdb\x47\x59\x4c\xf7\x25\x3a\x35\x95\x1d\x29\x0e\x22\x5f\xb2\x69\x8d\xdf\xa2\xf0\xce\xa6\x4a\xef\x5a\x14\x2e\xd9\xb4\xce\x93\xde\x35\x44\xac\x5e\x30\x14\xb6\x00\x2f\xdf\x39\x82\xce\x65\xdb\x7d\xa3\x10\xde\xd9\x8f\x0d\x36\xc0\x7a\x4b\xae\xeb\x3d\x52\x6d\xbb\xff\x75\x18\x1a\xbc\xe1\xf6\xd1\x74\x7b\xba\x7c\x87\xdb\x5a\x6f\x49\x0c\xf1\xbf\x70\x15\x2c\xfa\x60\xaa\x7e\x65\x8a\xcd\x58\xc6\xf4\x0d\x2e\xba\x67\xca\x6d\xdb\x70\xee\xec\xad\x1a\xea\xc3\xa8\xda\xca\x3e\xba\x31\x3c\x96\x92\xdc\x04\x3b\x30\xa3\x39\xcb\xb2\x40\xcb\xd2\xd5\x22\x95\x56\x2b\x49\x3e\xf9\x98\x76\x91\xe6\x2e\xcd\x22\x11\x3c\x02\x31\x5c\x56\x02\x19\x4e\x77\xe9\x78\x64\x75\xec\x80\x76\xeb\x17\xa5\xb6\x67\x73\x97\xef\x70\x1f\xd3\x06\x16\xcc\x71\x32\x16\x5b\x2d\xfc\x6e\x5a\x6b\x70\x53\xab\xa3\x31\x6f\xdc\x63\xd7\xc4\x6b\x55\xb5\x41\x5a\x92\x3b\x81\xe3\x6a\xc1\xc6\x65\xa2\x0b\xb7\x2a\x85\x2a\xfd\x8d\xe0\x95\x0d\xe4\x76\x00\x88\x06\xfc\xae\x41\x70\x0a\xf3\x4c\xac\x40\x0b\x48\x99\x2a\x32\x72\x03\x7a\x49\x21\x41\x87\x1e\x79\xbd\xda\xab\x3a\xc6\xef\xf4\x91\x7f\xca\x43\x76\x82\xa7\x53\xbe\xe1\xc2\x69\xde\xb5\x44\xae\x99\x2a\x31\x5f\xc0\xa0\xc2\x23\x82\xe6\x74\x21\x10\xe4\x82\xcc\x32\xea\x8a\x5b\x2b\xa7\xc6\x55\xda\x8a\x01\xbd\xaa\x95\x15\x53\xaf\x35\x49\xae\x68\x3a\xb6\x19\x81\x8d\x11\x9a\xe9\x8c\x8e\xc1\xc7\xdc\x07\x9e\xcf\x0a\xe5\xb7\xc6\x31\x60\xbc\xd6\x37\x08\x50\x29\x78\x2e\xb8\x7e\xcd\xfe\xa0\x63\x38\x7e\x58\x45\x99\x99\xc8\xd2\x31\xa0\x19\x57\x23\x89\xc8\x30\xce\xf9\xf7\x1e\x3c\x78\xe0\xb7\x03\xe0\xf2\xf1\x9a\xa9\x86\x5a\xb5\x3a\xf6\xd4\xdc\xc2\x00\x2a\x23\x5c\xd3\x14\x17\xef\x52\x6d\x4d\x3c\xe6\x0b\xe4\xea\x9b\x3a\xd2\xa1\x25\x20\xc6\x8f\xd7\x54\xde\x8c\x61\x54\x4f\xec\xdb\xc8\x16\x93\x15\x38\x00\xd3\x24\x63\x49\x47\x48\xbb\x37\xd9\x16\xc6\xe8\x3b\x37\xe8\xf6\x09\xb0\x90\x2c\xcd\x18\xa7\x6a\xe7\x92\xf3\x07\xf8\x53\x6f\x19\x99\x29\xb9\x46\xbe\xb7\xe9\xe4\x8c\x0b\xf9\x97\x3b\x89\xd1\x47\xf8\xe3\x6f\x63\xea\xcf\xd8\x74\x6b\x27\xc7\xdd\xc3\x6a\x61\xae\xbb\x7a\xcb\x19\x37\x8d\x95\x31\x74\xe5\x3c\x06\xff\x39\x5d\x90\x19\xd3\x0a\x0a\x2a\x41\xd1\x44\xf0\xd4\xff\x87\x2e\xfe\x2f\x75\xb1\xa4\x6c\xb1\xd4\x63\x50\x89\xa4\x94\x47\xf6\x2b\x7c\x05\xc3\xe8\x5b\xbb\x07\xe3\x41\x1e\x4b\x4a\x9a\xc5\x56\x2c\xd5\xcb\x31\xf8\x8f\x86\x5f\xfa\x3d\x70\x83\x15\x21\xff\x5b\x1c\x75\x83\x5a\x14\x63\xf0\xbf\x6e\x06\xb0\x5d\xbb\x90\x98\x18\x9f\xd9\x4d\x36\x1b\x50\x5a\x8a\x2b\x3c\xbf\xf7\x92\x24\xf1\x7b\x5b\xe3\xbf\xd9\x35\x47\xbb\xf6\x70\x8b\xa8\x7f\x6f\x6e\xfe\x38\x2a\x46\x34\x0a\x1b\xd1\xf7\x8e\x47\x8f\xbe\x79\xfa\x00\xfb\x1a\xf7\x1e\x9e\x3d\x7e\xfa\xf5\xd0\x7c\x7c\xfa\xf4\x6c\x34\xfc\xd6\x7d\xfc\xfa\xdb\xe3\x63\xf3\xf1\xd1\xd9\xf1\xb7\x3f\x0c\xfd\xa9\x25\x42\x38\xcb\x8d\xcb\x6c\x78\x36\x77\x02\x65\xd1\x35\x9e\xb4\x94\x0e\x6c\xd4\xca\x9d\x29\x51\x8c\x2f\xc6\xe0\x8b\x52\x77\x7c\x98\xf5\xe8\x0d\xcd\x42\x28\x66\xd1\x7d\x2e\x38\xed\xc0\x6a\x21\x32\xcd\x8a\x06\xf8\xcf\x1a\x42\x35\x8b\xfe\xce\x88\xee\x4c\xa4\xd4\x6e\xa7\xc3\x23\xe3\x95\xfc\x8f\xed\x48\x21\x58\x4d\xcd\x95\x56\x26\x5c\x36\x7c\x0c\xc7\xf0\x1e\xd0\xb2\x9f\x10\xb5\x74\x3c\x5e\x3e\xec\xc1\xc3\x69\xb3\xec\x68\x17\xcc\x71\x0f\x8e\x5b\x30\xc7\x7b\xe8\xb4\x61\x1e\xec\xa1\xd3\x5e\xeb\xe1\x2e\x98\x51\x0f\x46\xd3\xca\x96\x30\x13\xae\xe3\x9d\xb1\x7a\x88\xcd\x1d\xd3\xce\xb8\x89\x27\xc2\x16\x1e\xfb\x63\x76\x15\xe5\x7d\x9b\xcc\x1b\x9a\x11\x96\xcb\x41\x1d\x96\x7b\x55\x64\xb5\xa1\xd6\x16\x34\xcf\x8c\x4d\xd8\x70\xec\x3a\xc0\xdd\x8a\xa7\x03\x00\xef\xdb\x3d\xf0\x4c\xac\x5e\x6b\xa2\xd5\x76\x1e\x9e\x51\x6d\x72\xbd\x51\x93\xeb\x35\x59\x50\x27\xdd\x03\x40\xd8\x9c\x60\x46\xdc\x3f\xe7\x73\xc6\x99\xbe\xa9\x92\xae\x9a\xd6\x56\xbe\xb7\x3b\xd9\xab\xb8\xc2\xe2\xc6\x65\xbb\xef\xa6\x97\x6c\xda\x4d\xc2\xb1\xb0\x17\x73\xec\x8c\x40\x8c\x5d\x3e\x5e\xe6\x33\x2a\x3d\x4c\xf0\x5d\xd1\x73\x4d\xb2\x56\x2f\xad\xc2\x43\x84\x53\xc8\xc9\x3a\x74\xdc\x5e\x93\xac\xa1\xbc\x39\xe8\xfe\x5b\x4b\xc6\xe6\x87\x0d\x31\x86\xed\xac\x31\xb0\xca\x52\x00\x32\x32\xa3\xd9\xb8\x92\xd1\x25\x73\x1e\x00\xff\xe6\x64\x3d\xb6\xab\xc5\x2d\xe9\xc0\xf7\x30\x04\x33\xee\x00\x37\x61\xa7\xd1\x37\x18\xc0\x6b\x21\x35\xe3\x0b\xc8\xc4\x82\x25\x75\xa9\x56\xd5\x0f\xd6\x0b\x20\x4c\x08\xb7\x86\x30\xe9\x82\x2b\x7a\x33\x06\xcf\x70\xe6\xf5\x80\xa8\xc4\x1e\x53\x34\xd9\x46\xce\x4a\x48\xfd\xcf\x14\xab\x84\x5b\x44\xa2\x2b\x7a\xb3\x0d\xfa\x58\x25\x3b\x41\x89\x4a\x5c\x53\xb9\x11\x1a\x92\x0e\x02\xd2\x83\x59\xd8\x2e\x67\xd5\x8a\xe9\x64\x09\x81\x5b\xb9\xa3\x7a\xcc\x96\x1d\xc7\xe3\x7a\xb4\xae\x67\x1d\x03\xad\x09\x80\xef\x81\x44\x06\x21\xc2\x66\x54\x46\xf1\x62\x81\x48\x1a\xcc\xec\x68\xd8\x01\x1e\xc3\x6c\x27\xb0\x23\x11\x4e\xb6\x38\xc9\xc9\xfa\x73\xf8\x40\x25\xf7\x61\x16\x35\x4a\x6d\x96\xb5\x73\x04\xe7\x9a\x55\x52\xdb\xd0\xdc\xb1\xc4\x56\x5d\xe9\x6e\x75\x2a\x4d\xd8\xd4\xde\x1e\xe9\x27\xcc\x74\xfa\x3f\xb1\x0a\x70\xfa\xec\xe2\x77\x4a\x83\xdf\x0d\xc4\x49\xca\xae\x21\xc9\x88\x52\xb1\x67\xd5\xdc\x5f\xd2\xac\xe8\x6b\x56\x78\xa7\x8e\xb5\x13\x0c\xbf\x7c\x71\xfa\x8b\x22\x0b\x3a\x3e\x19\xb8\xaf\x27\x33\xd9\x40\x14\x04\xaf\x94\x6e\x32\x1a\x7b\x26\xa4\x8e\x5d\x38\x9d\x60\x6c\xe9\xaf\x6c\x02\x80\xa9\xed\xc4\x3b\xfd\xfb\xbf\xff\x27\x9c\xe1\xcd\x17\x10\xe3\x94\x4e\x06\xaa\x20\xfc\x14\xab\x24\x8c\x36\x20\x78\x86\x75\x12\xd1\x66\x36\xaa\x60\x17\x84\xf1\x1a\x86\x64\x59\xf4\x11\x06\x6c\x10\xdf\xcb\x80\x96\xd9\xe0\x2c\x4f\x07\x2f\x8d\x93\x85\x23\xbb\x4c\x8b\x17\x92\xa6\x20\x24\xd8\x4b\x37\xc3\x8a\xfa\xd8\x92\x36\x43\xf8\xd8\x9e\x13\x91\x95\x39\x87\x25\x25\x29\x95\xed\xcd\x0b\xa9\x4d\x7d\x68\x15\x11\x99\xbd\x9d\x0c\x52\x76\x6d\xd7\xfc\xbd\x6b\x1b\xb8\x24\xfa\x72\x17\x25\x22\xb3\x6d\xd5\x3e\xc8\x1a\xc3\x44\xdb\x68\x12\x49\x89\xa6\xce\x6e\x02\xdf\xcc\x57\xb6\x62\xbe\x44\xc6\x14\x9e\x31\xa5\x23\x92\xa6\x15\x44\x0f\xec\x87\xbe\xca\x9b\xcf\x33\x21\x53\x2c\x9a\x6b\x5b\x33\x5c\x69\xdc\xd5\x5d\x4b\xe2\x7c\x17\x05\x47\x7e\x16\xab\xbb\x90\xa4\x5f\x9d\x8b\xba\xbf\x97\x93\x2b\xfa\x93\x91\xe0\x19\xc5\xde\x06\x5d\xeb\x1e\x5c\xb5\xdd\x8c\x93\xc1\xf2\x4e\x6e\x2a\x56\x00\xf4\xd2\xf4\x68\xcf\x04\xd7\xd4\xdc\x0e\xe0\xb7\xd6\xa4\xd1\x74\x94\x94\x52\x09\x89\x95\xb5\xc9\x73\xa8\xf4\x6f\x81\x94\x8a\xca\xd7\xb6\x13\x10\xbb\x4c\xad\x05\x43\xd2\xf4\x47\xbc\xe3\x40\x19\x53\x4e\x65\xe0\x9b\x4b\x60\xbf\x07\x01\xed\xb8\xcf\x4e\x3f\xa4\xeb\xac\x4d\x84\xe9\xec\xb5\xd5\x17\xea\x3a\x6b\x88\xe1\x76\x20\xc1\x89\x8a\x25\x80\x0d\xd0\x4c\xd1\x8f\x90\x32\xab\x42\x1d\x28\xec\xcf\x60\x00\xee\xaa\x66\x6c\x8d\x77\x76\x03\xcf\x1f\xff\xd5\x94\xf7\x90\x52\x95\x50\x9e\x32\xbe\xe8\xc1\xd3\x67\x2f\x7f\xc3\xc0\x64\xbf\xdf\xb9\x90\xe5\x39\xa8\x76\xe9\x1b\x87\xdd\x68\xa9\x09\xda\x7b\x32\xa2\x6a\x92\x46\x4a\x8b\xe2\x95\x14\x05\x59\x98\xa4\xbb\x99\xac\x22\xf0\x67\x4a\x78\xcb\x40\x8e\xf6\x84\x47\xf8\x1e\x7c\xf8\xfb\x7f\xfc\x97\x0f\x63\xf3\xe1\xbf\xfd\xc9\x56\xba\xe1\x5c\xbf\x5e\x76\x12\x01\x77\x0c\xdc\xc3\x8e\xb3\x25\xcb\xd2\xe0\x2e\xbb\x0d\x27\x78\x33\x66\x0e\x3d\x24\x34\xcb\x20\x30\xed\xa2\x70\x2f\xb1\xad\x13\xe3\xa3\x52\xfc\x5e\x2d\xe1\x70\xf2\xc9\x98\x95\x8e\x11\x3b\x27\xeb\x1a\xd7\x1c\xfe\x0e\xa6\xa3\xd6\x8d\x6a\x7a\x26\xd2\x9b\xbb\x4e\x25\xce\x37\x27\xbe\x69\xb6\xe3\x55\x0c\x76\xdb\xeb\xd4\xa3\x51\x8f\x05\x60\x10\x9b\xfb\x9a\xc8\x24\x6e\x95\xdc\x9b\x4e\xfd\x1d\x6b\xca\xc6\xc6\x5a\xfe\x15\x25\x75\x17\x56\xed\xca\x5c\x6d\x85\xf0\xce\x0d\x6c\x55\x9e\x10\x3b\x8f\x7d\x19\x30\xec\x65\x86\xf0\xa5\x1b\x70\xf9\xf1\x74\x1f\x21\x53\x52\xa3\xc7\x39\x1e\x16\xeb\xda\x98\x2c\x93\x46\x77\x9f\xc1\x64\x0d\xbf\xe5\xeb\x8c\xd0\xcc\x64\x97\x7e\x4e\xd6\x9f\x41\xdd\x41\xef\xa2\x9d\x93\x75\xa4\xc5\x53\xb6\xa6\x69\x30\xaa\x11\xe4\x96\xa1\xd5\x5b\xdf\x0b\x51\x6f\x60\x2f\x84\x63\xa2\x9e\x37\xd6\xd6\x81\x90\x8d\x41\xde\xdd\xaa\xbf\x64\x3b\xbb\xce\x56\xbf\xa2\x20\x09\xe6\xf7\x31\xf8\xc3\xe8\x61\x73\xc8\xb7\xbd\xe9\x4e\x8c\x51\x34\x6c\x30\x2a\x56\x10\x72\x7f\x78\xa8\x22\x5f\x60\xae\xc8\x77\x14\x52\xd8\x2c\xc7\x54\xd7\x3e\xb4\xdc\xb3\x25\xbc\x09\xc0\x47\x04\x3f\x08\x91\x51\xc2\xc3\xaa\x38\xab\x89\xa1\x3c\xcc\x0a\x51\xa2\x65\x86\x15\xc3\x87\x0f\x60\x07\x72\xaa\x49\x67\x80\x64\xdb\x89\x7d\xc7\xa5\xee\x11\x27\xdc\xbf\xbf\xc5\x6c\x1c\x37\xb7\x1b\xd5\x9f\x3f\x7f\xcb\x01\xb0\x47\x23\x77\x90\xaf\x58\x6c\xa2\xe6\x1e\x88\xce\x02\x07\x77\x2c\xf5\xff\x5b\x1e\x1f\x27\x6f\x3a\xa6\xe1\xe4\x53\x88\x55\xfb\x8d\x4d\x79\xda\x61\x69\x6f\x74\xdf\x75\xf7\xbd\x55\x41\x9b\x74\xb4\x73\xc0\x35\x86\x9d\x70\xb2\x6f\x16\x1d\x82\x9b\xdd\x2a\xb0\xba\x70\x88\xe9\x2e\x8a\x36\x07\x07\x83\x01\x64\xd5\x85\xfb\x6f\x44\x72\xc6\x17\x17\x4b\x49\xd5\x52\x64\x29\x30\x65\x12\x77\xb5\x24\x92\x62\x84\xca\x04\xd6\xd1\x24\x2f\x32\xaa\x80\xcc\xf0\x05\xe4\x6a\xc9\x92\x25\x48\xaa\xca\x4c\x2b\x40\x38\x3c\xb7\xcc\xe4\xd0\xc0\x78\x22\x10\x58\xd3\x03\xe7\xd8\xf7\x2e\x15\xc3\x30\x1a\x8e\xda\x0f\x50\x6f\xbf\x05\x68\xbd\x43\xad\x79\xee\x5e\x7e\xe2\x75\xfd\x82\xea\x9f\xdd\x8d\xbd\xcd\x0b\x02\xef\xaf\x7d\xbc\x9f\x59\x8a\x52\xd1\x3e\x92\xec\x1b\x54\xaf\x79\x6e\x6c\x9b\x2e\x35\x51\xf3\x24\xa6\x59\xe2\x64\x3f\xdf\x95\x3d\x77\x6e\xa2\x6e\xbd\x45\xf0\x7e\x29\xb0\xc4\xc3\x17\x1b\xcd\x22\x60\xde\x3c\x87\xad\xf8\x81\x2f\x8c\xbe\x44\x39\x1b\xa1\x3b\x31\x9b\xaf\xa8\x08\x96\x53\x90\xf8\x5c\x01\x56\x54\x52\x64\x49\x03\x96\x8e\x92\x70\xc5\x74\x04\x17\x92\xcc\xe7\x2c\x81\x9c\xdc\xc0\x8c\x42\x89\x59\xa4\x69\xc5\xd3\x34\x72\x0f\x1c\x3c\x94\x29\xe3\x0b\xef\x00\x60\xeb\x61\xc1\xf6\xa3\x6e\x7c\x97\xd4\x79\x59\x89\x03\x91\x16\xe7\xaf\x5f\xbe\xd6\x92\xf1\x45\x10\x46\xaa\x9c\x29\x2d\x83\x61\x0f\x46\xdf\x84\x1d\x6a\xcd\xfe\x73\xaa\xb0\xbe\xee\x01\xf6\xbc\xe2\xea\x66\xb0\x67\xb6\x23\x4a\x1d\x8f\xbe\x1e\x0e\x87\x3d\x48\xa9\x26\x2c\x53\x31\x2f\xb3\xac\xad\x67\x7c\x45\x46\x18\xa7\xd2\x3d\x35\xc6\x57\xcb\x45\xbf\x1e\xf5\x5a\x8f\xa0\xcc\xa3\xa4\x73\x7c\xda\xe0\x59\x28\x14\x37\xbe\x3b\x8f\xb8\x58\x05\x21\x1c\xc1\x73\xa2\x97\xd1\x3c\x13\x42\x06\xe6\xa3\x24\x3c\x15\x79\x10\x7e\x85\x7d\x6b\xfb\x42\x0a\x1b\x81\x8e\x97\x9f\x74\x8e\x3d\x3c\xcf\xab\x8c\xc4\x8d\x57\x2a\xb7\xab\xba\x41\xb3\x6e\xc5\xc1\x11\x78\x7d\x37\xee\xb9\x5b\xce\x0e\xc9\xdf\xdd\x89\x3f\xa9\x6b\x60\xfc\x39\x21\x80\x6f\x86\x62\x7c\x85\x64\x22\x6b\xec\x99\x8a\x5b\xe1\x5d\xc6\xe8\xb8\x58\x4f\x3c\x10\xdc\xc4\xcd\xd8\x3b\x0c\xfc\x7b\x87\xef\x1d\xd9\xf3\x74\x83\x8f\x28\xc4\x62\x61\x8a\x85\x4a\x63\xc6\x87\x4d\xbc\xd3\xd7\xd8\x7d\x78\x62\x41\x4f\x06\xa4\xb5\x62\x21\x29\xb0\x34\xf6\xda\x94\xea\xc5\x6b\x30\xa8\x6e\x79\xc7\x58\xf5\xb5\x1d\x5c\x93\x0c\x8e\xef\xcd\xbf\x9b\x3f\x9a\x93\xce\xac\x29\xa5\xc7\xa3\x62\x0d\x4a\x64\x2c\x85\x7b\x94\x76\xd0\x0b\x92\x62\xe1\x64\x37\xd7\x1a\xcf\x89\x5c\x30\xde\xc7\xab\x94\x87\xdd\x99\x96\x40\x1e\xec\x98\x99\x93\x9c\x65\x37\x63\xc8\x05\x17\xaa\x20\x09\xed\x35\x1f\xdb\xc0\x39\x59\xf7\xdd\xed\xcd\xf1\xd7\xc3\x2e\x21\x71\x4d\x25\x66\xe2\x63\x52\x6a\xd1\x9e\x58\x2d\x99\xa6\x7d\x43\x76\x5c\x48\xda\x5f\x49\x52\x74\xe6\x85\x4c\xfb\xe6\xc5\xe9\xd8\xfc\xee\x93\xac\xd5\x9c\xb5\x77\x0a\xd5\xaa\xa3\xe8\x61\x7b\xca\x36\x1d\xfa\x92\xa4\xac\x54\xdd\x3d\x7b\xa7\x87\xef\x0f\x03\xdf\x98\x8a\xef\xde\x5b\x39\x65\x55\xef\xec\x37\x27\x83\x42\xd2\x4a\xad\xdd\xce\x0a\xfa\xa3\xce\x01\x31\xe7\xa8\xd5\x24\xb3\xea\x77\x96\xbb\xf1\xaa\xa6\x99\x19\x00\xf3\xbb\x7f\xf8\x1e\xcf\xee\xc6\x7d\x4b\x99\xca\x99\x32\x71\x1d\xe6\x24\xa5\xc6\xd7\x79\x20\x05\xf6\xa8\x0c\x48\x6d\x41\x4e\x8f\x33\xa1\xb5\xc8\xc7\xdf\x15\xeb\x09\xb8\xf6\x42\xdf\xa4\x5e\xca\xca\xb8\xe9\xc8\x21\xe7\x87\xef\x9d\xcb\xd8\xb4\x76\x02\x50\x9b\x28\x9e\xa1\x2a\x9c\x9e\xcc\x4a\xad\x05\x77\xbe\xc5\x7e\xa9\xb7\x60\x9e\x99\x7b\xa6\xa7\x5d\x31\x5d\x33\x48\x24\x23\x7d\x93\x74\xc7\xde\x99\x85\x73\x3c\xdf\xc9\x60\xd5\x1e\x33\xe8\x4b\x96\xa6\x94\xc7\x1e\x46\x7d\xef\xf4\x3e\x3a\x34\x35\x71\x7d\xaf\x8a\xbf\x81\xe5\xe9\xb4\xdb\xf3\xaa\xff\x3f\x81\x75\x62\x2e\x3c\x07\x86\x37\x33\xa5\xec\x7f\x93\x11\xa5\x0e\x82\x56\xf7\xc4\x00\x44\xe6\x37\x26\xd0\x42\x51\x5b\xa7\x6d\x6a\x77\xba\xe5\xd4\xef\x78\xc1\xdf\x0d\x5c\x70\x88\x77\x39\xff\xf4\xfa\xe5\x8b\xc0\x1b\xa4\x2c\xd1\xf6\x0d\xac\x1a\x78\x47\x06\xba\x95\xa7\xb7\x9f\x83\xdd\xf9\x6e\xd6\xad\xd7\x3c\x9b\x45\x65\x57\xf9\x40\xeb\x36\x02\xff\x2a\x51\xca\x84\xda\x0b\x88\xe6\xea\xa1\xce\x88\xc2\xc9\xc1\xe6\xe0\x7f\x06\x00\xad\xa3\x43\x56\x77\x35\x00\x00")
 
 func assetsFlowhouseJsBytes() ([]byte, error) {
 	return bindataRead(
@@ -93,7 +93,7 @@ func assetsFlowhouseJs() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "assets/flowhouse.js", size: 13150, mode: os.FileMode(432), modTime: time.Unix(1748415000, 0)}
+	info := bindataFileInfo{name: "assets/flowhouse.js", size: 13687, mode: os.FileMode(432), modTime: time.Unix(1748415000, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -22,6 +22,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// lossRatioHeader carries the highest loss ratio of the flows a query result is based on
+const lossRatioHeader = "X-Flowhouse-Loss-Ratio"
+
 var (
 	fields []struct {
 		Name       string
@@ -51,6 +54,26 @@ func init() {
 			Label:      "Interface Out",
 			ShortLabel: "Int.Out",
 		},
+		{
+			Name:       "vlan_in",
+			Label:      "VLAN In",
+			ShortLabel: "VLAN.In",
+		},
+		{
+			Name:       "vlan_out",
+			Label:      "VLAN Out",
+			ShortLabel: "VLAN.Out",
+		},
+		{
+			Name:       "vlan_outer",
+			Label:      "Outer VLAN Tag",
+			ShortLabel: "VLAN.Outer",
+		},
+		{
+			Name:       "vlan_inner",
+			Label:      "Inner VLAN Tag",
+			ShortLabel: "VLAN.Inner",
+		},
 		{
 			Name:       "tos",
 			Label:      "Type of Service",
@@ -116,13 +139,89 @@ func init() {
 			Label:      "Destination Port",
 			ShortLabel: "Dst.Port",
 		},
+		{
+			Name:       "tcp_flags",
+			Label:      "TCP Flags",
+			ShortLabel: "TCP.Flags",
+		},
+		{
+			Name:       "icmp_type",
+			Label:      "ICMP Type",
+			ShortLabel: "ICMP.Type",
+		},
+		{
+			Name:       "icmp_code",
+			Label:      "ICMP Code",
+			ShortLabel: "ICMP.Code",
+		},
+		{
+			Name:       "fragment",
+			Label:      "Fragment",
+			ShortLabel: "Frag.",
+		},
+		{
+			Name:       "application",
+			Label:      "Application",
+			ShortLabel: "App.",
+		},
+		{
+			Name:       "vrf_name_in",
+			Label:      "VRF In",
+			ShortLabel: "VRF.In",
+		},
+		{
+			Name:       "vrf_name_out",
+			Label:      "VRF Out",
+			ShortLabel: "VRF.Out",
+		},
+		{
+			Name:       "mpls_top_label",
+			Label:      "MPLS Top Label",
+			ShortLabel: "MPLS.Top",
+		},
+		{
+			Name:       "mpls_bos_label",
+			Label:      "MPLS Bottom Label",
+			ShortLabel: "MPLS.Bottom",
+		},
+		{
+			Name:       "tunnel_type",
+			Label:      "Tunnel Type",
+			ShortLabel: "Tun.Type",
+		},
+		{
+			Name:       "tunnel_key",
+			Label:      "Tunnel Key/VNI",
+			ShortLabel: "Tun.Key",
+		},
+		{
+			Name:       "outer_src_addr",
+			Label:      "Outer Source IP",
+			ShortLabel: "Outer.Src.IP",
+		},
+		{
+			Name:       "outer_dst_addr",
+			Label:      "Outer Destination IP",
+			ShortLabel: "Outer.Dst.IP",
+		},
+		{
+			Name:       "inner_src_addr",
+			Label:      "Inner Source IP",
+			ShortLabel: "Inner.Src.IP",
+		},
+		{
+			Name:       "inner_dst_addr",
+			Label:      "Inner Destination IP",
+			ShortLabel: "Inner.Dst.IP",
+		},
 	}
 }
 
 // Frontend is a web frontend service
 type Frontend struct {
-	chgw     *clickhousegw.ClickHouseGateway
-	dictCfgs Dicts
+	chgw          *clickhousegw.ClickHouseGateway
+	dictCfgs      Dicts
+	windowSeconds int64
 }
 
 // IndexView is the index template data structure
@@ -162,11 +261,12 @@ func (d Dicts) getDict(field string) *Dict {
 // Dicts is a slice of dicts
 type Dicts []*Dict
 
-// New creates a new frontend
-func New(chgw *clickhousegw.ClickHouseGateway, dictCfgs Dicts) *Frontend {
+// New creates a new frontend. aggregationWindow is the length of the windows flows are aggregated in.
+func New(chgw *clickhousegw.ClickHouseGateway, dictCfgs Dicts, aggregationWindow time.Duration) *Frontend {
 	return &Frontend{
-		chgw:     chgw,
-		dictCfgs: dictCfgs,
+		chgw:          chgw,
+		dictCfgs:      dictCfgs,
+		windowSeconds: int64(aggregationWindow / time.Second),
 	}
 }
 
@@ -230,6 +330,10 @@ func (fe *Frontend) QueryHandler(flatCSV bool) func(http.ResponseWriter, *http.R
 			return
 		}
 
+		if res.lossRatio > 0 {
+			w.Header().Set(lossRatioHeader, strconv.FormatFloat(float64(res.lossRatio), 'f', 4, 32))
+		}
+
 		if flatCSV {
 			err = res.csvFlat(w)
 		} else {
@@ -301,9 +405,15 @@ func (fe *Frontend) processQuery(r *http.Request) (*result, error) {
 			return nil, fmt.Errorf("expected float64 for the last column")
 		}
 
+		lossRatio, ok := (*valuePtrs[len(columns)-2].(*interface{})).(float32)
+		if !ok {
+			return nil, fmt.Errorf("expected float32 for the loss ratio column")
+		}
+		res.addLossRatio(lossRatio)
+
 		if rowCount < rowLimit { // Process the top flows normally (sorted by mbps descending)
 			keyComponents := make([]string, 0)
-			for i := 1; i < len(columns)-1; i++ {
+			for i := 1; i < len(columns)-2; i++ {
 				label := getReadableLabel(columns[i])
 
 				switch (*valuePtrs[i].(*interface{})).(type) {
@@ -399,7 +509,8 @@ func (fe *Frontend) fieldsToQuery(fields url.Values) (string, error) {
 
 		selectFieldList = append(selectFieldList, fmt.Sprintf("%s as %s", statement, fieldName))
 	}
-	selectFieldList = append(selectFieldList, "sum(size * samplerate) * 8 / 10 / 1000000 AS mbps")
+	selectFieldList = append(selectFieldList, "max(loss_ratio) AS loss_ratio")
+	selectFieldList = append(selectFieldList, fmt.Sprintf("sum(size * samplerate) * 8 / %d / 1000000 AS mbps", fe.windowSeconds))
 
 	conditions := make([]string, 0)
 	conditions = append(conditions, fmt.Sprintf("t BETWEEN toDateTime(%d) AND toDateTime(%d)", start, end))
@@ -488,7 +599,12 @@ func prefixMultiValueCondition(statement string, fields url.Values, fieldName st
 }
 
 func isIPField(fieldName string) bool {
-	return fieldName == "nexthop" || fieldName == "src_ip_addr" || fieldName == "dst_ip_addr" || fieldName == "agent"
+	switch fieldName {
+	case "nexthop", "src_ip_addr", "dst_ip_addr", "agent", "outer_src_addr", "outer_dst_addr", "inner_src_addr", "inner_dst_addr":
+		return true
+	}
+
+	return false
 }
 
 func isPrefixField(fieldName string) bool {
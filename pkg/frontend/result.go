@@ -13,6 +13,9 @@ type void struct{}
 type result struct {
 	keys map[string]void
 	data map[time.Time]map[string]uint64 // timestamps -> keys -> values
+
+	// lossRatio is the highest ratio of samples lost by the exporters of the flows in the result
+	lossRatio float32
 }
 
 func newResult() *result {
@@ -31,6 +34,12 @@ func (r *result) add(ts time.Time, key string, value uint64) {
 	r.data[ts][key] = value
 }
 
+func (r *result) addLossRatio(lossRatio float32) {
+	if lossRatio > r.lossRatio {
+		r.lossRatio = lossRatio
+	}
+}
+
 func (r *result) csv(w io.Writer) error {
 	cw := csv.NewWriter(w)
 	defer cw.Flush()
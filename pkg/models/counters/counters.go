@@ -0,0 +1,37 @@
+package counters
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// InterfaceCounters defines a snapshot of an interfaces counters as exported by an agent
+type InterfaceCounters struct {
+	Agent              bnet.IP
+	Timestamp          int64
+	IfIndex            uint32
+	IntName            string
+	IfType             uint32
+	IfSpeed            uint64
+	IfDirection        uint32
+	IfStatus           uint32
+	InOctets           uint64
+	InUcastPkts        uint32
+	InMulticastPkts    uint32
+	InBroadcastPkts    uint32
+	InDiscards         uint32
+	InErrors           uint32
+	InUnknownProtos    uint32
+	OutOctets          uint64
+	OutUcastPkts       uint32
+	OutMulticastPkts   uint32
+	OutBroadcastPkts   uint32
+	OutDiscards        uint32
+	OutErrors          uint32
+	AlignmentErrors    uint32
+	FCSErrors          uint32
+	SingleCollisions   uint32
+	MultipleCollisions uint32
+	LateCollisions     uint32
+	FrameTooLongs      uint32
+	SymbolErrors       uint32
+}
@@ -6,36 +6,109 @@ import (
 	bnet "github.com/bio-routing/bio-rd/net"
 )
 
+// Tunnel types of decapsulated flows
+const (
+	TunnelTypeNone = iota
+	TunnelTypeGRE
+	TunnelTypeVXLAN
+	TunnelTypeIPIP
+)
+
+// FiveTuple identifies the outer or inner packet of a tunneled flow
+type FiveTuple struct {
+	SrcAddr  bnet.IP
+	DstAddr  bnet.IP
+	SrcPort  uint16
+	DstPort  uint16
+	Protocol uint8
+}
+
 // Flow defines a network flow
 type Flow struct {
-	Agent      bnet.IP
-	TOS        uint8
-	SrcPort    uint16
-	DstPort    uint16
-	SrcAs      uint32
-	DstAs      uint32
-	NextAs     uint32
-	IntIn      string
-	IntOut     string
-	Packets    uint64
-	Protocol   uint8
-	Family     uint8
-	Timestamp  int64
-	Size       uint64
-	Samplerate uint64
-	SrcAddr    bnet.IP
-	DstAddr    bnet.IP
-	NextHop    bnet.IP
-	SrcPfx     bnet.Prefix
-	DstPfx     bnet.Prefix
-	VRFIn      uint64
-	VRFOut     uint64
+	Agent       bnet.IP
+	SubAgentID  uint32
+	TOS         uint8
+	SrcPort     uint16
+	DstPort     uint16
+	SrcAs       uint32
+	DstAs       uint32
+	NextAs      uint32
+	IntIn       string
+	IntOut      string
+	VLANIn      uint16
+	VLANOut     uint16
+	VLANOuter   uint16
+	VLANInner   uint16
+	Packets     uint64
+	Protocol    uint8
+	TCPFlags    uint8
+	ICMPType    uint8
+	ICMPCode    uint8
+	Fragment    bool
+	Family      uint8
+	Timestamp   int64
+	Size        uint64
+	Samplerate  uint64
+	SrcAddr     bnet.IP
+	DstAddr     bnet.IP
+	NextHop     bnet.IP
+	SrcPfx      bnet.Prefix
+	DstPfx      bnet.Prefix
+	VRFIn       uint64
+	VRFOut      uint64
+	Application string
+	VRFNameIn   string
+	VRFNameOut  string
+	ASPath      []uint32
+	Communities []uint32
+
+	// MPLSTopLabel is the outermost label of the label stack (usually identifying the LSP)
+	MPLSTopLabel uint32
+
+	// MPLSBottomLabel is the innermost label of a label stack of two or more labels (usually the VPN label)
+	MPLSBottomLabel uint32
+
+	// TunnelType is the encapsulation of decapsulated flows. Outer and Inner are only set for these.
+	TunnelType uint8
+
+	// TunnelKey is the VXLAN VNI or GRE key
+	TunnelKey uint32
+	Outer     FiveTuple
+	Inner     FiveTuple
+
+	// LossRatio is the ratio of datagrams or samples of the exporter lost in transit around the time of the flow
+	LossRatio float32
+}
+
+// FiveTuple gets the 5-tuple of the flow
+func (fl *Flow) FiveTuple() FiveTuple {
+	return FiveTuple{
+		SrcAddr:  fl.SrcAddr,
+		DstAddr:  fl.DstAddr,
+		SrcPort:  fl.SrcPort,
+		DstPort:  fl.DstPort,
+		Protocol: fl.Protocol,
+	}
+}
+
+// SetFiveTuple sets the 5-tuple of the flow
+func (fl *Flow) SetFiveTuple(t FiveTuple) {
+	fl.SrcAddr = t.SrcAddr
+	fl.DstAddr = t.DstAddr
+	fl.SrcPort = t.SrcPort
+	fl.DstPort = t.DstPort
+	fl.Protocol = t.Protocol
 }
 
 // Add adds up to flows
 func (fl *Flow) Add(a *Flow) {
 	fl.Size += a.Size
 	fl.Packets += a.Packets
+	fl.TCPFlags |= a.TCPFlags
+
+	if a.LossRatio > fl.LossRatio {
+		fl.LossRatio = a.LossRatio
+	}
 }
 
 // Dump dumps the flow
@@ -37,27 +37,21 @@ func errorIncompatibleVersion(version uint16) error {
 
 // Decode is the main function of this package. It converts raw packet bytes to Packet struct.
 func Decode(raw []byte) (*Packet, error) {
-	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
-
-	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
-
-	if pSize > bufSize {
-		panic("Buffer too small")
+	if uintptr(len(raw)) < sizeOfHeader {
+		return nil, errors.Wrapf(ErrTruncated, "Message is too short: %d", len(raw))
 	}
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
-	}
+	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
+
+	// copy data as templates point into the buffer while raw is reused by the caller
+	buffer := make([]byte, len(data))
+	copy(buffer, data)
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeader))
+	bufferMinPtr := uintptr(unsafe.Pointer(&buffer[0]))
+	headerPtr := unsafe.Pointer(&buffer[uintptr(len(buffer))-sizeOfHeader])
 
 	var packet Packet
-	packet.Buffer = buffer[:]
+	packet.Buffer = buffer
 	packet.Header = (*Header)(headerPtr)
 
 	if packet.Header.Version != 10 {
@@ -68,29 +62,43 @@ func Decode(raw []byte) (*Packet, error) {
 	packet.Templates = make([]*TemplateRecords, 0, numPreAllocRecs)
 	packet.OptionsTemplateRecords = make([]*OptionsTemplateRecords, 0)
 
-	for uintptr(headerPtr) > uintptr(bufferMinPtr) {
+	for uintptr(headerPtr) > bufferMinPtr {
+		remaining := uintptr(headerPtr) - bufferMinPtr
+		if remaining < sizeOfFlowSetHeader {
+			return nil, errors.Wrapf(ErrTruncated, "Set header exceeds message: %d bytes remaining", remaining)
+		}
+
 		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfFlowSetHeader)
 
 		fls := &FlowSet{
 			Header: (*FlowSetHeader)(ptr),
 		}
 
+		length := uintptr(fls.Header.Length)
+		if length < sizeOfFlowSetHeader {
+			return nil, errors.Wrapf(ErrInvalidSetLength, "Set %d: %d", fls.Header.SetID, length)
+		}
+
+		if length > remaining {
+			return nil, errors.Wrapf(ErrTruncated, "Set %d length %d exceeds message: %d bytes remaining", fls.Header.SetID, length, remaining)
+		}
+
 		switch fls.Header.SetID {
 		case TemplateSetID:
-			err := decodeTemplate(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader)
+			err := decodeTemplate(&packet, ptr, length-sizeOfFlowSetHeader)
 			if err != nil {
 				return nil, errors.Wrap(err, "Unable to decode template")
 			}
 		case OptionsTemplateSetID:
-			err := decodeOptionsTemplate(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader)
+			err := decodeOptionsTemplate(&packet, ptr, length-sizeOfFlowSetHeader)
 			if err != nil {
 				return nil, errors.Wrap(err, "Unable to decode template")
 			}
 		default:
-			decodeData(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader)
+			decodeData(&packet, ptr, length-sizeOfFlowSetHeader)
 		}
 
-		headerPtr = unsafe.Pointer(uintptr(headerPtr) - uintptr(fls.Header.Length))
+		headerPtr = unsafe.Pointer(uintptr(headerPtr) - length)
 	}
 
 	return &packet, nil
@@ -99,11 +107,11 @@ func Decode(raw []byte) (*Packet, error) {
 // decodeData decodes a flowSet from `packet`
 func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 	flsh := (*FlowSetHeader)(unsafe.Pointer(headerPtr))
-	data := unsafe.Pointer(uintptr(headerPtr) - uintptr(flsh.Length))
+	data := unsafe.Pointer(uintptr(headerPtr) - size)
 
 	fls := &FlowSet{
 		Header:  flsh,
-		Records: (*(*[1<<31 - 1]byte)(data))[sizeOfFlowSetHeader:flsh.Length],
+		Records: unsafe.Slice((*byte)(data), size),
 	}
 
 	packet.FlowSets = append(packet.FlowSets, fls)
@@ -112,27 +120,33 @@ func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 // decodeTemplate decodes a template from `packet`
 func decodeTemplate(packet *Packet, p unsafe.Pointer, size uintptr) error {
 	min := uintptr(p) - size
-	for uintptr(p) > min {
+
+	// anything shorter than a header is padding (RFC7011 3.3.1)
+	for uintptr(p)-min >= sizeOfTemplateRecordHeader {
 		p = unsafe.Pointer(uintptr(p) - sizeOfTemplateRecordHeader)
 		tmplRecs := &TemplateRecords{}
 		tmplRecs.Header = (*TemplateRecordHeader)(unsafe.Pointer(p))
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		if uintptr(p)-uintptr(tmplRecs.Header.FieldCount*uint16(sizeOfTemplateRecord)) < min {
-			return fmt.Errorf("invalid ipfix template: buffer underrun")
+		if uintptr(p)-uintptr(tmplRecs.Header.FieldCount)*sizeOfFieldSpecifier < min {
+			return errors.Wrap(ErrTruncated, "invalid ipfix template")
 		}
 
 		for i := uint16(0); i < tmplRecs.Header.FieldCount; i++ {
-			p = unsafe.Pointer(uintptr(p) - sizeOfTemplateRecord)
-			rec := (*TemplateRecord)(unsafe.Pointer(p))
-
-			if rec.isEnterprise() {
-				return fmt.Errorf("enterprise TLV currently not supported")
+			rec, next, err := decodeFieldSpecifier(p, min)
+			if err != nil {
+				return errors.Wrap(err, "invalid ipfix template")
 			}
 
+			p = next
 			tmplRecs.Records = append(tmplRecs.Records, rec)
 		}
 
+		// Template IDs below 256 are reserved. Zero padding decodes as template ID 0.
+		if tmplRecs.Header.TemplateID <= SetIDTemplateMax && !tmplRecs.IsWithdrawAll() {
+			continue
+		}
+
 		packet.Templates = append(packet.Templates, tmplRecs)
 	}
 
@@ -142,32 +156,95 @@ func decodeTemplate(packet *Packet, p unsafe.Pointer, size uintptr) error {
 // decodeOptionsTemplate decodes a template from `packet`
 func decodeOptionsTemplate(packet *Packet, p unsafe.Pointer, size uintptr) error {
 	min := uintptr(p) - size
-	for uintptr(p) > min {
+
+	// anything shorter than a header is padding (RFC7011 3.3.1)
+	for uintptr(p)-min >= sizeOfTemplateRecordHeader {
+		// Options Template Withdrawal Records consist of template ID and a field count of 0 only
+		withdrawal := (*TemplateRecordHeader)(unsafe.Pointer(uintptr(p) - sizeOfTemplateRecordHeader))
+		if withdrawal.FieldCount == 0 {
+			p = unsafe.Pointer(uintptr(p) - sizeOfTemplateRecordHeader)
+
+			// Template IDs below 256 are reserved. Zero padding decodes as template ID 0.
+			if withdrawal.TemplateID <= SetIDTemplateMax && withdrawal.TemplateID != OptionsTemplateSetID {
+				continue
+			}
+
+			packet.OptionsTemplateRecords = append(packet.OptionsTemplateRecords, &OptionsTemplateRecords{
+				Header: &OptionsTemplateRecordHeader{
+					TemplateID: withdrawal.TemplateID,
+				},
+				Records: make([]*TemplateRecord, 0),
+			})
+			continue
+		}
+
+		if uintptr(p)-sizeOfOptionsTemplateRecordHeader < min {
+			return errors.Wrap(ErrTruncated, "invalid ipfix options template")
+		}
+
 		p = unsafe.Pointer(uintptr(p) - sizeOfOptionsTemplateRecordHeader)
 		optTmplRecs := &OptionsTemplateRecords{}
 		optTmplRecs.Header = (*OptionsTemplateRecordHeader)(unsafe.Pointer(p))
 		optTmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		if uintptr(p)-uintptr(optTmplRecs.Header.TotelFieldCount*uint16(sizeOfTemplateRecord)) < min {
-			return fmt.Errorf("invalid ipfix options template: buffer underrun")
+		if uintptr(p)-uintptr(optTmplRecs.Header.TotelFieldCount)*sizeOfFieldSpecifier < min {
+			return errors.Wrap(ErrTruncated, "invalid ipfix options template")
 		}
 
 		for i := uint16(0); i < optTmplRecs.Header.TotelFieldCount; i++ {
-			p = unsafe.Pointer(uintptr(p) - sizeOfTemplateRecord)
-			rec := (*TemplateRecord)(unsafe.Pointer(p))
-			if rec.isEnterprise() {
-				return fmt.Errorf("enterprise TLV currently not supported")
+			rec, next, err := decodeFieldSpecifier(p, min)
+			if err != nil {
+				return errors.Wrap(err, "invalid ipfix options template")
 			}
 
+			p = next
 			optTmplRecs.Records = append(optTmplRecs.Records, rec)
 		}
 
+		if optTmplRecs.Header.TemplateID <= SetIDTemplateMax {
+			continue
+		}
+
 		packet.OptionsTemplateRecords = append(packet.OptionsTemplateRecords, optTmplRecs)
 	}
 
 	return nil
 }
 
+// decodeFieldSpecifier decodes the field specifier preceding p including the
+// enterprise number of enterprise-specific fields. It returns the pointer to the
+// beginning of the field specifier.
+func decodeFieldSpecifier(p unsafe.Pointer, min uintptr) (*TemplateRecord, unsafe.Pointer, error) {
+	if uintptr(p)-sizeOfFieldSpecifier < min {
+		return nil, nil, errors.Wrap(ErrTruncated, "field specifier")
+	}
+
+	p = unsafe.Pointer(uintptr(p) - sizeOfFieldSpecifier)
+	spec := (*fieldSpecifier)(p)
+	rec := &TemplateRecord{
+		Length: spec.Length,
+		Type:   spec.Type,
+	}
+
+	// Zero length fields would allow a single byte record to allocate a value for each field
+	if rec.Length == 0 {
+		return nil, nil, errors.Wrapf(ErrInvalidFieldLength, "field %d", rec.Type)
+	}
+
+	if !rec.isEnterprise() {
+		return rec, p, nil
+	}
+
+	if uintptr(p)-sizeOfEnterpriseNumber < min {
+		return nil, nil, errors.Wrap(ErrTruncated, "enterprise number")
+	}
+
+	p = unsafe.Pointer(uintptr(p) - sizeOfEnterpriseNumber)
+	rec.EnterpriseNumber = *(*uint32)(p)
+
+	return rec, p, nil
+}
+
 // PrintHeader prints the header of `packet`
 func PrintHeader(p *Packet) {
 	fmt.Printf("Version: %d\n", p.Header.Version)
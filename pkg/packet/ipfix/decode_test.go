@@ -6,13 +6,16 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestDecode(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []byte
-		expected *Packet
-		wantFail bool
-	}{
+type decodeTest struct {
+	name     string
+	input    []byte
+	expected *Packet
+	wantFail bool
+}
+
+// decodeTests returns the test cases of TestDecode
+func decodeTests() []decodeTest {
+	return []decodeTest{
 		{
 			name: "Template",
 			input: []byte{
@@ -155,6 +158,11 @@ func TestDecode(t *testing.T) {
 		},
 	}
 
+}
+
+func TestDecode(t *testing.T) {
+	tests := decodeTests()
+
 	for _, test := range tests {
 		p, err := Decode(test.input)
 		if err == nil && test.wantFail {
@@ -171,3 +179,328 @@ func TestDecode(t *testing.T) {
 		assert.Equalf(t, test.expected, p, test.name)
 	}
 }
+
+// ipv6FlowSetMessage returns a message with an IPv6 template and a matching data set
+func ipv6FlowSetMessage() []byte {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x8b, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x30, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x0a, // Field count
+		0x00, 0x1b, 0x00, 0x10, // IPv6SrcAddr
+		0x00, 0x1c, 0x00, 0x10, // IPv6DstAddr
+		0x00, 0x3e, 0x00, 0x10, // IPv6NextHop
+		0x00, 0x1d, 0x00, 0x01, // IPv6SrcMask
+		0x00, 0x1e, 0x00, 0x01, // IPv6DstMask
+		0x00, 0x04, 0x00, 0x01, // Protocol
+		0x00, 0x07, 0x00, 0x02, // L4SrcPort
+		0x00, 0x0b, 0x00, 0x02, // L4DstPort
+		0x00, 0x01, 0x00, 0x08, // InBytes
+		0x00, 0x02, 0x00, 0x08, // InPkts
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x4b, // FlowSet length
+		0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // 2001:db8::1
+		0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // 2001:db8:1::2
+		0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // fe80::1
+		0x30,       // IPv6SrcMask
+		0x40,       // IPv6DstMask
+		0x06,       // Protocol
+		0x01, 0xbb, // L4SrcPort
+		0xc3, 0x50, // L4DstPort
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xdc, // InBytes
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // InPkts
+	}
+
+	return input
+}
+
+func TestDecodeIPv6FlowSet(t *testing.T) {
+	input := ipv6FlowSetMessage()
+
+	p, err := Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if len(p.Templates) != 1 || len(p.FlowSets) != 1 {
+		t.Fatalf("expected 1 template and 1 flow set, got %d and %d", len(p.Templates), len(p.FlowSets))
+	}
+
+	records := DecodeFlowSet(*p.FlowSets[0], p.Templates[0].Records)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	values := records[0].Values
+	assert.Len(t, values, 10)
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xb8, 0x0d, 0x01, 0x20}, values[0])
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x80, 0xfe}, values[2])
+	assert.Equal(t, []byte{0x30}, values[3])
+	assert.Equal(t, []byte{0xdc, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, values[8])
+}
+
+// enterpriseVariableLengthMessage returns a message with enterprise-specific and variable-length fields
+func enterpriseVariableLengthMessage() []byte {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x3b, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x18, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x03, // Field count
+		0x00, 0x08, 0x00, 0x04, // IPv4SrcAddr
+		0x80, 0x01, 0xff, 0xff, // Enterprise element 1, variable length
+		0x00, 0x00, 0x0a, 0x4c, // Enterprise number 2636
+		0x00, 0x60, 0xff, 0xff, // ApplicationName, variable length
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x13, // FlowSet length
+		0x0a, 0x00, 0x00, 0x01, // 10.0.0.1
+		0x03, 'f', 'o', 'o', // Short variable length encoding
+		0xff, 0x00, 0x04, 'h', 't', 't', 'p', // Long variable length encoding
+	}
+
+	return input
+}
+
+func TestDecodeEnterpriseVariableLengthFlowSet(t *testing.T) {
+	input := enterpriseVariableLengthMessage()
+
+	p, err := Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if len(p.Templates) != 1 || len(p.FlowSets) != 1 {
+		t.Fatalf("expected 1 template and 1 flow set, got %d and %d", len(p.Templates), len(p.FlowSets))
+	}
+
+	assert.Equal(t, []*TemplateRecord{
+		{
+			Type:   8,
+			Length: 4,
+		},
+		{
+			Type:             0x8001,
+			Length:           VariableLength,
+			EnterpriseNumber: 2636,
+		},
+		{
+			Type:   96,
+			Length: VariableLength,
+		},
+	}, p.Templates[0].Records)
+
+	records := DecodeFlowSet(*p.FlowSets[0], p.Templates[0].Records)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	values := records[0].Values
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x0a}, values[0])
+	assert.Equal(t, []byte("oof"), values[1])
+	assert.Equal(t, []byte("ptth"), values[2])
+}
+
+// truncatedEnterpriseTemplateMessage returns a message with an enterprise field specifier lacking its enterprise number
+func truncatedEnterpriseTemplateMessage() []byte {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x1c, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x0c, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x01, // Field count
+		0x80, 0x01, 0x00, 0x04, // Enterprise element 1 without enterprise number
+	}
+
+	return input
+}
+
+func TestDecodeTruncatedEnterpriseTemplate(t *testing.T) {
+	input := truncatedEnterpriseTemplateMessage()
+
+	_, err := Decode(input)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+// templateWithdrawalMessage returns a message with template and options template withdrawals
+func templateWithdrawalMessage() []byte {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x24, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x0c, // FlowSet length
+		0x01, 0x00, // Template ID 256
+		0x00, 0x00, // Field count = 0 = withdrawal
+		0x00, 0x02, // Template ID 2 = all templates
+		0x00, 0x00, // Field count = 0 = withdrawal
+		0x00, 0x03, // FlowSet ID = 3 = options template
+		0x00, 0x08, // FlowSet length
+		0x02, 0x00, // Template ID 512
+		0x00, 0x00, // Field count = 0 = withdrawal
+	}
+
+	return input
+}
+
+func TestDecodeTemplateWithdrawal(t *testing.T) {
+	input := templateWithdrawalMessage()
+
+	p, err := Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if len(p.Templates) != 2 || len(p.OptionsTemplateRecords) != 1 {
+		t.Fatalf("expected 2 templates and 1 options template, got %d and %d", len(p.Templates), len(p.OptionsTemplateRecords))
+	}
+
+	assert.Equal(t, uint16(256), p.Templates[0].Header.TemplateID)
+	assert.True(t, p.Templates[0].IsWithdrawal())
+	assert.False(t, p.Templates[0].IsWithdrawAll())
+	assert.True(t, p.Templates[1].IsWithdrawAll())
+
+	assert.Equal(t, uint16(512), p.OptionsTemplateRecords[0].Header.TemplateID)
+	assert.True(t, p.OptionsTemplateRecords[0].IsWithdrawal())
+	assert.False(t, p.OptionsTemplateRecords[0].IsWithdrawAll())
+}
+
+func TestDecodeInvalidSetLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected error
+	}{
+		{
+			name: "Zero set length",
+			input: []byte{
+				0x00, 0x0a, // Version
+				0x00, 0x18, // Length
+				0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+				0x00, 0x00, 0x00, 0x01, // FlowSequence
+				0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+				0x01, 0x00, // FlowSet ID = 256
+				0x00, 0x00, // FlowSet length
+				0x00, 0x00, 0x00, 0x00,
+			},
+			expected: ErrInvalidSetLength,
+		},
+		{
+			name: "Set length exceeds message",
+			input: []byte{
+				0x00, 0x0a, // Version
+				0x00, 0x18, // Length
+				0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+				0x00, 0x00, 0x00, 0x01, // FlowSequence
+				0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+				0x01, 0x00, // FlowSet ID = 256
+				0x00, 0x40, // FlowSet length
+				0x00, 0x00, 0x00, 0x00,
+			},
+			expected: ErrTruncated,
+		},
+		{
+			name: "Trailing bytes",
+			input: []byte{
+				0x00, 0x0a, // Version
+				0x00, 0x12, // Length
+				0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+				0x00, 0x00, 0x00, 0x01, // FlowSequence
+				0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+				0x01, 0x00,
+			},
+			expected: ErrTruncated,
+		},
+		{
+			name:     "Short header",
+			input:    []byte{0x00, 0x0a, 0x00, 0x10},
+			expected: ErrTruncated,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(test.input)
+		assert.ErrorIsf(t, err, test.expected, test.name)
+	}
+}
+
+func TestDecodeZeroLengthField(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x1c, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x10, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x02, // Field count
+		0x00, 0x08, 0x00, 0x04, // sourceIPv4Address
+		0x00, 0x01, 0x00, 0x00, // octetDeltaCount with length 0
+	}
+
+	_, err := Decode(input)
+	assert.ErrorIs(t, err, ErrInvalidFieldLength)
+}
+
+func TestDecodeFlowSetZeroLengthFields(t *testing.T) {
+	fields := []*TemplateRecord{{Type: 1}, {Type: 2}}
+	records := DecodeFlowSet(FlowSet{
+		Records: make([]byte, 64),
+	}, fields)
+	assert.Nil(t, records)
+}
+
+func TestDecodeTemplatePadding(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x38, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x0e, // FlowSet length
+		0x01, 0x00, // Template ID 256
+		0x00, 0x01, // Field count
+		0x00, 0x08, 0x00, 0x04, // sourceIPv4Address
+		0x00, 0x00, // Padding
+		0x00, 0x03, // FlowSet ID = 3 = options template
+		0x00, 0x12, // FlowSet length
+		0x01, 0x01, // Template ID 257
+		0x00, 0x01, // Field count
+		0x00, 0x01, // Scope field count
+		0x00, 0x0a, 0x00, 0x04, // ingressInterface
+		0x00, 0x00, 0x00, 0x00, // Padding
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x08, // FlowSet length
+		0xc0, 0x00, 0x02, 0x01, // sourceIPv4Address
+	}
+
+	p, err := Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if len(p.Templates) != 1 || len(p.OptionsTemplateRecords) != 1 || len(p.FlowSets) != 1 {
+		t.Fatalf("expected 1 template, 1 options template and 1 data set, got %d, %d and %d", len(p.Templates), len(p.OptionsTemplateRecords), len(p.FlowSets))
+	}
+
+	assert.Equal(t, uint16(256), p.Templates[0].Header.TemplateID)
+	assert.False(t, p.Templates[0].IsWithdrawal())
+	assert.Equal(t, uint16(257), p.OptionsTemplateRecords[0].Header.TemplateID)
+	assert.False(t, p.OptionsTemplateRecords[0].IsWithdrawal())
+}
@@ -0,0 +1,137 @@
+package ipfix
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/tflow2/convert"
+)
+
+// DataType is the abstract data type of an Information Element (RFC7012 3.1)
+type DataType uint8
+
+const (
+	// DataTypeOctetArray is an opaque sequence of bytes
+	DataTypeOctetArray DataType = iota
+
+	// DataTypeUnsigned is an unsigned integer of up to 8 bytes
+	DataTypeUnsigned
+
+	// DataTypeString is an UTF-8 encoded string
+	DataTypeString
+
+	// DataTypeIPv4Address is an IPv4 address
+	DataTypeIPv4Address
+
+	// DataTypeIPv6Address is an IPv6 address
+	DataTypeIPv6Address
+)
+
+var dataTypeNames = map[string]DataType{
+	"octetArray":  DataTypeOctetArray,
+	"unsigned":    DataTypeUnsigned,
+	"string":      DataTypeString,
+	"ipv4Address": DataTypeIPv4Address,
+	"ipv6Address": DataTypeIPv6Address,
+}
+
+// ParseDataType parses the RFC7012 name of a data type
+func ParseDataType(name string) (DataType, error) {
+	t, ok := dataTypeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown data type %q", name)
+	}
+
+	return t, nil
+}
+
+// InformationElement describes an enterprise-specific Information Element
+type InformationElement struct {
+	EnterpriseNumber uint32
+	ElementID        uint16
+	Name             string
+	Type             DataType
+}
+
+// String converts a raw (reversed) value of the Information Element into a string
+func (ie *InformationElement) String(value []byte) string {
+	switch ie.Type {
+	case DataTypeString:
+		return strings.TrimRight(string(reversedCopy(value)), "\x00")
+	case DataTypeUnsigned:
+		if len(value) > 8 {
+			break
+		}
+
+		u := uint64(0)
+		for i := len(value) - 1; i >= 0; i-- {
+			u = u<<8 | uint64(value[i])
+		}
+
+		return fmt.Sprintf("%d", u)
+	case DataTypeIPv4Address, DataTypeIPv6Address:
+		addr, err := bnet.IPFromBytes(reversedCopy(value))
+		if err != nil {
+			break
+		}
+
+		return addr.String()
+	}
+
+	return fmt.Sprintf("%x", reversedCopy(value))
+}
+
+// reversedCopy reverses value without altering the underlying packet buffer
+func reversedCopy(value []byte) []byte {
+	c := make([]byte, len(value))
+	copy(c, value)
+	return convert.Reverse(c)
+}
+
+type registryKey struct {
+	enterpriseNumber uint32
+	elementID        uint16
+}
+
+// Registry maps enterprise numbers and element IDs to Information Elements
+type Registry struct {
+	elements   map[registryKey]*InformationElement
+	elementsMu sync.RWMutex
+}
+
+// NewRegistry creates a new empty Information Element registry
+func NewRegistry() *Registry {
+	return &Registry{
+		elements: make(map[registryKey]*InformationElement),
+	}
+}
+
+// Register adds an Information Element to the registry. Existing elements with the same
+// enterprise number and element ID are replaced.
+func (r *Registry) Register(ie *InformationElement) {
+	r.elementsMu.Lock()
+	defer r.elementsMu.Unlock()
+
+	r.elements[registryKey{
+		enterpriseNumber: ie.EnterpriseNumber,
+		elementID:        ie.ElementID,
+	}] = ie
+}
+
+// Lookup gets the Information Element described by an enterprise-specific template field.
+// Returns nil if the element is unknown.
+func (r *Registry) Lookup(tmpl *TemplateRecord) *InformationElement {
+	if !tmpl.isEnterprise() {
+		return nil
+	}
+
+	r.elementsMu.RLock()
+	defer r.elementsMu.RUnlock()
+
+	return r.elements[registryKey{
+		enterpriseNumber: tmpl.EnterpriseNumber,
+		elementID:        tmpl.ElementID(),
+	}]
+}
@@ -0,0 +1,76 @@
+package ipfix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	r := NewRegistry()
+	ie := &InformationElement{
+		EnterpriseNumber: 2636,
+		ElementID:        1,
+		Name:             "vrfName",
+		Type:             DataTypeString,
+	}
+	r.Register(ie)
+
+	assert.Equal(t, ie, r.Lookup(&TemplateRecord{Type: 0x8001, EnterpriseNumber: 2636}))
+	assert.Nil(t, r.Lookup(&TemplateRecord{Type: 0x8001, EnterpriseNumber: 9}))
+	assert.Nil(t, r.Lookup(&TemplateRecord{Type: 0x8002, EnterpriseNumber: 2636}))
+	assert.Nil(t, r.Lookup(&TemplateRecord{Type: 1}))
+}
+
+func TestInformationElementString(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataType DataType
+		value    []byte
+		expected string
+	}{
+		{
+			name:     "string",
+			dataType: DataTypeString,
+			value:    []byte{0x00, 'f', 'e', 'r'},
+			expected: "ref",
+		},
+		{
+			name:     "unsigned",
+			dataType: DataTypeUnsigned,
+			value:    []byte{0x01, 0x01},
+			expected: "257",
+		},
+		{
+			name:     "IPv4 address",
+			dataType: DataTypeIPv4Address,
+			value:    []byte{0x01, 0x02, 0x00, 0xc0},
+			expected: "192.0.2.1",
+		},
+		{
+			name:     "octet array",
+			dataType: DataTypeOctetArray,
+			value:    []byte{0xad, 0xde},
+			expected: "dead",
+		},
+	}
+
+	for _, test := range tests {
+		ie := &InformationElement{
+			Type: test.dataType,
+		}
+
+		value := append([]byte{}, test.value...)
+		assert.Equal(t, test.expected, ie.String(value), test.name)
+		assert.Equal(t, test.value, value, test.name)
+	}
+}
+
+func TestParseDataType(t *testing.T) {
+	dt, err := ParseDataType("string")
+	assert.NoError(t, err)
+	assert.Equal(t, DataTypeString, dt)
+
+	_, err = ParseDataType("float128")
+	assert.Error(t, err)
+}
@@ -0,0 +1,14 @@
+package ipfix
+
+import "github.com/pkg/errors"
+
+var (
+	// ErrTruncated is returned if a header or length field exceeds the received message
+	ErrTruncated = errors.New("Truncated message")
+
+	// ErrInvalidSetLength is returned for sets shorter than their own header
+	ErrInvalidSetLength = errors.New("Invalid set length")
+
+	// ErrInvalidFieldLength is returned for fixed-length template fields of length 0
+	ErrInvalidFieldLength = errors.New("Invalid field length")
+)
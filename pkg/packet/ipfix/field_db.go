@@ -108,5 +108,15 @@ const (
 	ApplicationDescription    = 94
 	ApplicationTag            = 95
 	ApplicationName           = 96
+	IcmpTypeCodeIPv6          = 139
+	FlowStartSeconds          = 150
+	FlowEndSeconds            = 151
+	FlowStartMilliseconds     = 152
+	FlowEndMilliseconds       = 153
+	SystemInitTimeMillis      = 160
+	IcmpTypeIPv4              = 176
+	IcmpCodeIPv4              = 177
+	IcmpTypeIPv6              = 178
+	IcmpCodeIPv6              = 179
 	SamplingPacketInterval    = 305
 )
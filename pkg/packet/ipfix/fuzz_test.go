@@ -0,0 +1,31 @@
+package ipfix
+
+import (
+	"testing"
+)
+
+func FuzzDecode(f *testing.F) {
+	for _, test := range decodeTests() {
+		f.Add(test.input)
+	}
+
+	f.Add(ipv6FlowSetMessage())
+	f.Add(enterpriseVariableLengthMessage())
+	f.Add(truncatedEnterpriseTemplateMessage())
+	f.Add(templateWithdrawalMessage())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Decode(append([]byte(nil), data...))
+		if err != nil {
+			return
+		}
+
+		for _, tmpl := range p.Templates {
+			for _, fls := range p.FlowSets {
+				if fls.Header.SetID == tmpl.Header.TemplateID {
+					DecodeFlowSet(*fls, tmpl.Records)
+				}
+			}
+		}
+	})
+}
@@ -34,3 +34,13 @@ type OptionsTemplateRecords struct {
 	// List of fields in this Template Record.
 	Records []*TemplateRecord
 }
+
+// IsWithdrawal returns true if the record withdraws a previously announced options template (RFC7011 8.1)
+func (otr *OptionsTemplateRecords) IsWithdrawal() bool {
+	return otr.Header.TotelFieldCount == 0
+}
+
+// IsWithdrawAll returns true if the record withdraws all options templates of the observation domain
+func (otr *OptionsTemplateRecords) IsWithdrawAll() bool {
+	return otr.IsWithdrawal() && otr.Header.TemplateID == OptionsTemplateSetID
+}
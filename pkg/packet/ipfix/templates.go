@@ -46,19 +46,54 @@ type TemplateRecords struct {
 	Records []*TemplateRecord
 }
 
-// TemplateRecord represents a Template Record as described in RFC3954
+// VariableLength is the field length signaling a variable-length encoded field (RFC7011 7.)
+const VariableLength = 65535
+
+// IsWithdrawal returns true if the record withdraws a previously announced template (RFC7011 8.1)
+func (tr *TemplateRecords) IsWithdrawal() bool {
+	return tr.Header.FieldCount == 0
+}
+
+// IsWithdrawAll returns true if the record withdraws all templates of the observation domain
+func (tr *TemplateRecords) IsWithdrawAll() bool {
+	return tr.IsWithdrawal() && tr.Header.TemplateID == TemplateSetID
+}
+
+// TemplateRecord represents a Template Record as described in RFC7011
 type TemplateRecord struct {
-	// The length (in bytes) of the field.
+	// The length (in bytes) of the field. VariableLength if the length
+	// is encoded in the data record.
 	Length uint16
 
-	// A numeric value that represents the type of field.
+	// A numeric value that represents the type of field. The most
+	// significant bit is set for enterprise-specific fields.
 	Type uint16
+
+	// IANA Private Enterprise Number of the authority defining the field.
+	// Only set for enterprise-specific fields.
+	EnterpriseNumber uint32
+}
+
+// fieldSpecifier is the raw representation of a template field as found on the wire
+type fieldSpecifier struct {
+	Length uint16
+	Type   uint16
 }
 
 func (tmpl *TemplateRecord) isEnterprise() bool {
 	return tmpl.Type&0x8000 == 0x8000
 }
 
+// ElementID gets the Information Element identifier without the enterprise bit
+func (tmpl *TemplateRecord) ElementID() uint16 {
+	return tmpl.Type & 0x7fff
+}
+
+// IsVariableLength returns true if the fields length is encoded in the data record
+func (tmpl *TemplateRecord) IsVariableLength() bool {
+	return tmpl.Length == VariableLength
+}
+
 // FlowDataRecord is actual NetFlow data. This structure does not contain any
 // information about the actual data meaning. It must be combined with
 // corresponding TemplateRecord to be decoded to a single NetFlow data row.
@@ -67,14 +102,22 @@ type FlowDataRecord struct {
 	Values [][]byte
 }
 
-// sizeOfTemplateRecord is the raw size of a TemplateRecord
-var sizeOfTemplateRecord = unsafe.Sizeof(TemplateRecord{})
+// sizeOfFieldSpecifier is the raw size of a field specifier
+var sizeOfFieldSpecifier = unsafe.Sizeof(fieldSpecifier{})
+
+// sizeOfEnterpriseNumber is the raw size of the enterprise number following enterprise-specific field specifiers
+var sizeOfEnterpriseNumber = unsafe.Sizeof(uint32(0))
 
 // DecodeFlowSet uses current TemplateRecord to decode data in Data FlowSet to
 // a list of Flow Data Records.
 func DecodeFlowSet(set FlowSet, templateRecords []*TemplateRecord) (list []FlowDataRecord) {
 	var record FlowDataRecord
 
+	// Records without any data can not be told apart from each other
+	if minRecordLength(templateRecords) == 0 {
+		return nil
+	}
+
 	// Pre-allocate some room for flows
 	list = make([]FlowDataRecord, 0, numPreAllocFlowDataRecs)
 
@@ -86,7 +129,7 @@ func DecodeFlowSet(set FlowSet, templateRecords []*TemplateRecord) (list []FlowD
 
 	for n >= 4 {
 		record.Values, count = parseFieldValues(set.Records[0:n], templateRecords)
-		if record.Values == nil {
+		if record.Values == nil || count == 0 {
 			return
 		}
 
@@ -97,6 +140,22 @@ func DecodeFlowSet(set FlowSet, templateRecords []*TemplateRecord) (list []FlowD
 	return list
 }
 
+// minRecordLength returns the minimum length of a data record described by fields.
+// Variable-length fields take at least their one byte length prefix.
+func minRecordLength(fields []*TemplateRecord) int {
+	n := 0
+	for _, f := range fields {
+		if f.IsVariableLength() {
+			n++
+			continue
+		}
+
+		n += int(f.Length)
+	}
+
+	return n
+}
+
 // parseFieldValues reads actual fields values from a Data Record utilizing a template
 func parseFieldValues(data []byte, fields []*TemplateRecord) ([][]byte, int) {
 	count := 0
@@ -104,14 +163,46 @@ func parseFieldValues(data []byte, fields []*TemplateRecord) ([][]byte, int) {
 	values := make([][]byte, len(fields))
 
 	for i, f := range fields {
-		if n < int(f.Length) {
+		length := int(f.Length)
+		if f.IsVariableLength() {
+			l, prefixLen := variableFieldLength(data[:n])
+			if prefixLen == 0 {
+				return nil, 0
+			}
+
+			length = l
+			count += prefixLen
+			n -= prefixLen
+		}
+
+		if n < length {
 			return nil, 0
 		}
 
-		values[i] = data[n-int(f.Length) : n]
-		count += int(f.Length)
-		n -= int(f.Length)
+		values[i] = data[n-length : n]
+		count += length
+		n -= length
 	}
 
 	return values, count
 }
+
+// variableFieldLength reads the length prefix of a variable-length field (RFC7011 7.)
+// from the end of the reversed data. It returns the fields length and the length
+// of the prefix or 0 if the prefix is truncated.
+func variableFieldLength(data []byte) (int, int) {
+	n := len(data)
+	if n < 1 {
+		return 0, 0
+	}
+
+	if data[n-1] < 255 {
+		return int(data[n-1]), 1
+	}
+
+	if n < 3 {
+		return 0, 0
+	}
+
+	return int(data[n-2])<<8 | int(data[n-3]), 3
+}
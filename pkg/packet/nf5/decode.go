@@ -0,0 +1,57 @@
+package nf5
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/bio-routing/tflow2/convert"
+	"github.com/pkg/errors"
+)
+
+// errorIncompatibleVersion prints an error message in case the detected version is not supported
+func errorIncompatibleVersion(version uint16) error {
+	return errors.Errorf("NF5: Incompatible protocol version v%d, only v5 is supported", version)
+}
+
+// Decode converts raw packet bytes to a Packet struct.
+func Decode(raw []byte) (*Packet, error) {
+	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
+
+	pSize := uintptr(len(data))
+	if pSize < sizeOfHeader {
+		return nil, errors.Errorf("Packet is too short: %d", pSize)
+	}
+
+	bufferPtr := unsafe.Pointer(&data[0])
+	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + pSize - sizeOfHeader)
+
+	var packet Packet
+	packet.Header = (*Header)(headerPtr)
+
+	if packet.Header.Version != 5 {
+		return nil, errorIncompatibleVersion(packet.Header.Version)
+	}
+
+	if sizeOfHeader+uintptr(packet.Header.Count)*sizeOfFlowRecord > pSize {
+		return nil, errors.Errorf("Packet is too short for %d records: %d", packet.Header.Count, pSize)
+	}
+
+	packet.Records = make([]*FlowRecord, 0, packet.Header.Count)
+	ptr := headerPtr
+	for i := uint16(0); i < packet.Header.Count; i++ {
+		ptr = unsafe.Pointer(uintptr(ptr) - sizeOfFlowRecord)
+		packet.Records = append(packet.Records, (*FlowRecord)(ptr))
+	}
+
+	return &packet, nil
+}
+
+// PrintHeader prints the header of `packet`
+func PrintHeader(p *Packet) {
+	fmt.Printf("Version: %d\n", p.Header.Version)
+	fmt.Printf("Count: %d\n", p.Header.Count)
+	fmt.Printf("SysUpTime: %d\n", p.Header.SysUpTime)
+	fmt.Printf("UnixSecs: %d\n", p.Header.UnixSecs)
+	fmt.Printf("Sequence: %d\n", p.Header.FlowSequence)
+	fmt.Printf("SamplingInterval: %d\n", p.Header.SampleRate())
+}
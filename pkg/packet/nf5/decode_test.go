@@ -0,0 +1,141 @@
+package nf5
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected *Packet
+		wantFail bool
+	}{
+		{
+			name: "Single record",
+			input: []byte{
+				0x00, 0x05, // Version
+				0x00, 0x01, // Count
+				0x00, 0x00, 0x27, 0x10, // SysUpTime
+				0x68, 0x3d, 0x5a, 0xc1, // UnixSecs
+				0x00, 0x00, 0x00, 0x00, // UnixNsecs
+				0x00, 0x00, 0x00, 0x2a, // FlowSequence
+				0x00,       // EngineType
+				0x01,       // EngineID
+				0x40, 0x64, // Sampling mode 1, interval 100
+				0xc0, 0x00, 0x02, 0x01, // SrcAddr
+				0xc6, 0x33, 0x64, 0x02, // DstAddr
+				0xcb, 0x00, 0x71, 0x03, // NextHop
+				0x00, 0x0a, // Input
+				0x00, 0x14, // Output
+				0x00, 0x00, 0x00, 0x03, // DPkts
+				0x00, 0x00, 0x05, 0xdc, // DOctets
+				0x00, 0x00, 0x23, 0x28, // First
+				0x00, 0x00, 0x27, 0x0f, // Last
+				0xc3, 0x50, // SrcPort
+				0x01, 0xbb, // DstPort
+				0x00,       // Pad1
+				0x12,       // TCPFlags
+				0x06,       // Protocol
+				0xb8,       // TOS
+				0xfd, 0xe8, // SrcAs
+				0xfd, 0xe9, // DstAs
+				0x18,       // SrcMask
+				0x10,       // DstMask
+				0x00, 0x00, // Pad2
+			},
+			expected: &Packet{
+				Header: &Header{
+					Version:          5,
+					Count:            1,
+					SysUpTime:        10000,
+					UnixSecs:         1748851393,
+					FlowSequence:     42,
+					EngineID:         1,
+					SamplingInterval: 0x4064,
+				},
+				Records: []*FlowRecord{
+					{
+						SrcAddr:  [4]byte{1, 2, 0, 192},
+						DstAddr:  [4]byte{2, 100, 51, 198},
+						NextHop:  [4]byte{3, 113, 0, 203},
+						Input:    10,
+						Output:   20,
+						DPkts:    3,
+						DOctets:  1500,
+						First:    9000,
+						Last:     9999,
+						SrcPort:  50000,
+						DstPort:  443,
+						TCPFlags: 0x12,
+						Protocol: 6,
+						TOS:      0xb8,
+						SrcAs:    65000,
+						DstAs:    65001,
+						SrcMask:  24,
+						DstMask:  16,
+					},
+				},
+			},
+		},
+		{
+			name: "Wrong version",
+			input: []byte{
+				0x00, 0x09, // Version
+				0x00, 0x00, // Count
+				0x00, 0x00, 0x27, 0x10, // SysUpTime
+				0x68, 0x3d, 0x5a, 0xc1, // UnixSecs
+				0x00, 0x00, 0x00, 0x00, // UnixNsecs
+				0x00, 0x00, 0x00, 0x2a, // FlowSequence
+				0x00,       // EngineType
+				0x01,       // EngineID
+				0x00, 0x00, // Sampling
+			},
+			wantFail: true,
+		},
+		{
+			name: "Truncated record",
+			input: []byte{
+				0x00, 0x05, // Version
+				0x00, 0x01, // Count
+				0x00, 0x00, 0x27, 0x10, // SysUpTime
+				0x68, 0x3d, 0x5a, 0xc1, // UnixSecs
+				0x00, 0x00, 0x00, 0x00, // UnixNsecs
+				0x00, 0x00, 0x00, 0x2a, // FlowSequence
+				0x00,       // EngineType
+				0x01,       // EngineID
+				0x00, 0x00, // Sampling
+				0xc0, 0x00, 0x02, 0x01, // SrcAddr
+			},
+			wantFail: true,
+		},
+		{
+			name:     "Truncated header",
+			input:    []byte{0x00, 0x05, 0x00},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		p, err := Decode(test.input)
+		if err == nil && test.wantFail {
+			t.Errorf("unexpected success for %q", test.name)
+			continue
+		}
+
+		if err != nil && !test.wantFail {
+			t.Errorf("unexpected failure for %q: %v", test.name, err)
+			continue
+		}
+
+		if test.wantFail {
+			continue
+		}
+
+		assert.Equalf(t, test.expected, p, test.name)
+		assert.Equalf(t, uint16(100), p.Header.SampleRate(), test.name)
+		assert.Equalf(t, uint8(1), p.Header.SamplingMode(), test.name)
+	}
+}
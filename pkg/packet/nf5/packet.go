@@ -0,0 +1,132 @@
+// Package nf5 provides structures and functions to decode NetFlow v5 packets.
+//
+// NetFlow v5 packets consist of a fixed size header followed by up to 30
+// fixed size flow records:
+//
+//	+--------+--------+--------+-----+--------+
+//	| Packet | Flow   | Flow   |     | Flow   |
+//	| Header | Record | Record | ... | Record |
+//	+--------+--------+--------+-----+--------+
+//
+// Like the nf9 and ipfix packages this package decodes the packet in reversed
+// byte order. Structures are therefore defined with their fields in reverse order.
+package nf5
+
+import "unsafe"
+
+var (
+	sizeOfHeader     = unsafe.Sizeof(Header{})
+	sizeOfFlowRecord = unsafe.Sizeof(FlowRecord{})
+)
+
+// Packet is a decoded representation of a single NetFlow v5 UDP packet.
+type Packet struct {
+	// A pointer to the packets header
+	Header *Header
+
+	// A slice of pointers to the flow records found in this packet
+	Records []*FlowRecord
+}
+
+// Header is a NetFlow v5 header
+type Header struct {
+	// First two bits hold the sampling mode, the remaining 14 bits the sampling interval
+	SamplingInterval uint16
+
+	// Slot number of the flow-switching engine
+	EngineID uint8
+
+	// Type of flow-switching engine
+	EngineType uint8
+
+	// Sequence counter of total flows seen
+	FlowSequence uint32
+
+	// Residual nanoseconds since 0000 UTC 1970
+	UnixNsecs uint32
+
+	// Current count of seconds since 0000 UTC 1970
+	UnixSecs uint32
+
+	// Current time in milliseconds since the export device booted
+	SysUpTime uint32
+
+	// Number of flows exported in this packet (1-30)
+	Count uint16
+
+	// NetFlow export format version number
+	Version uint16
+}
+
+// SamplingMode returns the sampling mode encoded in the sampling interval field
+func (h *Header) SamplingMode() uint8 {
+	return uint8(h.SamplingInterval >> 14)
+}
+
+// SampleRate returns the sampling interval. A value of 0 means the packets were not sampled.
+func (h *Header) SampleRate() uint16 {
+	return h.SamplingInterval & 0x3fff
+}
+
+// FlowRecord is a NetFlow v5 flow record
+type FlowRecord struct {
+	// Unused (zero) bytes
+	Pad2 uint16
+
+	// Destination address prefix mask bits
+	DstMask uint8
+
+	// Source address prefix mask bits
+	SrcMask uint8
+
+	// Autonomous system number of the destination, either origin or peer
+	DstAs uint16
+
+	// Autonomous system number of the source, either origin or peer
+	SrcAs uint16
+
+	// IP type of service (ToS)
+	TOS uint8
+
+	// IP protocol type (for example, TCP = 6; UDP = 17)
+	Protocol uint8
+
+	// Cumulative OR of TCP flags
+	TCPFlags uint8
+
+	// Unused (zero) byte
+	Pad1 uint8
+
+	// TCP/UDP destination port number or equivalent
+	DstPort uint16
+
+	// TCP/UDP source port number or equivalent
+	SrcPort uint16
+
+	// SysUptime at the time the last packet of the flow was received
+	Last uint32
+
+	// SysUptime at start of flow
+	First uint32
+
+	// Total number of Layer 3 bytes in the packets of the flow
+	DOctets uint32
+
+	// Packets in the flow
+	DPkts uint32
+
+	// SNMP index of output interface
+	Output uint16
+
+	// SNMP index of input interface
+	Input uint16
+
+	// IP address of next hop router
+	NextHop [4]byte
+
+	// Destination IP address
+	DstAddr [4]byte
+
+	// Source IP address
+	SrcAddr [4]byte
+}
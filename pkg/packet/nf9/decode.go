@@ -41,27 +41,21 @@ func errorIncompatibleVersion(version uint16) error {
 
 // Decode is the main function of this package. It converts raw packet bytes to Packet struct.
 func Decode(raw []byte, remote net.IP) (*Packet, error) {
-	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
-
-	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
-
-	if pSize > bufSize {
-		panic("Buffer too small\n")
+	if uintptr(len(raw)) < sizeOfHeader {
+		return nil, errors.Wrapf(ErrTruncated, "Packet is too short: %d", len(raw))
 	}
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
-	}
+	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
+
+	// copy data as templates point into the buffer while raw is reused by the caller
+	buffer := make([]byte, len(data))
+	copy(buffer, data)
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeader))
+	bufferMinPtr := uintptr(unsafe.Pointer(&buffer[0]))
+	headerPtr := unsafe.Pointer(&buffer[uintptr(len(buffer))-sizeOfHeader])
 
 	var packet Packet
-	packet.Buffer = buffer[:]
+	packet.Buffer = buffer
 	packet.Header = (*Header)(headerPtr)
 
 	if packet.Header.Version != 9 {
@@ -71,35 +65,56 @@ func Decode(raw []byte, remote net.IP) (*Packet, error) {
 	//Pre-allocate some room for templates to avoid later copying
 	packet.Templates = make([]*TemplateRecords, 0, numPreAllocRecs)
 
-	for uintptr(headerPtr) > uintptr(bufferMinPtr) {
+	for uintptr(headerPtr) > bufferMinPtr {
+		remaining := uintptr(headerPtr) - bufferMinPtr
+		if remaining < sizeOfFlowSetHeader {
+			return nil, errors.Wrapf(ErrTruncated, "FlowSet header exceeds packet: %d bytes remaining", remaining)
+		}
+
 		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfFlowSetHeader)
 
 		fls := &FlowSet{
 			Header: (*FlowSetHeader)(ptr),
 		}
 
+		length := uintptr(fls.Header.Length)
+		if length < sizeOfFlowSetHeader {
+			return nil, errors.Wrapf(ErrInvalidFlowSetLength, "FlowSet %d: %d", fls.Header.FlowSetID, length)
+		}
+
+		if length > remaining {
+			return nil, errors.Wrapf(ErrTruncated, "FlowSet %d length %d exceeds packet: %d bytes remaining", fls.Header.FlowSetID, length, remaining)
+		}
+
 		if fls.Header.FlowSetID == TemplateFlowSetID {
 			// Template
-			decodeTemplate(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader, remote)
+			err := decodeTemplate(&packet, ptr, length-sizeOfFlowSetHeader, remote)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decode template")
+			}
 		} else if fls.Header.FlowSetID == OptionTemplateFlowSetID {
 			// Option Template
-			decodeOption(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader, remote)
+			err := decodeOption(&packet, ptr, length-sizeOfFlowSetHeader, remote)
+			if err != nil {
+				return nil, errors.Wrap(err, "Unable to decode option template")
+			}
 		} else if fls.Header.FlowSetID > FlowSetIDTemplateMax {
 			// Actual data packet
-			decodeData(&packet, ptr, uintptr(fls.Header.Length)-sizeOfFlowSetHeader)
+			decodeData(&packet, ptr, length-sizeOfFlowSetHeader)
 		}
 
-		headerPtr = unsafe.Pointer(uintptr(headerPtr) - uintptr(fls.Header.Length))
+		headerPtr = unsafe.Pointer(uintptr(headerPtr) - length)
 	}
 
 	return &packet, nil
 }
 
 // decodeOption decodes an option template from `packet`
-func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) {
+func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) error {
 	min := uintptr(end) - size
 
-	for uintptr(end) > min {
+	// anything shorter than a header is padding
+	for uintptr(end)-min >= sizeOfOptionsTemplateRecordHeader {
 		headerPtr := unsafe.Pointer(uintptr(end) - sizeOfOptionsTemplateRecordHeader)
 
 		tmplRecs := &TemplateRecords{}
@@ -107,33 +122,51 @@ func decodeOption(packet *Packet, end unsafe.Pointer, size uintptr, remote net.I
 		tmplRecs.Header = &TemplateRecordHeader{TemplateID: hdr.TemplateID}
 		tmplRecs.Packet = packet
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
+		tmplRecs.IsOptionsTemplate = true
+
+		recordSize := uintptr(hdr.OptionScopeLength) + uintptr(hdr.OptionLength)
+		if uintptr(headerPtr)-min < recordSize {
+			return errors.Wrapf(ErrTruncated, "Option template %d exceeds flow set", hdr.TemplateID)
+		}
 
 		ptr := headerPtr
 		// Process option scopes
 		for i := uint16(0); i < hdr.OptionScopeLength/uint16(sizeOfOptionScope); i++ {
+			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfOptionScope)
 			optScope := (*OptionScope)(ptr)
+			if optScope.ScopeFieldLength == 0 {
+				return errors.Wrapf(ErrInvalidFieldLength, "Option template %d: scope type %d", hdr.TemplateID, optScope.ScopeFieldType)
+			}
+
 			tmplRecs.OptionScopes = append(tmplRecs.OptionScopes, optScope)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfOptionScope)
 		}
 
 		// Process option fields
 		for i := uint16(0); i < hdr.OptionLength/uint16(sizeOfTemplateRecord); i++ {
+			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
 			opt := (*TemplateRecord)(ptr)
+			if opt.Length == 0 {
+				return errors.Wrapf(ErrInvalidFieldLength, "Option template %d: field type %d", hdr.TemplateID, opt.Type)
+			}
+
 			tmplRecs.Records = append(tmplRecs.Records, opt)
-			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
 		}
 
 		//packet.OptionsTemplates = append(packet.OptionsTemplates, tmplRecs)
 		packet.Templates = append(packet.Templates, tmplRecs)
 
-		end = unsafe.Pointer(uintptr(end) - uintptr(hdr.OptionScopeLength) - uintptr(hdr.OptionLength) - sizeOfOptionsTemplateRecordHeader)
+		end = unsafe.Pointer(uintptr(headerPtr) - recordSize)
 	}
+
+	return nil
 }
 
 // decodeTemplate decodes a template from `packet`
-func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) {
+func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr, remote net.IP) error {
 	min := uintptr(end) - size
-	for uintptr(end) > min {
+
+	// anything shorter than a header is padding
+	for uintptr(end)-min >= sizeOfTemplateRecordHeader {
 		headerPtr := unsafe.Pointer(uintptr(end) - sizeOfTemplateRecordHeader)
 
 		tmplRecs := &TemplateRecords{}
@@ -141,27 +174,39 @@ func decodeTemplate(packet *Packet, end unsafe.Pointer, size uintptr, remote net
 		tmplRecs.Packet = packet
 		tmplRecs.Records = make([]*TemplateRecord, 0, numPreAllocRecs)
 
-		ptr := unsafe.Pointer(uintptr(headerPtr) - sizeOfTemplateRecordHeader)
-		var i uint16
-		for i = 0; i < tmplRecs.Header.FieldCount; i++ {
-			rec := (*TemplateRecord)(unsafe.Pointer(ptr))
-			tmplRecs.Records = append(tmplRecs.Records, rec)
+		recordSize := uintptr(tmplRecs.Header.FieldCount) * sizeOfTemplateRecord
+		if uintptr(headerPtr)-min < recordSize {
+			return errors.Wrapf(ErrTruncated, "Template %d exceeds flow set", tmplRecs.Header.TemplateID)
+		}
+
+		ptr := headerPtr
+		for i := uint16(0); i < tmplRecs.Header.FieldCount; i++ {
 			ptr = unsafe.Pointer(uintptr(ptr) - sizeOfTemplateRecord)
+			rec := (*TemplateRecord)(ptr)
+
+			// Zero length fields would allow a single byte record to allocate a value for each field
+			if rec.Length == 0 {
+				return errors.Wrapf(ErrInvalidFieldLength, "Template %d: field type %d", tmplRecs.Header.TemplateID, rec.Type)
+			}
+
+			tmplRecs.Records = append(tmplRecs.Records, rec)
 		}
 
 		packet.Templates = append(packet.Templates, tmplRecs)
-		end = unsafe.Pointer(uintptr(end) - uintptr(tmplRecs.Header.FieldCount)*sizeOfTemplateRecord - sizeOfTemplateRecordHeader)
+		end = unsafe.Pointer(uintptr(headerPtr) - recordSize)
 	}
+
+	return nil
 }
 
 // decodeData decodes a flowSet from `packet`
 func decodeData(packet *Packet, headerPtr unsafe.Pointer, size uintptr) {
 	flsh := (*FlowSetHeader)(unsafe.Pointer(headerPtr))
-	data := unsafe.Pointer(uintptr(headerPtr) - uintptr(flsh.Length))
+	data := unsafe.Pointer(uintptr(headerPtr) - size)
 
 	fls := &FlowSet{
 		Header: flsh,
-		Flows:  (*(*[1<<31 - 1]byte)(data))[sizeOfFlowSetHeader:flsh.Length],
+		Flows:  unsafe.Slice((*byte)(data), size),
 	}
 
 	packet.FlowSets = append(packet.FlowSets, fls)
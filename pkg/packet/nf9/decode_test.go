@@ -16,6 +16,7 @@ import (
 	"testing"
 
 	"github.com/bio-routing/tflow2/convert"
+	"github.com/pkg/errors"
 )
 
 /*func TestDecode(t *testing.T) {
@@ -34,7 +35,8 @@ import (
 	}
 }*/
 
-func TestDecode2(t *testing.T) {
+// optionTemplatePacket returns a packet with an options template in wire order
+func optionTemplatePacket() []byte {
 	s := []byte{
 		8, 0, // Length
 		44, 0, // Type
@@ -64,13 +66,44 @@ func TestDecode2(t *testing.T) {
 		75, 91, 213, 103, // sysUpTime
 		1, 0, // Count
 		9, 0} // Version
-	s = convert.Reverse(s)
 
-	_, err := Decode(s, net.IP([]byte{1, 1, 1, 1}))
+	return convert.Reverse(s)
+}
+
+func TestDecode2(t *testing.T) {
+	s := optionTemplatePacket()
+
+	packet, err := Decode(s, net.IP([]byte{1, 1, 1, 1}))
 	if err != nil {
-		t.Errorf("Decoding packet failed: %v\n", err)
+		t.Fatalf("Decoding packet failed: %v\n", err)
+	}
+
+	if len(packet.Templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(packet.Templates))
+	}
+
+	tmpl := packet.Templates[0]
+	if !tmpl.IsOptionsTemplate {
+		t.Errorf("Expected options template")
+	}
+
+	if tmpl.Header.TemplateID != 266 {
+		t.Errorf("Unexpected template ID. Expected 266, got %d", tmpl.Header.TemplateID)
 	}
 
+	if len(tmpl.OptionScopes) != 1 || tmpl.OptionScopes[0].ScopeFieldType != 1 || tmpl.OptionScopes[0].ScopeFieldLength != 4 {
+		t.Errorf("Unexpected option scopes: %v", tmpl.OptionScopes)
+	}
+
+	if len(tmpl.Records) != 4 {
+		t.Fatalf("Expected 4 option fields, got %d", len(tmpl.Records))
+	}
+
+	for i, typ := range []uint16{TotalPktsExp, TotalFlowsExp, VendorProprietary43, IPv4SrcPrefix} {
+		if tmpl.Records[i].Type != typ || tmpl.Records[i].Length != 8 {
+			t.Errorf("Unexpected option field %d: %+v", i, tmpl.Records[i])
+		}
+	}
 }
 
 func testEq(a, b []byte) bool {
@@ -95,3 +128,52 @@ func testEq(a, b []byte) bool {
 
 	return true
 }
+
+func TestDecodeTruncated(t *testing.T) {
+	s := optionTemplatePacket()
+
+	for _, n := range []int{0, 19, 22, 30, len(s) - 1} {
+		_, err := Decode(append([]byte(nil), s[:n]...), net.IP([]byte{1, 1, 1, 1}))
+		if !errors.Is(err, ErrTruncated) {
+			t.Errorf("Expected truncation error for length %d, got %v", n, err)
+		}
+	}
+}
+
+func TestDecodeZeroLengthField(t *testing.T) {
+	s := []byte{
+		0, 0, // Length
+		1, 0, // Type
+
+		4, 0, // Length
+		8, 0, // Type
+
+		2, 0, // FieldCount
+		0, 1, // TemplateID
+
+		16, 0, // Length
+		0, 0, // FlowSetID
+
+		0, 0, 0, 0, //Source ID
+		167, 51, 204, 11, // Sequence Number
+		128, 207, 118, 88, // UNIX secs
+		75, 91, 213, 103, // sysUpTime
+		1, 0, // Count
+		9, 0} // Version
+
+	_, err := Decode(convert.Reverse(s), net.IP([]byte{1, 1, 1, 1}))
+	if !errors.Is(err, ErrInvalidFieldLength) {
+		t.Errorf("Expected invalid field length error, got %v", err)
+	}
+}
+
+func TestDecodeFlowSetZeroLengthFields(t *testing.T) {
+	fields := []*TemplateRecord{{Type: 1}, {Type: 2}}
+	records := DecodeFlowSet(fields, FlowSet{
+		Flows: make([]byte, 64),
+	})
+
+	if records != nil {
+		t.Errorf("Expected no records, got %d", len(records))
+	}
+}
@@ -0,0 +1,14 @@
+package nf9
+
+import "github.com/pkg/errors"
+
+var (
+	// ErrTruncated is returned if a header or length field exceeds the received packet
+	ErrTruncated = errors.New("Truncated packet")
+
+	// ErrInvalidFlowSetLength is returned for flow sets shorter than their own header
+	ErrInvalidFlowSetLength = errors.New("Invalid flow set length")
+
+	// ErrInvalidFieldLength is returned for template fields of length 0
+	ErrInvalidFieldLength = errors.New("Invalid field length")
+)
@@ -0,0 +1,25 @@
+package nf9
+
+import (
+	"net"
+	"testing"
+)
+
+func FuzzDecode(f *testing.F) {
+	f.Add(optionTemplatePacket())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Decode(append([]byte(nil), data...), net.IP([]byte{1, 1, 1, 1}))
+		if err != nil {
+			return
+		}
+
+		for _, tmpl := range p.Templates {
+			for _, fls := range p.FlowSets {
+				if fls.Header.FlowSetID == tmpl.Header.TemplateID {
+					DecodeFlowSet(tmpl.Records, *fls)
+				}
+			}
+		}
+	})
+}
@@ -72,6 +72,10 @@ type TemplateRecords struct {
 	Packet *Packet
 
 	Values [][]byte
+
+	// IsOptionsTemplate is true if this template was received in an
+	// Options Template FlowSet
+	IsOptionsTemplate bool
 }
 
 // OptionScope represents an option scope in an options template flowset
@@ -140,6 +144,11 @@ var sizeOfTemplateRecord = unsafe.Sizeof(TemplateRecord{})
 func DecodeFlowSet(templateRecords []*TemplateRecord, set FlowSet) (list []FlowDataRecord) {
 	var record FlowDataRecord
 
+	// Records without any data can not be told apart from each other
+	if recordLength(templateRecords) == 0 {
+		return nil
+	}
+
 	// Pre-allocate some room for flows
 	list = make([]FlowDataRecord, 0, numPreAllocFlowDataRecs)
 
@@ -151,7 +160,7 @@ func DecodeFlowSet(templateRecords []*TemplateRecord, set FlowSet) (list []FlowD
 
 	for n >= 4 {
 		record.Values, count = parseFieldValues(set.Flows[0:n], templateRecords)
-		if record.Values == nil {
+		if record.Values == nil || count == 0 {
 			return
 		}
 		list = append(list, record)
@@ -161,6 +170,16 @@ func DecodeFlowSet(templateRecords []*TemplateRecord, set FlowSet) (list []FlowD
 	return
 }
 
+// recordLength returns the length of a data record described by fields
+func recordLength(fields []*TemplateRecord) int {
+	n := 0
+	for _, f := range fields {
+		n += int(f.Length)
+	}
+
+	return n
+}
+
 // parseFieldValues reads actual fields values from a Data Record utilizing a template
 func parseFieldValues(flows []byte, fields []*TemplateRecord) ([][]byte, int) {
 	count := 0
@@ -1,8 +1,9 @@
 package packet
 
 import (
-	"fmt"
 	"unsafe"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -16,10 +17,20 @@ type Dot1Q struct {
 	TCI       uint16
 }
 
+// VLANID gets the VLAN identifier of the tag
+func (d *Dot1Q) VLANID() uint16 {
+	return d.TCI & 0x0fff
+}
+
+// IsVLANTag returns true if etherType identifies an 802.1Q or 802.1ad VLAN tag
+func IsVLANTag(etherType uint16) bool {
+	return etherType == EtherTypeIEEE8021Q || etherType == EtherTypeIEEE8021AD || etherType == EtherTypeQinQ
+}
+
 // DecodeDot1Q decodes an 802.1q header
 func DecodeDot1Q(raw unsafe.Pointer, length uint32) (*Dot1Q, error) {
-	if SizeOfEthernetII > uintptr(length) {
-		return nil, fmt.Errorf("frame is too short: %d", length)
+	if SizeOfDot1Q > uintptr(length) {
+		return nil, errors.Wrapf(ErrTruncated, "frame is too short: %d", length)
 	}
 
 	ptr := unsafe.Pointer(uintptr(raw) - SizeOfDot1Q)
@@ -0,0 +1,6 @@
+package packet
+
+import "github.com/pkg/errors"
+
+// ErrTruncated is returned if a header exceeds the captured part of a frame
+var ErrTruncated = errors.New("Truncated frame")
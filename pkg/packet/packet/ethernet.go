@@ -34,6 +34,21 @@ const (
 
 	// EtherTypeIEEE8021Q is VLAN-tagged frame (IEEE 802.1Q) EtherType value
 	EtherTypeIEEE8021Q = 0x8100
+
+	// EtherTypeIEEE8021AD is the service VLAN tag (IEEE 802.1ad) EtherType value
+	EtherTypeIEEE8021AD = 0x88a8
+
+	// EtherTypeQinQ is the pre-standard stacked VLAN tag EtherType value
+	EtherTypeQinQ = 0x9100
+
+	// EtherTypeMPLSUnicast is MPLS unicast EtherType value
+	EtherTypeMPLSUnicast = 0x8847
+
+	// EtherTypeMPLSMulticast is MPLS multicast EtherType value
+	EtherTypeMPLSMulticast = 0x8848
+
+	// EtherTypeTransparentEthernetBridging is the protocol type of Ethernet frames carried in GRE
+	EtherTypeTransparentEthernetBridging = 0x6558
 )
 
 var (
@@ -57,7 +72,7 @@ type ethernetII struct {
 // DecodeEthernet decodes an EthernetII header
 func DecodeEthernet(raw unsafe.Pointer, length uint32) (*EthernetHeader, error) {
 	if SizeOfEthernetII > uintptr(length) {
-		return nil, errors.Errorf("Frame is too short: %d", length)
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
 	}
 
 	ptr := unsafe.Pointer(uintptr(raw) - SizeOfEthernetII)
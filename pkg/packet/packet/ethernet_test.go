@@ -15,8 +15,8 @@ import (
 	"unsafe"
 )
 
-func TestDecode(t *testing.T) {
-	data := []byte{
+// ethernetFrame is an Ethernet frame carrying IPv4 and TCP in reversed order
+var ethernetFrame = []byte{
 		128,               // Header Length
 		92, 180, 133, 203, // ACK Number
 		31, 4, 191, 24, // Sequence Number
@@ -37,8 +37,10 @@ func TestDecode(t *testing.T) {
 		0, 8, // EtherType
 		185, 28, 4, 113, 78, 32, // Source MAC
 		148, 2, 127, 31, 113, 128, // Destination MAC
-	}
+}
 
+func TestDecode(t *testing.T) {
+	data := ethernetFrame
 	pSize := len(data)
 	bufSize := 128
 	buffer := [128]byte{}
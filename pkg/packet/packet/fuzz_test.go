@@ -0,0 +1,57 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/bio-routing/tflow2/convert"
+)
+
+func FuzzDecoders(f *testing.F) {
+	frame := make([]byte, len(ethernetFrame))
+	copy(frame, ethernetFrame)
+	f.Add(convert.Reverse(frame))
+
+	for _, test := range greTests {
+		f.Add(test.data)
+	}
+
+	for _, test := range ipv6ExtensionHeaderTests {
+		f.Add(test.data)
+	}
+
+	for _, test := range mplsTests {
+		f.Add(test.data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+
+		ptr, length := reversedBuffer(data)
+
+		DecodeEthernet(ptr, length)
+		DecodeDot1Q(ptr, length)
+		DecodeIPv4(ptr, length)
+		DecodeIPv6(ptr, length)
+		DecodeTCP(ptr, length)
+		DecodeUDP(ptr, length)
+		DecodeICMP(ptr, length)
+		DecodeVXLAN(ptr, length)
+		DecodeIPVersion(ptr, length)
+
+		if gre, err := DecodeGRE(ptr, length); err == nil && gre.Length > length {
+			t.Fatalf("GRE header of %d bytes exceeds frame of %d bytes", gre.Length, length)
+		}
+
+		if labels, err := DecodeMPLS(ptr, length); err == nil && uint32(len(labels))*uint32(SizeOfMPLSLabel) > length {
+			t.Fatalf("%d labels exceed frame of %d bytes", len(labels), length)
+		}
+
+		for _, nextHeader := range []uint8{IPv6HopByHop, IPv6Routing, IPv6Fragment, IPv6DestinationOptions, IPv6AuthHeader} {
+			if p, err := WalkIPv6ExtensionHeaders(ptr, length, nextHeader); err == nil && p.Length > length {
+				t.Fatalf("Extension headers of %d bytes exceed frame of %d bytes", p.Length, length)
+			}
+		}
+	})
+}
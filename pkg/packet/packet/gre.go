@@ -0,0 +1,76 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// GRE IP protocol number
+	GRE = 47
+
+	greChecksumPresent = 0x8000
+	greKeyPresent      = 0x2000
+	greSequencePresent = 0x1000
+)
+
+var (
+	sizeOfGREBaseHeader = unsafe.Sizeof(greBaseHeader{})
+)
+
+type greBaseHeader struct {
+	ProtocolType uint16
+	FlagsVersion uint16
+}
+
+// GREHeader represents a GRE header (RFC2784, RFC2890)
+type GREHeader struct {
+	ProtocolType uint16
+	KeyPresent   bool
+	Key          uint32
+
+	// Length is the size of the header including optional fields in bytes
+	Length uint32
+}
+
+// DecodeGRE decodes a GRE header
+func DecodeGRE(raw unsafe.Pointer, length uint32) (*GREHeader, error) {
+	if sizeOfGREBaseHeader > uintptr(length) {
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
+	}
+
+	base := (*greBaseHeader)(unsafe.Pointer(uintptr(raw) - sizeOfGREBaseHeader))
+	h := &GREHeader{
+		ProtocolType: base.ProtocolType,
+		Length:       uint32(sizeOfGREBaseHeader),
+	}
+
+	if base.FlagsVersion&greChecksumPresent != 0 {
+		h.Length += 4
+	}
+
+	if base.FlagsVersion&greKeyPresent != 0 {
+		h.KeyPresent = true
+		h.Length += 4
+	}
+
+	if base.FlagsVersion&greSequencePresent != 0 {
+		h.Length += 4
+	}
+
+	if h.Length > length {
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short for GRE header of %d bytes: %d", h.Length, length)
+	}
+
+	if h.KeyPresent {
+		keyOffset := uintptr(h.Length)
+		if base.FlagsVersion&greSequencePresent != 0 {
+			keyOffset -= 4
+		}
+
+		h.Key = *(*uint32)(unsafe.Pointer(uintptr(raw) - keyOffset))
+	}
+
+	return h, nil
+}
@@ -0,0 +1,62 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var greTests = []struct {
+	name     string
+	data     []byte
+	wantFail bool
+	expected *GREHeader
+}{
+	{
+		name: "Basic header",
+		data: []byte{
+			0x00, 0x00, 0x08, 0x00, // Flags, Protocol Type
+		},
+		expected: &GREHeader{
+			ProtocolType: EtherTypeIPv4,
+			Length:       4,
+		},
+	},
+	{
+		name: "Checksum, key and sequence number",
+		data: []byte{
+			0xb0, 0x00, 0x65, 0x58, // Flags, Protocol Type
+			0x12, 0x34, 0x00, 0x00, // Checksum, Reserved
+			0x00, 0x00, 0x30, 0x39, // Key
+			0x00, 0x00, 0x00, 0x01, // Sequence Number
+		},
+		expected: &GREHeader{
+			ProtocolType: EtherTypeTransparentEthernetBridging,
+			KeyPresent:   true,
+			Key:          12345,
+			Length:       16,
+		},
+	},
+	{
+		name: "Truncated key",
+		data: []byte{
+			0x20, 0x00, 0x86, 0xdd, // Flags, Protocol Type
+			0x00, 0x00,
+		},
+		wantFail: true,
+	},
+}
+
+func TestDecodeGRE(t *testing.T) {
+	for _, test := range greTests {
+		ptr, length := reversedBuffer(test.data)
+		h, err := DecodeGRE(ptr, length)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, h, test.name)
+	}
+}
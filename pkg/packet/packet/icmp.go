@@ -0,0 +1,36 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ICMP IP protocol number
+	ICMP = 1
+
+	// ICMPv6 IP protocol number
+	ICMPv6 = 58
+)
+
+var (
+	// SizeOfICMPHeader is the size of an ICMP header (without the rest of header field) in bytes
+	SizeOfICMPHeader = unsafe.Sizeof(ICMPHeader{})
+)
+
+// ICMPHeader represents the type, code and checksum of an ICMP or ICMPv6 header
+type ICMPHeader struct {
+	Checksum uint16
+	Code     uint8
+	Type     uint8
+}
+
+// DecodeICMP decodes an ICMP or ICMPv6 header
+func DecodeICMP(raw unsafe.Pointer, length uint32) (*ICMPHeader, error) {
+	if SizeOfICMPHeader > uintptr(length) {
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
+	}
+
+	return (*ICMPHeader)(unsafe.Pointer(uintptr(raw) - SizeOfICMPHeader)), nil
+}
@@ -17,6 +17,14 @@ import (
 	"github.com/pkg/errors"
 )
 
+const (
+	// IPIP is the IP protocol number of IPv4 encapsulated in IP
+	IPIP = 4
+
+	ipv4MoreFragments      = 0x2000
+	ipv4FragmentOffsetMask = 0x1fff
+)
+
 var (
 	SizeOfIPv4Header = unsafe.Sizeof(IPv4Header{})
 )
@@ -34,9 +42,24 @@ type IPv4Header struct {
 	VersionHeaderLength uint8
 }
 
+// HeaderLength gets the length of the header including options in bytes
+func (h *IPv4Header) HeaderLength() uint32 {
+	return uint32(h.VersionHeaderLength&0x0f) * 4
+}
+
+// IsFragment returns true if the packet is a fragment of a larger packet
+func (h *IPv4Header) IsFragment() bool {
+	return h.FlagsFragmentOffset&ipv4MoreFragments != 0 || h.FragmentOffset() != 0
+}
+
+// FragmentOffset gets the offset of a fragment in 8 byte units
+func (h *IPv4Header) FragmentOffset() uint16 {
+	return h.FlagsFragmentOffset & ipv4FragmentOffsetMask
+}
+
 func DecodeIPv4(raw unsafe.Pointer, length uint32) (*IPv4Header, error) {
 	if SizeOfIPv4Header > uintptr(length) {
-		return nil, errors.Errorf("frame is too short: %d", length)
+		return nil, errors.Wrapf(ErrTruncated, "frame is too short: %d", length)
 	}
 
 	return (*IPv4Header)(unsafe.Pointer(uintptr(raw) - SizeOfIPv4Header)), nil
@@ -16,6 +16,11 @@ import (
 	"unsafe"
 )
 
+const (
+	// IPv6Encap is the IP protocol number of IPv6 encapsulated in IP
+	IPv6Encap = 41
+)
+
 var (
 	SizeOfIPv6Header = unsafe.Sizeof(IPv6Header{})
 )
@@ -31,7 +36,7 @@ type IPv6Header struct {
 
 func DecodeIPv6(raw unsafe.Pointer, length uint32) (*IPv6Header, error) {
 	if SizeOfIPv6Header > uintptr(length) {
-		return nil, errors.Errorf("Frame is too short: %d", length)
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
 	}
 
 	return (*IPv6Header)(unsafe.Pointer(uintptr(raw) - SizeOfIPv6Header)), nil
@@ -0,0 +1,92 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// IPv6 extension header types
+const (
+	IPv6HopByHop           = 0
+	IPv6Routing            = 43
+	IPv6Fragment           = 44
+	IPv6AuthHeader         = 51
+	IPv6DestinationOptions = 60
+	IPv6Mobility           = 135
+	IPv6HostIdentity       = 139
+	IPv6Shim6              = 140
+
+	// maxIPv6ExtensionHeaders limits the length of the extension header chain walked
+	maxIPv6ExtensionHeaders = 16
+
+	sizeOfIPv6FragmentHeader = 8
+	ipv6FragmentOffsetMask   = 0xfff8
+	ipv6MoreFragments        = 0x0001
+)
+
+// IPv6Payload describes the upper-layer payload of an IPv6 packet
+type IPv6Payload struct {
+	// Protocol is the upper-layer protocol following all extension headers
+	Protocol uint8
+
+	// Length is the size of all extension headers in bytes
+	Length uint32
+
+	// Fragment is set if the packet carries a fragment header
+	Fragment bool
+
+	// FragmentOffset is the offset of the fragment in 8 byte units
+	FragmentOffset uint16
+}
+
+// WalkIPv6ExtensionHeaders follows the extension header chain starting at raw to the
+// upper-layer protocol. nextHeader is the NextHeader field of the IPv6 header.
+func WalkIPv6ExtensionHeaders(raw unsafe.Pointer, length uint32, nextHeader uint8) (*IPv6Payload, error) {
+	p := &IPv6Payload{
+		Protocol: nextHeader,
+	}
+
+	for i := 0; i < maxIPv6ExtensionHeaders; i++ {
+		hdrLen := uint32(0)
+		switch p.Protocol {
+		case IPv6HopByHop, IPv6Routing, IPv6DestinationOptions, IPv6Mobility, IPv6HostIdentity, IPv6Shim6:
+			if p.Length+2 > length {
+				return nil, errors.Wrapf(ErrTruncated, "Extension header %d exceeds frame: %d", p.Protocol, length)
+			}
+
+			hdrLen = (uint32(byteAt(raw, p.Length+1)) + 1) * 8
+		case IPv6AuthHeader:
+			if p.Length+2 > length {
+				return nil, errors.Wrapf(ErrTruncated, "Extension header %d exceeds frame: %d", p.Protocol, length)
+			}
+
+			hdrLen = (uint32(byteAt(raw, p.Length+1)) + 2) * 4
+		case IPv6Fragment:
+			if p.Length+sizeOfIPv6FragmentHeader > length {
+				return nil, errors.Wrapf(ErrTruncated, "Fragment header exceeds frame: %d", length)
+			}
+
+			offsetFlags := uint16(byteAt(raw, p.Length+2))<<8 | uint16(byteAt(raw, p.Length+3))
+			p.Fragment = true
+			p.FragmentOffset = (offsetFlags & ipv6FragmentOffsetMask) >> 3
+			hdrLen = sizeOfIPv6FragmentHeader
+		default:
+			return p, nil
+		}
+
+		if p.Length+hdrLen > length {
+			return nil, errors.Wrapf(ErrTruncated, "Extension header %d exceeds frame: %d", p.Protocol, length)
+		}
+
+		p.Protocol = byteAt(raw, p.Length)
+		p.Length += hdrLen
+	}
+
+	return nil, errors.Errorf("Too many extension headers")
+}
+
+// byteAt gets the byte at offset (in wire order) of a reversed buffer ending at raw
+func byteAt(raw unsafe.Pointer, offset uint32) uint8 {
+	return *(*uint8)(unsafe.Pointer(uintptr(raw) - uintptr(offset) - 1))
+}
@@ -0,0 +1,78 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ipv6ExtensionHeaderTests = []struct {
+	name       string
+	nextHeader uint8
+	data       []byte
+	wantFail   bool
+	expected   *IPv6Payload
+}{
+	{
+		name:       "No extension headers",
+		nextHeader: TCP,
+		data: []byte{
+			0x00, 0x50, 0xc3, 0x50, // Source Port, Destination Port
+		},
+		expected: &IPv6Payload{
+			Protocol: TCP,
+		},
+	},
+	{
+		name:       "Hop-by-hop and first fragment",
+		nextHeader: IPv6HopByHop,
+		data: []byte{
+			0x2c, 0x00, 0x01, 0x04, 0x00, 0x00, 0x00, 0x00, // Hop-by-hop: Next Header, Length, PadN
+			0x06, 0x00, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78, // Fragment: Next Header, Reserved, Offset + M, Identification
+			0x00, 0x50, 0xc3, 0x50, // Source Port, Destination Port
+		},
+		expected: &IPv6Payload{
+			Protocol: TCP,
+			Length:   16,
+			Fragment: true,
+		},
+	},
+	{
+		name:       "Destination options, routing and non-first fragment",
+		nextHeader: IPv6DestinationOptions,
+		data: []byte{
+			0x2b, 0x00, 0x01, 0x04, 0x00, 0x00, 0x00, 0x00, // Destination options
+			0x2c, 0x02, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, // Routing: Next Header, Length (24 bytes), Type, Segments Left
+			0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, // Segment
+			0x11, 0x00, 0x05, 0xc8, 0x12, 0x34, 0x56, 0x78, // Fragment: offset 185
+		},
+		expected: &IPv6Payload{
+			Protocol:       UDP,
+			Length:         40,
+			Fragment:       true,
+			FragmentOffset: 185,
+		},
+	},
+	{
+		name:       "Truncated extension header",
+		nextHeader: IPv6HopByHop,
+		data: []byte{
+			0x06, 0x01, 0x01, 0x04, 0x00, 0x00, 0x00, 0x00,
+		},
+		wantFail: true,
+	},
+}
+
+func TestWalkIPv6ExtensionHeaders(t *testing.T) {
+	for _, test := range ipv6ExtensionHeaderTests {
+		ptr, length := reversedBuffer(test.data)
+		p, err := WalkIPv6ExtensionHeaders(ptr, length, test.nextHeader)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, p, test.name)
+	}
+}
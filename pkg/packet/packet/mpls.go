@@ -0,0 +1,54 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// SizeOfMPLSLabel is the size of an MPLS label stack entry in bytes
+	SizeOfMPLSLabel = unsafe.Sizeof(uint32(0))
+)
+
+// MPLSLabel represents an MPLS label stack entry (RFC3032)
+type MPLSLabel struct {
+	Label         uint32
+	TrafficClass  uint8
+	BottomOfStack bool
+	TTL           uint8
+}
+
+// DecodeMPLS decodes an MPLS label stack up to and including the bottom of stack entry
+func DecodeMPLS(raw unsafe.Pointer, length uint32) ([]MPLSLabel, error) {
+	labels := make([]MPLSLabel, 0, 2)
+	for {
+		size := uintptr(len(labels)+1) * SizeOfMPLSLabel
+		if size > uintptr(length) {
+			return nil, errors.Wrapf(ErrTruncated, "Label stack exceeds frame: %d", length)
+		}
+
+		entry := *(*uint32)(unsafe.Pointer(uintptr(raw) - size))
+		l := MPLSLabel{
+			Label:         entry >> 12,
+			TrafficClass:  uint8(entry>>9) & 0x7,
+			BottomOfStack: entry&0x100 != 0,
+			TTL:           uint8(entry),
+		}
+
+		labels = append(labels, l)
+		if l.BottomOfStack {
+			return labels, nil
+		}
+	}
+}
+
+// DecodeIPVersion gets the version field of the IP header at raw. This is used to determine
+// the payload of headers not indicating the type of their payload, e.g. MPLS.
+func DecodeIPVersion(raw unsafe.Pointer, length uint32) (uint8, error) {
+	if length < 1 {
+		return 0, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
+	}
+
+	return *(*uint8)(unsafe.Pointer(uintptr(raw) - 1)) >> 4, nil
+}
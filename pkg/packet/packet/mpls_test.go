@@ -0,0 +1,75 @@
+package packet
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/bio-routing/tflow2/convert"
+	"github.com/stretchr/testify/assert"
+)
+
+// reversedBuffer copies wire order bytes into a reversed buffer and returns a pointer to its end
+func reversedBuffer(data []byte) (unsafe.Pointer, uint32) {
+	buffer := make([]byte, len(data))
+	copy(buffer, data)
+	convert.Reverse(buffer)
+
+	return unsafe.Pointer(uintptr(unsafe.Pointer(&buffer[0])) + uintptr(len(buffer))), uint32(len(buffer))
+}
+
+var mplsTests = []struct {
+	name     string
+	data     []byte
+	wantFail bool
+	expected []MPLSLabel
+}{
+	{
+		name: "Two labels followed by IPv4",
+		data: []byte{
+			0x00, 0x3e, 0x80, 0x40, // Label 1000, TC 0, TTL 64
+			0x00, 0x01, 0x4b, 0x3f, // Label 20, TC 5, BoS, TTL 63
+			0x45, 0x00,
+		},
+		expected: []MPLSLabel{
+			{
+				Label: 1000,
+				TTL:   64,
+			},
+			{
+				Label:         20,
+				TrafficClass:  5,
+				BottomOfStack: true,
+				TTL:           63,
+			},
+		},
+	},
+	{
+		name: "No bottom of stack",
+		data: []byte{
+			0x00, 0x3e, 0x80, 0x40,
+			0x00, 0x01, 0x4a, 0x3f,
+		},
+		wantFail: true,
+	},
+}
+
+func TestDecodeMPLS(t *testing.T) {
+	for _, test := range mplsTests {
+		ptr, length := reversedBuffer(test.data)
+		labels, err := DecodeMPLS(ptr, length)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		assert.Equal(t, test.expected, labels, test.name)
+
+		v, err := DecodeIPVersion(unsafe.Pointer(uintptr(ptr)-uintptr(len(labels))*SizeOfMPLSLabel), length-uint32(len(labels))*uint32(SizeOfMPLSLabel))
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, uint8(4), v, test.name)
+	}
+}
@@ -19,6 +19,16 @@ import (
 
 const (
 	TCP = 6
+
+	// TCP flags
+	TCPFlagFIN = 0x01
+	TCPFlagSYN = 0x02
+	TCPFlagRST = 0x04
+	TCPFlagPSH = 0x08
+	TCPFlagACK = 0x10
+	TCPFlagURG = 0x20
+	TCPFlagECE = 0x40
+	TCPFlagCWR = 0x80
 )
 
 var (
@@ -39,7 +49,7 @@ type TCPHeader struct {
 
 func DecodeTCP(raw unsafe.Pointer, length uint32) (*TCPHeader, error) {
 	if SizeOfTCPHeader > uintptr(length) {
-		return nil, errors.Errorf("Frame is too short: %d", length)
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
 	}
 
 	return (*TCPHeader)(unsafe.Pointer(uintptr(raw) - SizeOfTCPHeader)), nil
@@ -37,8 +37,8 @@ type UDPHeader struct {
 
 // DecodeUDP decodes a UDP header
 func DecodeUDP(raw unsafe.Pointer, length uint32) (*UDPHeader, error) {
-	if SizeOfTCPHeader > uintptr(length) {
-		return nil, errors.Errorf("Frame is too short: %d", length)
+	if SizeOfUDPHeader > uintptr(length) {
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
 	}
 
 	return (*UDPHeader)(unsafe.Pointer(uintptr(raw) - SizeOfUDPHeader)), nil
@@ -0,0 +1,45 @@
+package packet
+
+import (
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// VXLANPort is the IANA assigned UDP port of VXLAN
+	VXLANPort = 4789
+
+	vxlanValidVNI = 0x08000000
+)
+
+var (
+	// SizeOfVXLANHeader is the size of a VXLAN header in bytes
+	SizeOfVXLANHeader = unsafe.Sizeof(vxlanHeader{})
+)
+
+type vxlanHeader struct {
+	VNIReserved   uint32
+	FlagsReserved uint32
+}
+
+// VXLANHeader represents a VXLAN header (RFC7348)
+type VXLANHeader struct {
+	VNI uint32
+}
+
+// DecodeVXLAN decodes a VXLAN header
+func DecodeVXLAN(raw unsafe.Pointer, length uint32) (*VXLANHeader, error) {
+	if SizeOfVXLANHeader > uintptr(length) {
+		return nil, errors.Wrapf(ErrTruncated, "Frame is too short: %d", length)
+	}
+
+	h := (*vxlanHeader)(unsafe.Pointer(uintptr(raw) - SizeOfVXLANHeader))
+	if h.FlagsReserved&vxlanValidVNI == 0 {
+		return nil, errors.Errorf("VNI flag not set")
+	}
+
+	return &VXLANHeader{
+		VNI: h.VNIReserved >> 8,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package packet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeVXLAN(t *testing.T) {
+	ptr, length := reversedBuffer([]byte{
+		0x08, 0x00, 0x00, 0x00, // Flags, Reserved
+		0x01, 0x86, 0xa0, 0x00, // VNI, Reserved
+	})
+	h, err := DecodeVXLAN(ptr, length)
+	assert.NoError(t, err)
+	assert.Equal(t, &VXLANHeader{VNI: 100000}, h)
+
+	ptr, length = reversedBuffer([]byte{
+		0x00, 0x00, 0x00, 0x00, // Flags, Reserved
+		0x01, 0x86, 0xa0, 0x00, // VNI, Reserved
+	})
+	_, err = DecodeVXLAN(ptr, length)
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package sflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// counterSampleDatagram returns a datagram with a generic and an ethernet interface counter record
+func counterSampleDatagram() []byte {
+	s := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent Address Type
+		10, 205, 19, 14, // Agent Address
+		0, 0, 0, 0, // Sub-AgentID
+		0, 0, 0, 222, // Sequence Number
+		0, 0, 0, 111, // SysUpTime
+		0, 0, 0, 1, // NumSamples
+
+		0, 0, 0, 2, // Enterprise/Type (Counter sample)
+		0, 0, 0, 168, // Sample length
+		0, 0, 0, 7, // Sequence Number
+		0, 0, 0, 3, // Source ID Type + Index
+		0, 0, 0, 2, // Counter Record count
+
+		0, 0, 0, 1, // Enterprise/Type (Generic interface counters)
+		0, 0, 0, 88, // Counter Data Length
+		0, 0, 0, 3, // ifIndex
+		0, 0, 0, 6, // ifType
+		0, 0, 0, 2, 84, 11, 228, 0, // ifSpeed
+		0, 0, 0, 1, // ifDirection
+		0, 0, 0, 3, // ifStatus
+		0, 0, 0, 1, 0, 0, 0, 0, // ifInOctets
+		0, 0, 0, 100, // ifInUcastPkts
+		0, 0, 0, 2, // ifInMulticastPkts
+		0, 0, 0, 3, // ifInBroadcastPkts
+		0, 0, 0, 4, // ifInDiscards
+		0, 0, 0, 5, // ifInErrors
+		0, 0, 0, 6, // ifInUnknownProtos
+		0, 0, 0, 0, 0, 0, 16, 0, // ifOutOctets
+		0, 0, 0, 200, // ifOutUcastPkts
+		0, 0, 0, 7, // ifOutMulticastPkts
+		0, 0, 0, 8, // ifOutBroadcastPkts
+		0, 0, 0, 9, // ifOutDiscards
+		0, 0, 0, 10, // ifOutErrors
+		0, 0, 0, 0, // ifPromiscuousMode
+
+		0, 0, 0, 2, // Enterprise/Type (Ethernet interface counters)
+		0, 0, 0, 52, // Counter Data Length
+		0, 0, 0, 1, // dot3StatsAlignmentErrors
+		0, 0, 0, 2, // dot3StatsFCSErrors
+		0, 0, 0, 3, // dot3StatsSingleCollisionFrames
+		0, 0, 0, 4, // dot3StatsMultipleCollisionFrames
+		0, 0, 0, 5, // dot3StatsSQETestErrors
+		0, 0, 0, 6, // dot3StatsDeferredTransmissions
+		0, 0, 0, 7, // dot3StatsLateCollisions
+		0, 0, 0, 8, // dot3StatsExcessiveCollisions
+		0, 0, 0, 9, // dot3StatsInternalMacTransmitErrors
+		0, 0, 0, 10, // dot3StatsCarrierSenseErrors
+		0, 0, 0, 11, // dot3StatsFrameTooLongs
+		0, 0, 0, 12, // dot3StatsInternalMacReceiveErrors
+		0, 0, 0, 13, // dot3StatsSymbolErrors
+	}
+
+	return s
+}
+
+func TestDecodeCounterSample(t *testing.T) {
+	s := counterSampleDatagram()
+
+	p, err := Decode(s)
+	if err != nil {
+		t.Fatalf("Decoding packet failed: %v", err)
+	}
+
+	assert.Len(t, p.FlowSamples, 0)
+	if !assert.Len(t, p.CounterSamples, 1) {
+		return
+	}
+
+	cs := p.CounterSamples[0]
+	assert.Equal(t, uint32(7), cs.SequenceNumber)
+	assert.Equal(t, uint32(0), cs.SourceIDType)
+	assert.Equal(t, uint32(3), cs.SourceIDIndex)
+
+	gc := cs.GenericInterfaceCounters
+	if assert.NotNil(t, gc) {
+		assert.Equal(t, uint32(3), gc.IfIndex)
+		assert.Equal(t, uint32(6), gc.IfType)
+		assert.Equal(t, uint64(10000000000), gc.IfSpeed)
+		assert.Equal(t, uint32(1), gc.IfDirection)
+		assert.Equal(t, uint32(3), gc.IfStatus)
+		assert.Equal(t, uint64(1<<32), gc.IfInOctets)
+		assert.Equal(t, uint32(100), gc.IfInUcastPkts)
+		assert.Equal(t, uint32(6), gc.IfInUnknownProtos)
+		assert.Equal(t, uint64(4096), gc.IfOutOctets)
+		assert.Equal(t, uint32(200), gc.IfOutUcastPkts)
+		assert.Equal(t, uint32(10), gc.IfOutErrors)
+	}
+
+	ec := cs.EthernetCounters
+	if assert.NotNil(t, ec) {
+		assert.Equal(t, uint32(1), ec.Dot3StatsAlignmentErrors)
+		assert.Equal(t, uint32(2), ec.Dot3StatsFCSErrors)
+		assert.Equal(t, uint32(13), ec.Dot3StatsSymbolErrors)
+	}
+}
@@ -12,6 +12,7 @@
 package sflow
 
 import (
+	"encoding/binary"
 	"net"
 	"unsafe"
 
@@ -22,13 +23,17 @@ import (
 )
 
 const (
-	dataFlowSample     = 1
-	expandedFlowSample = 3
-	dataCounterSample  = 2
-	standardSflow      = 0
-	rawPacketHeader    = 1
-	extendedSwitchData = 1001
-	extendedRouterData = 1002
+	dataFlowSample           = 1
+	expandedFlowSample       = 3
+	dataCounterSample        = 2
+	expandedCounterSample    = 4
+	standardSflow            = 0
+	rawPacketHeader          = 1
+	extendedSwitchData       = 1001
+	extendedRouterData       = 1002
+	extendedGatewayData      = 1003
+	genericInterfaceCounters = 1
+	ethernetInterfaceCounter = 2
 )
 
 // errorIncompatibleVersion prints an error message in case the detected version is not supported
@@ -40,48 +45,38 @@ func errorIncompatibleVersion(version uint32) error {
 func Decode(raw []byte) (*Packet, error) {
 	data := convert.Reverse(raw) //TODO: Make it endian aware. This assumes a little endian machine
 
-	pSize := len(data)
-	bufSize := 1500
-	buffer := [1500]byte{}
+	// copy data as the decoded packet points into it while raw is reused by the caller
+	buffer := make([]byte, len(data))
+	copy(buffer, data)
 
-	if pSize > bufSize {
-		panic("Buffer too small\n")
-	}
+	var p Packet
+	p.Buffer = buffer
 
-	// copy data into array as arrays allow us to cast the shit out of it
-	for i := 0; i < pSize; i++ {
-		buffer[bufSize-pSize+i] = data[i]
+	r := newReversedReader(buffer)
+	p.headerTop = (*headerTop)(r.next(sizeOfHeaderTop))
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "Unable to decode header")
 	}
 
-	bufferPtr := unsafe.Pointer(&buffer)
-	//bufferMinPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(pSize))
-	headerPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeaderTop))
-
-	var p Packet
-	p.Buffer = buffer[:]
-	p.headerTop = (*headerTop)(headerPtr)
-
 	if p.headerTop.Version != 5 {
-		return nil, errorIncompatibleVersion(p.Header.Version)
+		return nil, errorIncompatibleVersion(p.headerTop.Version)
 	}
 
-	agentAddressLen := uint64(0)
-	switch p.headerTop.AgentAddressType {
-	default:
-		return nil, errors.Errorf("Unknown AgentAddressType %d", p.headerTop.AgentAddressType)
-	case 1:
-		agentAddressLen = 4
-	case 2:
-		agentAddressLen = 16
+	agentAddressLen, err := addressLength(p.headerTop.AgentAddressType)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode agent address")
 	}
 
-	headerBottomPtr := unsafe.Pointer(uintptr(bufferPtr) + uintptr(bufSize) - uintptr(sizeOfHeaderTop) - uintptr(agentAddressLen) - uintptr(sizeOfHeaderBottom))
-	p.headerBottom = (*headerBottom)(headerBottomPtr)
+	agentAddress := r.netIP(agentAddressLen)
+	p.headerBottom = (*headerBottom)(r.next(sizeOfHeaderBottom))
+	if r.err != nil {
+		return nil, errors.Wrap(r.err, "Unable to decode header")
+	}
 
 	h := Header{
 		Version:          p.headerTop.Version,
 		AgentAddressType: p.headerTop.AgentAddressType,
-		AgentAddress:     getNetIP(headerPtr, agentAddressLen),
+		AgentAddress:     agentAddress,
 		SubAgentID:       p.headerBottom.SubAgentID,
 		SequenceNumber:   p.headerBottom.SequenceNumber,
 		SysUpTime:        p.headerBottom.SysUpTime,
@@ -89,161 +84,443 @@ func Decode(raw []byte) (*Packet, error) {
 	}
 	p.Header = &h
 
-	flowSamples, err := decodeFlowSamples(headerBottomPtr, h.NumSamples)
+	flowSamples, counterSamples, err := decodeSamples(r, h.NumSamples)
 	if err != nil {
 		return nil, errors.Wrap(err, "Unable to dissect flows")
 	}
 	p.FlowSamples = flowSamples
+	p.CounterSamples = counterSamples
 
 	return &p, nil
 }
 
+// AgentAddress reads the agent address from the header of a raw datagram without decoding it.
+// raw is not modified.
+func AgentAddress(raw []byte) (net.IP, error) {
+	if len(raw) < 8 {
+		return nil, errors.Wrapf(ErrTruncated, "Datagram is too short: %d", len(raw))
+	}
+
+	version := binary.BigEndian.Uint32(raw[0:4])
+	if version != 5 {
+		return nil, errorIncompatibleVersion(version)
+	}
+
+	addressLen, err := addressLength(binary.BigEndian.Uint32(raw[4:8]))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to decode agent address")
+	}
+
+	if uint64(len(raw)) < 8+addressLen {
+		return nil, errors.Wrap(ErrTruncated, "Agent address exceeds datagram")
+	}
+
+	return net.IP(append([]byte(nil), raw[8:8+addressLen]...)), nil
+}
+
 func extractEnterpriseFormat(sfType uint32) (sfTypeEnterprise uint32, sfTypeFormat uint32) {
 	return sfType >> 12, sfType & 0xfff
 }
 
-func decodeFlowSamples(samplesPtr unsafe.Pointer, NumSamples uint32) ([]*FlowSample, error) {
+// addressLength gets the length of an address of addressType in bytes
+func addressLength(addressType uint32) (uint64, error) {
+	switch addressType {
+	case 1:
+		return 4, nil
+	case 2:
+		return 16, nil
+	}
+
+	return 0, errors.Wrapf(ErrUnknownAddressType, "Type %d", addressType)
+}
+
+// nextRecord returns a reader limited to the sample or record (including its type and length fields) at the top of r
+func nextRecord(r *reversedReader) (sfTypeEnterprise uint32, sfTypeFormat uint32, rec *reversedReader) {
+	peek := *r
+	sfTypeEnterprise, sfTypeFormat = extractEnterpriseFormat(peek.uint32())
+	length := peek.uint32()
+	if peek.err != nil {
+		r.err = peek.err
+		return 0, 0, nil
+	}
+
+	return sfTypeEnterprise, sfTypeFormat, r.sub(8 + uintptr(length))
+}
+
+func decodeSamples(r *reversedReader, NumSamples uint32) ([]*FlowSample, []*CounterSample, error) {
 	flowSamples := make([]*FlowSample, 0)
+	counterSamples := make([]*CounterSample, 0)
 	for i := uint32(0); i < NumSamples; i++ {
-		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(*(*uint32)(unsafe.Pointer(uintptr(samplesPtr) - uintptr(4))))
+		sfTypeEnterprise, sfTypeFormat, sr := nextRecord(r)
+		if r.err != nil {
+			return nil, nil, errors.Wrapf(r.err, "Unable to decode sample %d", i)
+		}
 
 		if sfTypeEnterprise != 0 {
-			return nil, errors.Errorf("Unknown Enterprise: %d", sfTypeEnterprise)
+			return nil, nil, errors.Errorf("Unknown Enterprise: %d", sfTypeEnterprise)
 		}
 
-		sampleLengthPtr := unsafe.Pointer(uintptr(samplesPtr) - uintptr(8))
-		sampleLength := *(*uint32)(sampleLengthPtr)
-
 		if sfTypeFormat == dataFlowSample {
-			fs, err := decodeFlowSample(samplesPtr)
+			fs, err := decodeFlowSample(sr)
 			if err != nil {
-				return nil, errors.Wrap(err, "Unable to decode flow sample")
+				return nil, nil, errors.Wrap(err, "Unable to decode flow sample")
 			}
 			flowSamples = append(flowSamples, fs)
 		} else if sfTypeFormat == expandedFlowSample {
-			fs, err := decodeExpandedFlowSample(samplesPtr)
+			fs, err := decodeExpandedFlowSample(sr)
 			if err != nil {
-				return nil, errors.Wrap(err, "Unable to decode flow sample")
+				return nil, nil, errors.Wrap(err, "Unable to decode flow sample")
 			}
 			flowSamples = append(flowSamples, fs)
+		} else if sfTypeFormat == dataCounterSample {
+			cs, err := decodeCounterSample(sr)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "Unable to decode counter sample")
+			}
+			counterSamples = append(counterSamples, cs)
+		} else if sfTypeFormat == expandedCounterSample {
+			cs, err := decodeExpandedCounterSample(sr)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "Unable to decode counter sample")
+			}
+			counterSamples = append(counterSamples, cs)
+		}
+	}
+
+	return flowSamples, counterSamples, nil
+}
+
+func decodeCounterSample(r *reversedReader) (*CounterSample, error) {
+	csh := (*counterSampleHeader)(r.next(sizeOfCounterSampleHeader))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	cs := &CounterSample{
+		SequenceNumber: csh.SequenceNumber,
+		SourceIDType:   csh.SourceID >> 24,
+		SourceIDIndex:  csh.SourceID & 0xffffff,
+	}
+
+	return cs, decodeCounterRecords(r, csh.CounterRecords, cs)
+}
+
+func decodeExpandedCounterSample(r *reversedReader) (*CounterSample, error) {
+	csh := (*expandedCounterSampleHeader)(r.next(sizeOfExpandedCounterSampleHdr))
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	cs := &CounterSample{
+		SequenceNumber: csh.SequenceNumber,
+		SourceIDType:   csh.SourceIDType,
+		SourceIDIndex:  csh.SourceIDIndex,
+	}
+
+	return cs, decodeCounterRecords(r, csh.CounterRecords, cs)
+}
+
+func decodeCounterRecords(r *reversedReader, numRecords uint32, cs *CounterSample) error {
+	for i := uint32(0); i < numRecords; i++ {
+		sfTypeEnterprise, sfTypeFormat, rec := nextRecord(r)
+		if r.err != nil {
+			return errors.Wrapf(r.err, "Unable to decode counter record %d", i)
 		}
 
-		samplesPtr = unsafe.Pointer(uintptr(samplesPtr) - uintptr(sampleLength+8))
+		if sfTypeEnterprise != standardSflow {
+			continue
+		}
+
+		switch sfTypeFormat {
+		case genericInterfaceCounters:
+			if rec.remaining() >= sizeOfGenericIfCounters {
+				c := *(*GenericInterfaceCounters)(rec.next(sizeOfGenericIfCounters))
+				cs.GenericInterfaceCounters = &c
+			}
+
+		case ethernetInterfaceCounter:
+			if rec.remaining() >= sizeOfEthernetCounters {
+				c := *(*EthernetCounters)(rec.next(sizeOfEthernetCounters))
+				cs.EthernetCounters = &c
+			}
+		}
 	}
 
-	return flowSamples, nil
+	return nil
 }
 
-func decodeFlowSample(flowSamplePtr unsafe.Pointer) (*FlowSample, error) {
-	flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(sizeOfFlowSampleHeader))
-	fsh := (*FlowSampleHeader)(flowSamplePtr)
+func decodeFlowSample(r *reversedReader) (*FlowSample, error) {
+	fsh := (*FlowSampleHeader)(r.next(sizeOfFlowSampleHeader))
+	if r.err != nil {
+		return nil, r.err
+	}
 
-	return _decodeFlowSample(flowSamplePtr, fsh)
+	return _decodeFlowSample(r, fsh)
 }
 
-func decodeExpandedFlowSample(flowSamplePtr unsafe.Pointer) (*FlowSample, error) {
-	flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(sizeOfExpandedFlowSampleHeader))
-	fsh := (*ExpandedFlowSampleHeader)(flowSamplePtr).toFlowSampleHeader()
+func decodeExpandedFlowSample(r *reversedReader) (*FlowSample, error) {
+	efsh := (*ExpandedFlowSampleHeader)(r.next(sizeOfExpandedFlowSampleHeader))
+	if r.err != nil {
+		return nil, r.err
+	}
 
-	return _decodeFlowSample(flowSamplePtr, fsh)
+	return _decodeFlowSample(r, efsh.toFlowSampleHeader())
 }
 
-func _decodeFlowSample(flowSamplePtr unsafe.Pointer, fsh *FlowSampleHeader) (*FlowSample, error) {
+func _decodeFlowSample(r *reversedReader, fsh *FlowSampleHeader) (*FlowSample, error) {
 	var rph *RawPacketHeader
 	var rphd unsafe.Pointer
 	var erd *ExtendedRouterData
 	var esd *ExtendedSwitchData
+	var egd *ExtendedGatewayData
 
 	for i := uint32(0); i < fsh.FlowRecord; i++ {
-		sfTypeEnterprise, sfTypeFormat := extractEnterpriseFormat(*(*uint32)(unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(4))))
-		flowDataLength := *(*uint32)(unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(8)))
+		sfTypeEnterprise, sfTypeFormat, rec := nextRecord(r)
+		if r.err != nil {
+			return nil, errors.Wrapf(r.err, "Unable to decode flow record %d", i)
+		}
 
 		if sfTypeEnterprise == standardSflow {
 			var err error
 			switch sfTypeFormat {
 			case rawPacketHeader:
-				rph = decodeRawPacketHeader(flowSamplePtr)
-				rphd = unsafe.Pointer(uintptr(flowSamplePtr) - sizeOfRawPacketHeader)
+				rph, rphd, err = decodeRawPacketHeader(rec)
+				if err != nil {
+					return nil, errors.Wrap(err, "Unable to decode raw packet header")
+				}
 
 			case extendedRouterData:
-				erd, err = decodeExtendRouterData(flowSamplePtr)
+				erd, err = decodeExtendRouterData(rec)
 				if err != nil {
 					return nil, errors.Wrap(err, "Unable to decide extended router data")
 				}
 
 			case extendedSwitchData:
-				esd, err = decodeExtendedSwitchData(flowSamplePtr)
+				esd, err = decodeExtendedSwitchData(rec)
 				if err != nil {
 					return nil, errors.Wrap(err, "Unable to decide extended switch data")
 				}
 
+			case extendedGatewayData:
+				egd, err = decodeExtendedGatewayData(rec)
+				if err != nil {
+					return nil, errors.Wrap(err, "Unable to decode extended gateway data")
+				}
+
 			default:
 				log.Infof("Unknown sfTypeFormat %d\n", sfTypeFormat)
 			}
 
 		}
-
-		flowSamplePtr = unsafe.Pointer(uintptr(flowSamplePtr) - uintptr(8) - uintptr(flowDataLength))
 	}
 
 	fs := &FlowSample{
-		FlowSampleHeader:   fsh,
-		RawPacketHeader:    rph,
-		Data:               rphd,
-		DataLen:            rph.OriginalPacketLength,
-		ExtendedSwitchData: esd,
-		ExtendedRouterData: erd,
+		FlowSampleHeader:    fsh,
+		RawPacketHeader:     rph,
+		Data:                rphd,
+		ExtendedSwitchData:  esd,
+		ExtendedRouterData:  erd,
+		ExtendedGatewayData: egd,
+	}
+
+	if rph != nil {
+		fs.DataLen = rph.OriginalPacketLength
 	}
 
 	return fs, nil
 }
 
-func decodeRawPacketHeader(rphPtr unsafe.Pointer) *RawPacketHeader {
-	rphPtr = unsafe.Pointer(uintptr(rphPtr) - uintptr(sizeOfRawPacketHeader))
-	rph := (*RawPacketHeader)(rphPtr)
-	return rph
+// decodeRawPacketHeader decodes a raw packet header record and returns a pointer to the end of the sampled header.
+// The header length is checked against the record so packet decoders can rely on it.
+func decodeRawPacketHeader(r *reversedReader) (*RawPacketHeader, unsafe.Pointer, error) {
+	rph := (*RawPacketHeader)(r.next(sizeOfRawPacketHeader))
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	if uintptr(rph.OriginalPacketLength) > r.remaining() {
+		return nil, nil, errors.Wrapf(ErrTruncated, "Header length %d exceeds record", rph.OriginalPacketLength)
+	}
+
+	return rph, r.top(), nil
 }
 
-func decodeExtendRouterData(erhPtr unsafe.Pointer) (*ExtendedRouterData, error) {
-	erhTopPtr := unsafe.Pointer(uintptr(erhPtr) - uintptr(sizeOfextendedRouterDataTop))
-	erhTop := (*extendedRouterDataTop)(erhTopPtr)
+func decodeExtendRouterData(r *reversedReader) (*ExtendedRouterData, error) {
+	erhTop := (*extendedRouterDataTop)(r.next(sizeOfextendedRouterDataTop))
+	if r.err != nil {
+		return nil, r.err
+	}
 
-	addressLen := uint64(0)
-	switch erhTop.AddressType {
-	default:
-		return nil, errors.Errorf("Unknown AgentAddressType %d", erhTop.AddressType)
-	case 1:
-		addressLen = 4
-	case 2:
-		addressLen = 16
+	addressLen, err := addressLength(erhTop.AddressType)
+	if err != nil {
+		return nil, err
 	}
 
-	erhBottomPtr := unsafe.Pointer(uintptr(erhTopPtr) - uintptr(sizeOfextendedRouterDataBottom) - uintptr(addressLen) - uintptr(sizeOfextendedRouterDataBottom))
-	erhBottom := (*extendedRouterDataBottom)(erhBottomPtr)
+	nextHop := r.netIP(addressLen)
+	erhBottom := (*extendedRouterDataBottom)(r.next(sizeOfextendedRouterDataBottom))
+	if r.err != nil {
+		return nil, r.err
+	}
 
 	return &ExtendedRouterData{
 		EnterpriseType:         erhTop.EnterpriseType,
 		FlowDataLength:         erhTop.FlowDataLength,
 		AddressType:            erhTop.AddressType,
-		NextHop:                getNetIP(unsafe.Pointer(uintptr(erhTopPtr)), addressLen),
+		NextHop:                nextHop,
 		NextHopSourceMask:      erhBottom.NextHopSourceMask,
 		NextHopDestinationMask: erhBottom.NextHopDestinationMask,
 	}, nil
 }
 
-func decodeExtendedSwitchData(eshPtr unsafe.Pointer) (*ExtendedSwitchData, error) {
-	eshPtr = unsafe.Pointer(uintptr(eshPtr) - uintptr(sizeOfExtendedSwitchData))
-	esh := (*ExtendedSwitchData)(eshPtr)
+func decodeExtendedSwitchData(r *reversedReader) (*ExtendedSwitchData, error) {
+	esh := (*ExtendedSwitchData)(r.next(sizeOfExtendedSwitchData))
+	if r.err != nil {
+		return nil, r.err
+	}
 
 	eshCopy := *esh
 	return &eshCopy, nil
 }
 
-func getNetIP(headerPtr unsafe.Pointer, addressLen uint64) net.IP {
-	ptr := unsafe.Pointer(uintptr(headerPtr) - uintptr(1))
+// decodeExtendedGatewayData decodes an extended gateway record. r starts at the records type field.
+func decodeExtendedGatewayData(r *reversedReader) (*ExtendedGatewayData, error) {
+	r.advance(8)
+
+	egd := &ExtendedGatewayData{}
+	addressType := r.uint32()
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	addressLen, err := addressLength(addressType)
+	if err != nil {
+		return nil, err
+	}
+
+	egd.NextHop = r.netIP(addressLen)
+	egd.AS = r.uint32()
+	egd.SrcAS = r.uint32()
+	egd.SrcPeerAS = r.uint32()
+
+	numSegments := r.uint32()
+	for i := uint32(0); i < numSegments && r.err == nil; i++ {
+		seg := ASPathSegment{
+			Type: r.uint32(),
+		}
+
+		seg.ASNs = r.uint32s(r.uint32())
+		egd.ASPath = append(egd.ASPath, seg)
+	}
+
+	egd.Communities = r.uint32s(r.uint32())
+	egd.LocalPref = r.uint32()
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return egd, nil
+}
+
+// reversedReader reads consecutive fields from the top of a reversed buffer downwards without crossing min
+type reversedReader struct {
+	buf []byte
+	off int
+	min int
+	err error
+}
+
+func newReversedReader(buf []byte) *reversedReader {
+	return &reversedReader{
+		buf: buf,
+		off: len(buf),
+	}
+}
+
+// remaining gets the number of bytes left below the current position
+func (r *reversedReader) remaining() uintptr {
+	return uintptr(r.off - r.min)
+}
+
+// top gets a pointer to the end of the remaining data
+func (r *reversedReader) top() unsafe.Pointer {
+	return unsafe.Pointer(uintptr(unsafe.Pointer(&r.buf[0])) + uintptr(r.off))
+}
+
+func (r *reversedReader) advance(n uintptr) bool {
+	if r.err != nil {
+		return false
+	}
+
+	if r.remaining() < n {
+		r.err = errors.Wrapf(ErrTruncated, "Need %d bytes, %d remaining", n, r.remaining())
+		return false
+	}
+
+	r.off -= int(n)
+	return true
+}
+
+// next skips a field of n bytes and returns a pointer to its beginning in the reversed buffer
+func (r *reversedReader) next(n uintptr) unsafe.Pointer {
+	if !r.advance(n) {
+		return nil
+	}
+
+	return unsafe.Pointer(&r.buf[r.off])
+}
+
+// sub returns a reader limited to the next n bytes and skips them in r
+func (r *reversedReader) sub(n uintptr) *reversedReader {
+	s := &reversedReader{
+		buf: r.buf,
+		off: r.off,
+	}
+
+	if !r.advance(n) {
+		s.err = r.err
+		return s
+	}
+
+	s.min = r.off
+	return s
+}
+
+func (r *reversedReader) uint32() uint32 {
+	if !r.advance(4) {
+		return 0
+	}
+
+	return convert.Uint32(r.buf[r.off : r.off+4])
+}
+
+func (r *reversedReader) uint32s(n uint32) []uint32 {
+	if r.err != nil {
+		return nil
+	}
+
+	if r.remaining()/4 < uintptr(n) {
+		r.err = errors.Wrapf(ErrTruncated, "Need %d 32 bit values, %d bytes remaining", n, r.remaining())
+		return nil
+	}
+
+	res := make([]uint32, n)
+	for i := range res {
+		res[i] = r.uint32()
+	}
+
+	return res
+}
+
+func (r *reversedReader) netIP(addressLen uint64) net.IP {
+	top := r.off
+	if !r.advance(uintptr(addressLen)) {
+		return nil
+	}
+
 	addr := make([]byte, addressLen)
-	for i := uint64(0); i < addressLen; i++ {
-		addr[i] = *(*byte)(unsafe.Pointer(uintptr(ptr) - uintptr(i)))
+	for i := range addr {
+		addr[i] = r.buf[top-1-i]
 	}
 
 	return net.IP(addr)
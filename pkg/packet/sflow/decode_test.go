@@ -11,13 +11,16 @@
 package sflow
 
 import (
+	"encoding/binary"
 	"fmt"
 	"testing"
 
 	"github.com/bio-routing/tflow2/convert"
+	"github.com/stretchr/testify/assert"
 )
 
-func TestDecode(t *testing.T) {
+// testDatagram returns a datagram with five flow samples in wire order
+func testDatagram() []byte {
 	s := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 22, 0, 0, 0, 32, 0, 0, 0, 62, 190, 59, 194, 1, 0, 0, 0, 16, 0, 0, 0, 234, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 233, 3, 0, 0, 237, 199, 45, 191, 139, 110, 125, 230, 182, 29, 57, 172, 218, 131, 46, 119, 222, 169, 239, 221, 168, 115, 245, 18, 162, 61, 247, 165, 225, 137, 141, 210, 165, 115, 237, 171, 115, 10, 153, 41, 121, 49, 57, 188, 199, 201, 25, 85, 91, 144, 240, 211, 169, 192, 41, 161, 202, 222, 113, 99, 33, 78, 210, 92, 70, 28, 134, 39, 126, 255, 10, 8, 1, 1, 0, 0, 118, 202, 230, 1, 16, 128, 78, 151, 101, 60, 114, 24, 235, 218, 161, 4, 80, 0, 127, 251, 90, 95, 2, 153, 37, 185, 194, 50, 6, 63, 0, 64, 128, 86, 180, 5, 0, 69, 0, 8, 236, 43, 4, 113, 78, 32, 82, 114, 59, 217, 103, 216, 128, 0, 0, 0, 4, 0, 0, 0, 198, 5, 0, 0, 1, 0, 0, 0, 144, 0, 0, 0, 1, 0, 0, 0, 3, 0, 0, 0, 190, 2, 0, 0, 168, 2, 0, 0, 0, 0, 0, 0, 64, 127, 94, 90, 224, 3, 0, 0, 144, 2, 0, 0, 197, 164, 97, 81, 232, 0, 0, 0, 1, 0, 0, 0, 22, 0, 0, 0,
 		32, 0, 0, 0,
 		62, 190, 59, 194, // Next-Hop
@@ -80,7 +83,12 @@ func TestDecode(t *testing.T) {
 		1, 0, 0, 0, // Agent Address Type
 		5, 0, 0, 0, // Version
 	}
-	s = convert.Reverse(s)
+
+	return convert.Reverse(s)
+}
+
+func TestDecode(t *testing.T) {
+	s := testDatagram()
 
 	packet, err := Decode(s)
 	if err != nil {
@@ -129,3 +137,85 @@ func testEq(a, b []byte) bool {
 
 	return true
 }
+
+func TestDecodeTruncated(t *testing.T) {
+	s := testDatagram()
+
+	for _, n := range []int{0, 4, 12, 27, 100, 500} {
+		_, err := Decode(append([]byte(nil), s[:n]...))
+		assert.ErrorIs(t, err, ErrTruncated, "length %d", n)
+	}
+}
+
+func TestAgentAddress(t *testing.T) {
+	s := testDatagram()
+
+	addr, err := AgentAddress(s)
+	if err != nil {
+		t.Fatalf("Unable to read agent address: %v", err)
+	}
+
+	assert.Equal(t, "10.205.19.14", addr.String())
+	assert.Equal(t, testDatagram(), s)
+
+	for _, n := range []int{0, 7, 11} {
+		_, err := AgentAddress(s[:n])
+		assert.ErrorIs(t, err, ErrTruncated, "length %d", n)
+	}
+}
+
+func TestDecodeRawPacketHeaderExceedsRecord(t *testing.T) {
+	s := rawPacketHeaderDatagram(make([]byte, 4))
+	binary.BigEndian.PutUint32(s[88:], 32) // Header length
+
+	_, err := Decode(s)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func TestDecodeJumboDatagram(t *testing.T) {
+	s := rawPacketHeaderDatagram(make([]byte, 8000))
+
+	p, err := Decode(s)
+	if !assert.NoError(t, err) || !assert.Len(t, p.FlowSamples, 1) {
+		return
+	}
+
+	assert.Equal(t, uint32(8000), p.FlowSamples[0].DataLen)
+}
+
+// rawPacketHeaderDatagram returns a datagram with one flow sample holding a raw packet header record with header
+func rawPacketHeaderDatagram(header []byte) []byte {
+	s := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent Address Type
+		10, 205, 19, 14, // Agent Address
+		0, 0, 0, 0, // Sub-AgentID
+		0, 0, 0, 222, // Sequence Number
+		0, 0, 0, 111, // SysUpTime
+		0, 0, 0, 1, // NumSamples
+
+		0, 0, 0, 1, // Enterprise/Type (Flow sample)
+		0, 0, 0, 0, // Sample length
+		0, 0, 0, 7, // Sequence Number
+		0, 0, 0, 3, // Source ID + Index
+		0, 0, 3, 232, // Sampling Rate
+		0, 0, 16, 0, // Sampling Pool
+		0, 0, 0, 0, // Dropped Packets
+		0, 0, 0, 3, // Input interface
+		0, 0, 0, 4, // Output interface
+		0, 0, 0, 1, // Flow Record count
+
+		0, 0, 0, 1, // Enterprise/Type (Raw packet header)
+		0, 0, 0, 0, // Flow Data Length
+		0, 0, 0, 1, // Header Protocol
+		0, 0, 5, 220, // Frame length
+		0, 0, 0, 4, // Payload removed
+		0, 0, 0, 0, // Header length
+	}
+
+	binary.BigEndian.PutUint32(s[32:], uint32(56+len(header)))
+	binary.BigEndian.PutUint32(s[72:], uint32(16+len(header)))
+	binary.BigEndian.PutUint32(s[88:], uint32(len(header)))
+
+	return append(s, header...)
+}
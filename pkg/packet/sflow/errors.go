@@ -0,0 +1,11 @@
+package sflow
+
+import "github.com/pkg/errors"
+
+var (
+	// ErrTruncated is returned if a header or length field exceeds the received datagram
+	ErrTruncated = errors.New("Truncated datagram")
+
+	// ErrUnknownAddressType is returned for address types other than IPv4 (1) and IPv6 (2)
+	ErrUnknownAddressType = errors.New("Unknown address type")
+)
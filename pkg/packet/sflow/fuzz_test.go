@@ -0,0 +1,39 @@
+package sflow
+
+import (
+	"io"
+	"testing"
+	"unsafe"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func FuzzDecode(f *testing.F) {
+	log.SetOutput(io.Discard)
+
+	f.Add(testDatagram())
+	f.Add(counterSampleDatagram())
+	f.Add(gatewayDatagram())
+	f.Add(truncatedGatewayDatagram())
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := Decode(data)
+		if err != nil {
+			return
+		}
+
+		for _, fs := range p.FlowSamples {
+			if fs.RawPacketHeader == nil {
+				continue
+			}
+
+			// the sampled header must be within the datagram
+			end := uintptr(fs.Data)
+			start := end - uintptr(fs.DataLen)
+			min := uintptr(unsafe.Pointer(&p.Buffer[0]))
+			if start < min || end > min+uintptr(len(p.Buffer)) {
+				t.Fatalf("Sampled header [%d, %d) exceeds datagram [%d, %d)", start, end, min, min+uintptr(len(p.Buffer)))
+			}
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package sflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gatewayDatagram returns a datagram with a flow sample carrying extended gateway data
+func gatewayDatagram() []byte {
+	s := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent Address Type
+		10, 205, 19, 14, // Agent Address
+		0, 0, 0, 0, // Sub-AgentID
+		0, 0, 0, 222, // Sequence Number
+		0, 0, 0, 111, // SysUpTime
+		0, 0, 0, 1, // NumSamples
+
+		0, 0, 0, 1, // Enterprise/Type (Flow sample)
+		0, 0, 0, 100, // Sample length
+		0, 0, 0, 7, // Sequence Number
+		0, 0, 0, 3, // Source ID + Index
+		0, 0, 3, 232, // Sampling Rate
+		0, 0, 16, 0, // Sampling Pool
+		0, 0, 0, 0, // Dropped Packets
+		0, 0, 0, 3, // Input interface
+		0, 0, 0, 4, // Output interface
+		0, 0, 0, 1, // Flow Record count
+
+		0, 0, 3, 235, // Enterprise/Type (Extended gateway data)
+		0, 0, 0, 60, // Flow Data Length
+		0, 0, 0, 1, // Address Type
+		192, 0, 2, 1, // Next-Hop
+		0, 0, 0xfd, 0xe8, // AS 65000
+		0, 0, 0xfd, 0xe9, // Source AS 65001
+		0, 0, 0xfd, 0xea, // Source Peer AS 65002
+		0, 0, 0, 1, // AS path segment count
+		0, 0, 0, 2, // Segment type (sequence)
+		0, 0, 0, 3, // Segment length
+		0, 0, 0x0c, 0xb9, // AS 3257
+		0, 0, 0x0b, 0x62, // AS 2914
+		0, 0, 0x3b, 0x41, // AS 15169
+		0, 0, 0, 2, // Community count
+		0xfd, 0xe8, 0, 100, // 65000:100
+		0xfd, 0xe8, 0, 200, // 65000:200
+		0, 0, 0, 100, // Local Pref
+	}
+
+	return s
+}
+
+func TestDecodeExtendedGatewayData(t *testing.T) {
+	s := gatewayDatagram()
+
+	p, err := Decode(s)
+	if err != nil {
+		t.Fatalf("Decoding packet failed: %v", err)
+	}
+
+	if !assert.Len(t, p.FlowSamples, 1) {
+		return
+	}
+
+	fs := p.FlowSamples[0]
+	assert.Nil(t, fs.RawPacketHeader)
+	assert.Equal(t, uint32(1000), fs.FlowSampleHeader.SamplingRate)
+
+	egd := fs.ExtendedGatewayData
+	if !assert.NotNil(t, egd) {
+		return
+	}
+
+	assert.Equal(t, "192.0.2.1", egd.NextHop.String())
+	assert.Equal(t, uint32(65000), egd.AS)
+	assert.Equal(t, uint32(65001), egd.SrcAS)
+	assert.Equal(t, uint32(65002), egd.SrcPeerAS)
+	assert.Equal(t, []ASPathSegment{
+		{
+			Type: ASPathSegmentTypeSequence,
+			ASNs: []uint32{3257, 2914, 15169},
+		},
+	}, egd.ASPath)
+	assert.Equal(t, []uint32{3257, 2914, 15169}, egd.FlatASPath())
+	assert.Equal(t, []uint32{65000<<16 | 100, 65000<<16 | 200}, egd.Communities)
+	assert.Equal(t, uint32(100), egd.LocalPref)
+}
+
+// truncatedGatewayDatagram returns a datagram whose gateway record ends within the AS path
+func truncatedGatewayDatagram() []byte {
+	s := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent Address Type
+		10, 205, 19, 14, // Agent Address
+		0, 0, 0, 0, // Sub-AgentID
+		0, 0, 0, 222, // Sequence Number
+		0, 0, 0, 111, // SysUpTime
+		0, 0, 0, 1, // NumSamples
+
+		0, 0, 0, 1, // Enterprise/Type (Flow sample)
+		0, 0, 0, 68, // Sample length
+		0, 0, 0, 7, // Sequence Number
+		0, 0, 0, 3, // Source ID + Index
+		0, 0, 3, 232, // Sampling Rate
+		0, 0, 16, 0, // Sampling Pool
+		0, 0, 0, 0, // Dropped Packets
+		0, 0, 0, 3, // Input interface
+		0, 0, 0, 4, // Output interface
+		0, 0, 0, 1, // Flow Record count
+
+		0, 0, 3, 235, // Enterprise/Type (Extended gateway data)
+		0, 0, 0, 28, // Flow Data Length
+		0, 0, 0, 1, // Address Type
+		192, 0, 2, 1, // Next-Hop
+		0, 0, 0xfd, 0xe8, // AS 65000
+		0, 0, 0xfd, 0xe9, // Source AS 65001
+		0, 0, 0xfd, 0xea, // Source Peer AS 65002
+		0, 0, 0, 1, // AS path segment count
+		0, 0, 0, 2, // Segment type (sequence)
+	}
+
+	return s
+}
+
+func TestDecodeExtendedGatewayDataTruncated(t *testing.T) {
+	s := truncatedGatewayDatagram()
+
+	_, err := Decode(s)
+	assert.ErrorIs(t, err, ErrTruncated)
+}
@@ -26,6 +26,9 @@ type Packet struct {
 	// A slice of pointers to FlowSet. Each element is instance of (Data)FlowSet
 	FlowSamples []*FlowSample
 
+	// A slice of pointers to counter samples found in this packet
+	CounterSamples []*CounterSample
+
 	// Buffer is a slice pointing to the original byte array that this packet was decoded from.
 	// This field is only populated if debug level is at least 2
 	Buffer []byte
@@ -41,6 +44,10 @@ var (
 	sizeOfextendedRouterDataTop    = unsafe.Sizeof(extendedRouterDataTop{})
 	sizeOfextendedRouterDataBottom = unsafe.Sizeof(extendedRouterDataBottom{})
 	sizeOfExtendedSwitchData       = unsafe.Sizeof(ExtendedSwitchData{})
+	sizeOfCounterSampleHeader      = unsafe.Sizeof(counterSampleHeader{})
+	sizeOfExpandedCounterSampleHdr = unsafe.Sizeof(expandedCounterSampleHeader{})
+	sizeOfGenericIfCounters        = unsafe.Sizeof(GenericInterfaceCounters{})
+	sizeOfEthernetCounters         = unsafe.Sizeof(EthernetCounters{})
 )
 
 // Header is an sflow version 5 header
@@ -75,6 +82,7 @@ type FlowSample struct {
 	DataLen                  uint32
 	ExtendedSwitchData       *ExtendedSwitchData
 	ExtendedRouterData       *ExtendedRouterData
+	ExtendedGatewayData      *ExtendedGatewayData
 }
 
 // FlowSampleHeader is an sflow version 5 flow sample header
@@ -123,6 +131,14 @@ func (e *ExpandedFlowSampleHeader) toFlowSampleHeader() *FlowSampleHeader {
 	}
 }
 
+// Header protocols of raw packet headers
+const (
+	HeaderProtocolEthernet = 1
+	HeaderProtocolIPv4     = 11
+	HeaderProtocolIPv6     = 12
+	HeaderProtocolMPLS     = 13
+)
+
 // RawPacketHeader is a raw packet header
 type RawPacketHeader struct {
 	OriginalPacketLength uint32
@@ -154,6 +170,39 @@ type ExtendedRouterData struct {
 	EnterpriseType         uint32
 }
 
+// AS path segment types
+const (
+	ASPathSegmentTypeSet      = 1
+	ASPathSegmentTypeSequence = 2
+)
+
+// ASPathSegment is a segment of an AS path
+type ASPathSegment struct {
+	Type uint32
+	ASNs []uint32
+}
+
+// ExtendedGatewayData represents sflow version 5 extended gateway data
+type ExtendedGatewayData struct {
+	NextHop     net.IP
+	AS          uint32
+	SrcAS       uint32
+	SrcPeerAS   uint32
+	ASPath      []ASPathSegment
+	Communities []uint32
+	LocalPref   uint32
+}
+
+// FlatASPath gets the ASNs of all segments of the AS path
+func (egd *ExtendedGatewayData) FlatASPath() []uint32 {
+	res := make([]uint32, 0)
+	for _, seg := range egd.ASPath {
+		res = append(res, seg.ASNs...)
+	}
+
+	return res
+}
+
 // ExtendedSwitchData represents sflow version 5 extended switch data
 type ExtendedSwitchData struct {
 	OutgoingPriority uint32
@@ -163,3 +212,73 @@ type ExtendedSwitchData struct {
 	FlowDataLength   uint32
 	EnterpriseType   uint32
 }
+
+// CounterSample is an sflow version 5 (expanded) counter sample
+type CounterSample struct {
+	SequenceNumber           uint32
+	SourceIDType             uint32
+	SourceIDIndex            uint32
+	GenericInterfaceCounters *GenericInterfaceCounters
+	EthernetCounters         *EthernetCounters
+}
+
+type counterSampleHeader struct {
+	CounterRecords uint32
+	SourceID       uint32
+	SequenceNumber uint32
+	SampleLength   uint32
+	EnterpriseType uint32
+}
+
+type expandedCounterSampleHeader struct {
+	CounterRecords uint32
+	SourceIDIndex  uint32
+	SourceIDType   uint32
+	SequenceNumber uint32
+	SampleLength   uint32
+	EnterpriseType uint32
+}
+
+// GenericInterfaceCounters represents sflow version 5 generic interface counters (RFC2233)
+type GenericInterfaceCounters struct {
+	IfPromiscuousMode  uint32
+	IfOutErrors        uint32
+	IfOutDiscards      uint32
+	IfOutBroadcastPkts uint32
+	IfOutMulticastPkts uint32
+	IfOutUcastPkts     uint32
+	IfOutOctets        uint64
+	IfInUnknownProtos  uint32
+	IfInErrors         uint32
+	IfInDiscards       uint32
+	IfInBroadcastPkts  uint32
+	IfInMulticastPkts  uint32
+	IfInUcastPkts      uint32
+	IfInOctets         uint64
+	IfStatus           uint32
+	IfDirection        uint32
+	IfSpeed            uint64
+	IfType             uint32
+	IfIndex            uint32
+	CounterDataLength  uint32
+	EnterpriseType     uint32
+}
+
+// EthernetCounters represents sflow version 5 ethernet interface counters (RFC2358)
+type EthernetCounters struct {
+	Dot3StatsSymbolErrors              uint32
+	Dot3StatsInternalMacReceiveErrors  uint32
+	Dot3StatsFrameTooLongs             uint32
+	Dot3StatsCarrierSenseErrors        uint32
+	Dot3StatsInternalMacTransmitErrors uint32
+	Dot3StatsExcessiveCollisions       uint32
+	Dot3StatsLateCollisions            uint32
+	Dot3StatsDeferredTransmissions     uint32
+	Dot3StatsSQETestErrors             uint32
+	Dot3StatsMultipleCollisionFrames   uint32
+	Dot3StatsSingleCollisionFrames     uint32
+	Dot3StatsFCSErrors                 uint32
+	Dot3StatsAlignmentErrors           uint32
+	CounterDataLength                  uint32
+	EnterpriseType                     uint32
+}
@@ -0,0 +1,194 @@
+// Package agentfilter decides which exporters the UDP collectors accept datagrams from
+package agentfilter
+
+import (
+	"sync"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+// Reasons for rejecting datagrams
+const (
+	ReasonUnknownAgent = "unknown_agent"
+	ReasonRateLimited  = "rate_limited"
+)
+
+const (
+	// maxBuckets is the number of rate limiting buckets kept before idle buckets are removed
+	maxBuckets = 65536
+
+	// maxReported is the number of rejected agents remembered in order to log each of them only once
+	maxReported = 4096
+)
+
+// Config is the configuration of a `Filter`
+type Config struct {
+	// KnownAgentsOnly rejects datagrams of agents that have not been added to the filter
+	KnownAgentsOnly bool
+
+	// RateLimit is the number of datagrams per second accepted per agent. 0 disables rate limiting.
+	RateLimit float64
+
+	// Burst is the number of datagrams an agent may send at once. Defaults to RateLimit.
+	Burst float64
+}
+
+type rejection struct {
+	agent  bnet.IP
+	reason string
+}
+
+// bucket is a token bucket limiting the datagram rate of an agent
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Filter keeps the set of known agents and the rate limiting state per agent
+type Filter struct {
+	cfg        Config
+	agents     map[bnet.IP]struct{}
+	agentsMu   sync.RWMutex
+	buckets    map[bnet.IP]*bucket
+	bucketsMu  sync.Mutex
+	reported   map[rejection]struct{}
+	reportedMu sync.Mutex
+}
+
+// New creates a new filter
+func New(cfg Config) *Filter {
+	if cfg.Burst < 1 {
+		cfg.Burst = cfg.RateLimit
+	}
+
+	if cfg.Burst < 1 {
+		cfg.Burst = 1
+	}
+
+	return &Filter{
+		cfg:      cfg,
+		agents:   make(map[bnet.IP]struct{}),
+		buckets:  make(map[bnet.IP]*bucket),
+		reported: make(map[rejection]struct{}),
+	}
+}
+
+// Add adds a known agent
+func (f *Filter) Add(agent bnet.IP) {
+	f.agentsMu.Lock()
+	f.agents[agent] = struct{}{}
+	f.agentsMu.Unlock()
+
+	f.reportedMu.Lock()
+	delete(f.reported, rejection{agent: agent, reason: ReasonUnknownAgent})
+	f.reportedMu.Unlock()
+}
+
+// Known checks if datagrams of agent are accepted by the allow list
+func (f *Filter) Known(agent bnet.IP) bool {
+	if !f.cfg.KnownAgentsOnly {
+		return true
+	}
+
+	f.agentsMu.RLock()
+	defer f.agentsMu.RUnlock()
+
+	_, found := f.agents[agent]
+	return found
+}
+
+// AgentLabel returns the value of the agent label of rejection metrics. Source addresses of
+// rejected datagrams can be spoofed, so only agents added to the filter get a label value of their own.
+func (f *Filter) AgentLabel(agent bnet.IP) string {
+	f.agentsMu.RLock()
+	defer f.agentsMu.RUnlock()
+
+	if _, found := f.agents[agent]; !found {
+		return ""
+	}
+
+	return agent.String()
+}
+
+// Allow takes a token from the bucket of agent. Returns false if the agent exceeded its rate limit.
+func (f *Filter) Allow(agent bnet.IP, now time.Time) bool {
+	if f.cfg.RateLimit == 0 {
+		return true
+	}
+
+	f.bucketsMu.Lock()
+	defer f.bucketsMu.Unlock()
+
+	b, found := f.buckets[agent]
+	if !found {
+		if len(f.buckets) >= maxBuckets {
+			f.removeIdleBuckets(now)
+		}
+
+		b = &bucket{
+			tokens: f.cfg.Burst,
+			last:   now,
+		}
+		f.buckets[agent] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * f.cfg.RateLimit
+	if b.tokens > f.cfg.Burst {
+		b.tokens = f.cfg.Burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// removeIdleBuckets removes the buckets of agents that would have been refilled completely by now
+func (f *Filter) removeIdleBuckets(now time.Time) {
+	for agent, b := range f.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*f.cfg.RateLimit >= f.cfg.Burst {
+			delete(f.buckets, agent)
+		}
+	}
+}
+
+// Check checks if a datagram of agent is accepted. Returns the reason for rejecting it otherwise.
+func (f *Filter) Check(agent bnet.IP, now time.Time) (bool, string) {
+	if !f.Known(agent) {
+		f.Reject(agent, ReasonUnknownAgent)
+		return false, ReasonUnknownAgent
+	}
+
+	if !f.Allow(agent, now) {
+		f.Reject(agent, ReasonRateLimited)
+		return false, ReasonRateLimited
+	}
+
+	return true, ""
+}
+
+// Reject logs the first rejection of datagrams of agent for reason
+func (f *Filter) Reject(agent bnet.IP, reason string) {
+	r := rejection{
+		agent:  agent,
+		reason: reason,
+	}
+
+	f.reportedMu.Lock()
+	defer f.reportedMu.Unlock()
+
+	if _, found := f.reported[r]; found || len(f.reported) >= maxReported {
+		return
+	}
+	f.reported[r] = struct{}{}
+
+	log.WithFields(log.Fields{
+		"agent":  agent.String(),
+		"reason": reason,
+	}).Warning("Rejecting datagrams of exporter")
+}
@@ -0,0 +1,109 @@
+package agentfilter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestCheck(t *testing.T) {
+	known := bnet.IPv4FromOctets(192, 0, 2, 1)
+	unknown := bnet.IPv4FromOctets(192, 0, 2, 2)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name     string
+		cfg      Config
+		agent    bnet.IP
+		offset   time.Duration
+		expected string
+	}{
+		{
+			name:     "unknown agent accepted by default",
+			agent:    unknown,
+			expected: "",
+		},
+		{
+			name: "unknown agent",
+			cfg: Config{
+				KnownAgentsOnly: true,
+			},
+			agent:    unknown,
+			expected: ReasonUnknownAgent,
+		},
+		{
+			name: "known agent",
+			cfg: Config{
+				KnownAgentsOnly: true,
+			},
+			agent:    known,
+			expected: "",
+		},
+		{
+			name: "rate limited",
+			cfg: Config{
+				RateLimit: 10,
+				Burst:     2,
+			},
+			agent:    known,
+			expected: ReasonRateLimited,
+		},
+		{
+			name: "rate limit bucket refilled",
+			cfg: Config{
+				RateLimit: 10,
+				Burst:     2,
+			},
+			agent:    known,
+			offset:   100 * time.Millisecond,
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		f := New(test.cfg)
+		f.Add(known)
+
+		// Drain the bucket if rate limiting is enabled
+		for i := 0; i < int(f.cfg.Burst) && test.cfg.RateLimit > 0; i++ {
+			ok, _ := f.Check(test.agent, now)
+			assert.True(t, ok, test.name)
+		}
+
+		ok, reason := f.Check(test.agent, now.Add(test.offset))
+		assert.Equal(t, test.expected == "", ok, test.name)
+		assert.Equal(t, test.expected, reason, test.name)
+	}
+}
+
+func TestRemoveIdleBuckets(t *testing.T) {
+	f := New(Config{
+		RateLimit: 10,
+	})
+
+	now := time.Unix(1700000000, 0)
+	busy := bnet.IPv4FromOctets(192, 0, 2, 1)
+	idle := bnet.IPv4FromOctets(192, 0, 2, 2)
+	for i := 0; i < 5; i++ {
+		f.Allow(busy, now)
+	}
+	f.Allow(idle, now.Add(-time.Hour))
+
+	f.removeIdleBuckets(now)
+
+	assert.Contains(t, f.buckets, busy)
+	assert.NotContains(t, f.buckets, idle)
+}
+
+func TestAgentLabel(t *testing.T) {
+	f := New(Config{
+		KnownAgentsOnly: true,
+	})
+	f.Add(bnet.IPv4FromOctets(192, 0, 2, 1))
+
+	assert.Equal(t, "192.0.2.1", f.AgentLabel(bnet.IPv4FromOctets(192, 0, 2, 1)))
+	assert.Equal(t, "", f.AgentLabel(bnet.IPv4FromOctets(192, 0, 2, 2)))
+}
@@ -1,118 +1,208 @@
 package aggregator
 
 import (
+	"runtime"
+	"sync"
 	"time"
 
-	bnet "github.com/bio-routing/bio-rd/net"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 const (
-	AggregationWindowSeconds = 10
+	// DefaultWindow is the default length of aggregation windows
+	DefaultWindow = 10 * time.Second
+
+	// maxSplitWindows limits the number of windows a single flow is spread across
+	maxSplitWindows = 360
+
+	// flushInterval is the interval closed windows are flushed at in the absence of traffic
+	flushInterval = time.Second
+
+	// shardQueueLength is the number of flows buffered per shard
+	shardQueueLength = 1024
+)
+
+var (
+	lateFlows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "aggregator",
+		Name:      "late_flows",
+		Help:      "Flows received after their aggregation window was closed",
+	}, []string{"agent"})
+	futureFlows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "aggregator",
+		Name:      "future_flows",
+		Help:      "Flows with timestamps ahead of the collector clock",
+	}, []string{"agent"})
 )
 
-type Key struct {
-	Agent    bnet.IP
-	Src      bnet.IP
-	Dst      bnet.IP
-	Sport    uint16
-	Dport    uint16
-	Protocol uint8
+// Config is the configuration of an `Aggregator`
+type Config struct {
+	// Window is the length of aggregation windows. Must be a multiple of a second. Defaults to DefaultWindow.
+	Window time.Duration
+
+	// KeyFields are the names of the optional fields flows are aggregated by. nil selects DefaultKeyFields.
+	KeyFields []string
+
+	// Lateness is the time a window is kept open after it ended in order to
+	// aggregate flows exported late. Defaults to two windows.
+	Lateness time.Duration
+
+	// DropLateFlows drops flows arriving after their window was closed.
+	// Otherwise they are emitted unaggregated with the next flush.
+	DropLateFlows bool
+
+	// Shards is the number of goroutines aggregating flows. Defaults to the number of CPUs.
+	Shards int
 }
 
+// Aggregator sums up flows sharing the same key within a time window. Flows are distributed
+// across shards by a hash of their key. Each shard is served by its own goroutine.
 type Aggregator struct {
-	data                   map[Key]*flow.Flow
-	stopCh                 chan struct{}
-	ingress                chan *flow.Flow
-	output                 chan []*flow.Flow
-	currentUnixTimeSeconds int64
+	cfg       Config
+	window    int64
+	keyFields KeyFields
+	shards    []*shard
+	stopCh    chan struct{}
+	wg        sync.WaitGroup
+	output    chan []*flow.Flow
 }
 
-func New(output chan []*flow.Flow) *Aggregator {
-	a := &Aggregator{
-		data:    make(map[Key]*flow.Flow),
-		stopCh:  make(chan struct{}),
-		ingress: make(chan *flow.Flow),
-		output:  output,
+func New(output chan []*flow.Flow, cfg Config) (*Aggregator, error) {
+	if cfg.Window == 0 {
+		cfg.Window = DefaultWindow
 	}
 
-	go a.service()
-	return a
-}
+	if cfg.Window < time.Second || cfg.Window%time.Second != 0 {
+		return nil, errors.Errorf("Invalid aggregation window %s: Must be a multiple of a second", cfg.Window)
+	}
 
-func (a *Aggregator) Stop() {
-	close(a.stopCh)
-}
+	if cfg.Lateness <= 0 {
+		cfg.Lateness = 2 * cfg.Window
+	}
 
-func FlowToKey(fl *flow.Flow) Key {
-	return Key{
-		Agent:    fl.Agent,
-		Src:      fl.SrcAddr,
-		Dst:      fl.DstAddr,
-		Sport:    fl.SrcPort,
-		Dport:    fl.DstPort,
-		Protocol: fl.Protocol,
+	if cfg.KeyFields == nil {
+		cfg.KeyFields = DefaultKeyFields
 	}
-}
 
-func (a *Aggregator) IsStopped() bool {
-	select {
-	case <-a.stopCh:
-		return true
-	default:
-		return false
+	if cfg.Shards <= 0 {
+		cfg.Shards = runtime.NumCPU()
 	}
-}
 
-func (a *Aggregator) service() {
-	for {
-		if a.IsStopped() {
-			return
-		}
+	keyFields, err := ParseKeyFields(cfg.KeyFields)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to parse key fields")
+	}
 
-		fl := <-a.ingress
-		a.Ingest(fl)
+	a := &Aggregator{
+		cfg:       cfg,
+		window:    int64(cfg.Window / time.Second),
+		keyFields: keyFields,
+		stopCh:    make(chan struct{}),
+		output:    output,
+	}
 
+	for i := 0; i < cfg.Shards; i++ {
+		a.shards = append(a.shards, newShard(a))
 	}
+
+	for _, s := range a.shards {
+		a.wg.Add(1)
+		go func(s *shard) {
+			defer a.wg.Done()
+			s.service(a.stopCh)
+		}(s)
+	}
+
+	return a, nil
 }
 
+// Stop flushes all windows, including open ones, and stops the shards. Flows must not be
+// ingested once Stop was called.
+func (a *Aggregator) Stop() {
+	close(a.stopCh)
+	a.wg.Wait()
+}
+
+// Ingest passes fl to the shard responsible for its key. Flows are added to the window
+// their timestamp belongs to. Flows without timestamp are assigned to the current window.
 func (a *Aggregator) Ingest(fl *flow.Flow) {
-	currentUnixTimeSeconds := time.Now().Unix()
-	currentUnixTimeSeconds -= currentUnixTimeSeconds % AggregationWindowSeconds
+	a.shards[shardIndex(fl, len(a.shards))].ingress <- fl
+}
 
-	if a.currentUnixTimeSeconds < currentUnixTimeSeconds {
-		a.flush()
-		a.currentUnixTimeSeconds = currentUnixTimeSeconds
+// SplitFlow spreads the packets and bytes of fl proportionally across all aggregation
+// windows covered by the time span [startMs, endMs] (unix time in milliseconds).
+// The returned flows have their timestamps set to the windows they belong to.
+// The share of windows that are already closed is assigned to the oldest open window.
+func (a *Aggregator) SplitFlow(fl *flow.Flow, startMs int64, endMs int64) []*flow.Flow {
+	return a.splitFlow(fl, startMs, endMs, time.Now())
+}
+
+func (a *Aggregator) splitFlow(fl *flow.Flow, startMs int64, endMs int64, now time.Time) []*flow.Flow {
+	windowMs := a.window * 1000
+	firstWindow := startMs - startMs%windowMs
+	lastWindow := endMs - endMs%windowMs
+	duration := endMs - startMs
+
+	// Parts of a flow must not end up in closed windows and be treated as late flows each.
+	// A flow that ended in a closed window is late as a whole.
+	openMs := a.oldestOpenWindow(now) * 1000
+	if firstWindow < openMs {
+		firstWindow = min(openMs, lastWindow)
 	}
 
-	fl.Timestamp = currentUnixTimeSeconds
-	a.add(fl)
-}
+	if duration <= 0 || firstWindow == lastWindow || (lastWindow-firstWindow)/windowMs >= maxSplitWindows {
+		fl.Timestamp = endMs / 1000
+		return []*flow.Flow{fl}
+	}
 
-func (a *Aggregator) add(fl *flow.Flow) {
-	k := FlowToKey(fl)
+	ret := make([]*flow.Flow, 0, (lastWindow-firstWindow)/windowMs+1)
+	remainingSize := fl.Size
+	remainingPackets := fl.Packets
+	for w := firstWindow; w <= lastWindow; w += windowMs {
+		part := *fl
+		part.Timestamp = w / 1000
+
+		if w == lastWindow {
+			part.Size = remainingSize
+			part.Packets = remainingPackets
+			ret = append(ret, &part)
+			break
+		}
 
-	if _, exists := a.data[k]; !exists {
-		a.data[k] = fl
-		return
+		from := max(startMs, w)
+		if w == firstWindow {
+			from = startMs
+		}
+
+		overlap := min(endMs, w+windowMs) - from
+		part.Size = fl.Size * uint64(overlap) / uint64(duration)
+		part.Packets = fl.Packets * uint64(overlap) / uint64(duration)
+		remainingSize -= part.Size
+		remainingPackets -= part.Packets
+
+		ret = append(ret, &part)
 	}
 
-	a.data[k].Add(fl)
+	return ret
 }
 
-func (a *Aggregator) GetIngress() chan<- *flow.Flow {
-	return a.ingress
+// closedBefore gets the time windows starting before are closed at now
+func (a *Aggregator) closedBefore(now time.Time) int64 {
+	return now.Add(-a.cfg.Lateness).Unix() - a.window + 1
 }
 
-func (a *Aggregator) flush() {
-	s := make([]*flow.Flow, len(a.data))
-
-	i := 0
-	for _, fl := range a.data {
-		s[i] = fl
-		i++
+// oldestOpenWindow gets the timestamp of the oldest window that is still open at now
+func (a *Aggregator) oldestOpenWindow(now time.Time) int64 {
+	cb := a.closedBefore(now)
+	w := cb - cb%a.window
+	if w < cb {
+		w += a.window
 	}
 
-	a.output <- s
-	a.data = make(map[Key]*flow.Flow)
+	return w
 }
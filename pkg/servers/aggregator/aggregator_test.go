@@ -0,0 +1,359 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestSplitFlow(t *testing.T) {
+	tests := []struct {
+		name     string
+		fl       *flow.Flow
+		startMs  int64
+		endMs    int64
+		now      int64
+		expected []*flow.Flow
+	}{
+		{
+			name: "Single window",
+			fl: &flow.Flow{
+				Size:    1000,
+				Packets: 10,
+			},
+			startMs: 100001000,
+			endMs:   100009000,
+			now:     100010,
+			expected: []*flow.Flow{
+				{
+					Timestamp: 100009,
+					Size:      1000,
+					Packets:   10,
+				},
+			},
+		},
+		{
+			name: "Three windows",
+			fl: &flow.Flow{
+				Size:    3000,
+				Packets: 30,
+			},
+			startMs: 100005000,
+			endMs:   100025000,
+			now:     100025,
+			expected: []*flow.Flow{
+				{
+					Timestamp: 100000,
+					Size:      750,
+					Packets:   7,
+				},
+				{
+					Timestamp: 100010,
+					Size:      1500,
+					Packets:   15,
+				},
+				{
+					Timestamp: 100020,
+					Size:      750,
+					Packets:   8,
+				},
+			},
+		},
+		{
+			name: "First window closed",
+			fl: &flow.Flow{
+				Size:    3000,
+				Packets: 30,
+			},
+			startMs: 100005000,
+			endMs:   100025000,
+			now:     100039,
+			expected: []*flow.Flow{
+				{
+					Timestamp: 100010,
+					Size:      2250,
+					Packets:   22,
+				},
+				{
+					Timestamp: 100020,
+					Size:      750,
+					Packets:   8,
+				},
+			},
+		},
+		{
+			name: "All windows closed",
+			fl: &flow.Flow{
+				Size:    3000,
+				Packets: 30,
+			},
+			startMs: 100005000,
+			endMs:   100025000,
+			now:     100060,
+			expected: []*flow.Flow{
+				{
+					Timestamp: 100025,
+					Size:      3000,
+					Packets:   30,
+				},
+			},
+		},
+		{
+			name: "End before start",
+			fl: &flow.Flow{
+				Size:    1000,
+				Packets: 10,
+			},
+			startMs: 100025000,
+			endMs:   100005000,
+			now:     100025,
+			expected: []*flow.Flow{
+				{
+					Timestamp: 100005,
+					Size:      1000,
+					Packets:   10,
+				},
+			},
+		},
+	}
+
+	a := &Aggregator{
+		cfg: Config{
+			Lateness: 20 * time.Second,
+		},
+		window: 10,
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, a.splitFlow(test.fl, test.startMs, test.endMs, time.Unix(test.now, 0)), test.name)
+	}
+}
+
+func TestAddMergesTCPFlags(t *testing.T) {
+	s := newShard(&Aggregator{})
+
+	s.add(&flow.Flow{Protocol: 6, Size: 60, Packets: 1, TCPFlags: 0x02})
+	s.add(&flow.Flow{Protocol: 6, Size: 1500, Packets: 1, TCPFlags: 0x10})
+	s.add(&flow.Flow{Protocol: 1, Size: 100, Packets: 1, ICMPType: 3, ICMPCode: 1})
+	s.add(&flow.Flow{Protocol: 1, Size: 100, Packets: 1, ICMPType: 8})
+
+	assert.Equal(t, 3, len(s.windows[0]))
+	assert.Equal(t, &flow.Flow{Protocol: 6, Size: 1560, Packets: 2, TCPFlags: 0x12}, s.windows[0][Key{Protocol: 6}])
+}
+
+func TestIngest(t *testing.T) {
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	now := time.Unix(1700000005, 0)
+
+	tests := []struct {
+		name         string
+		cfg          Config
+		fl           *flow.Flow
+		expectedLate []*flow.Flow
+		expectedLen  map[int64]int
+	}{
+		{
+			name:        "current window",
+			fl:          &flow.Flow{Agent: agent, Timestamp: 1700000003, Size: 100},
+			expectedLen: map[int64]int{1699999990: 1, 1700000000: 2},
+		},
+		{
+			name:        "no timestamp",
+			fl:          &flow.Flow{Agent: agent, Size: 100},
+			expectedLen: map[int64]int{1699999990: 1, 1700000000: 2},
+		},
+		{
+			name:        "previous window still open",
+			fl:          &flow.Flow{Agent: agent, Timestamp: 1699999985, Size: 100},
+			expectedLen: map[int64]int{1699999980: 1, 1699999990: 1, 1700000000: 1},
+		},
+		{
+			name:        "future",
+			fl:          &flow.Flow{Agent: agent, Timestamp: 1700003600, Size: 100},
+			expectedLen: map[int64]int{1699999990: 1, 1700000000: 2},
+		},
+		{
+			name:         "late",
+			fl:           &flow.Flow{Agent: agent, Timestamp: 1699999975, Size: 100},
+			expectedLate: []*flow.Flow{{Agent: agent, Timestamp: 1699999970, Size: 100}},
+			expectedLen:  map[int64]int{1699999990: 1, 1700000000: 1},
+		},
+		{
+			name: "late dropped",
+			cfg: Config{
+				DropLateFlows: true,
+			},
+			fl:          &flow.Flow{Agent: agent, Timestamp: 1699999975, Size: 100},
+			expectedLen: map[int64]int{1699999990: 1, 1700000000: 1},
+		},
+	}
+
+	for _, test := range tests {
+		a := &Aggregator{
+			cfg:    test.cfg,
+			window: 10,
+		}
+		if a.cfg.Lateness == 0 {
+			a.cfg.Lateness = 20 * time.Second
+		}
+		s := newShard(a)
+
+		s.ingest(&flow.Flow{Agent: agent, Timestamp: 1699999990, Protocol: 6}, now)
+		s.ingest(&flow.Flow{Agent: agent, Timestamp: 1700000001, Protocol: 17}, now)
+		s.ingest(test.fl, now)
+
+		assert.Equal(t, test.expectedLate, s.late, test.name)
+		assert.Equal(t, len(test.expectedLen), len(s.windows), test.name)
+		for ts, n := range test.expectedLen {
+			assert.Equal(t, n, len(s.windows[ts]), "%s: window %d", test.name, ts)
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	output := make(chan []*flow.Flow, 1)
+	s := newShard(&Aggregator{
+		cfg: Config{
+			Lateness: 20 * time.Second,
+		},
+		window: 10,
+		output: output,
+	})
+
+	start := time.Unix(1700000000, 0)
+	s.ingest(&flow.Flow{Timestamp: 1699999990, Size: 100}, start)
+	s.ingest(&flow.Flow{Timestamp: 1700000000, Size: 200}, start)
+	s.flush(s.closedBefore(start))
+	assert.Empty(t, output)
+
+	// The window starting at 1699999990 closes once the lateness allowance has passed
+	s.ingest(&flow.Flow{Timestamp: 1700000000, Size: 300}, start.Add(20*time.Second))
+	s.flush(s.closedBefore(start.Add(20 * time.Second)))
+	assert.Equal(t, []*flow.Flow{{Timestamp: 1699999990, Size: 100}}, <-output)
+	assert.Equal(t, uint64(500), s.windows[1700000000][Key{Timestamp: 1700000000}].Size)
+}
+
+func TestStop(t *testing.T) {
+	output := make(chan []*flow.Flow, 16)
+	a, err := New(output, Config{
+		Shards: 4,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create aggregator: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		a.Ingest(&flow.Flow{
+			SrcAddr: bnet.IPv4(uint32(i % 10)),
+			Size:    100,
+		})
+	}
+
+	// Stop flushes the open windows
+	a.Stop()
+	close(output)
+
+	keys := make(map[bnet.IP]struct{})
+	total := uint64(0)
+	for flows := range output {
+		for _, fl := range flows {
+			keys[fl.SrcAddr] = struct{}{}
+			total += fl.Size
+		}
+	}
+
+	assert.Equal(t, uint64(10000), total)
+	assert.Len(t, keys, 10)
+}
+
+func TestSplitFlowDropLateFlows(t *testing.T) {
+	output := make(chan []*flow.Flow, 16)
+	a, err := New(output, Config{
+		DropLateFlows: true,
+		Shards:        1,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create aggregator: %v", err)
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	late := testutil.ToFloat64(lateFlows.WithLabelValues(agent.String()))
+
+	// A flow exported after the active timeout of 5 minutes spans 30 windows
+	endMs := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, part := range a.SplitFlow(&flow.Flow{Agent: agent, Size: 30000, Packets: 300}, endMs-300000, endMs) {
+		a.Ingest(part)
+	}
+
+	a.Stop()
+	close(output)
+
+	total := uint64(0)
+	packets := uint64(0)
+	for flows := range output {
+		for _, fl := range flows {
+			total += fl.Size
+			packets += fl.Packets
+		}
+	}
+
+	assert.Equal(t, uint64(30000), total)
+	assert.Equal(t, uint64(300), packets)
+	assert.Equal(t, late, testutil.ToFloat64(lateFlows.WithLabelValues(agent.String())))
+}
+
+func TestFlowToKey(t *testing.T) {
+	fl := &flow.Flow{
+		Protocol: 6,
+		IntIn:    "et-0/0/0",
+		IntOut:   "et-0/0/1",
+		TOS:      0xb8,
+		VLANIn:   100,
+		NextHop:  bnet.IPv4FromOctets(192, 0, 2, 1),
+	}
+
+	tests := []struct {
+		name     string
+		fields   []string
+		expected Key
+	}{
+		{
+			name:     "base key",
+			fields:   []string{},
+			expected: Key{Protocol: 6},
+		},
+		{
+			name:   "default",
+			fields: DefaultKeyFields,
+			expected: Key{
+				Protocol: 6,
+				IntIn:    "et-0/0/0",
+				IntOut:   "et-0/0/1",
+				TOS:      0xb8,
+				VLANIn:   100,
+			},
+		},
+		{
+			name:   "next hop",
+			fields: []string{"next_hop"},
+			expected: Key{
+				Protocol: 6,
+				NextHop:  bnet.IPv4FromOctets(192, 0, 2, 1),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		fields, err := ParseKeyFields(test.fields)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, FlowToKey(fl, fields), test.name)
+	}
+
+	_, err := ParseKeyFields([]string{"interfaces", "foo"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package aggregator
+
+import (
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+)
+
+// KeyFields is a set of optional fields flows are aggregated by
+type KeyFields uint16
+
+// Optional aggregation key fields
+const (
+	KeyInterfaces KeyFields = 1 << iota
+	KeyTOS
+	KeyVLAN
+	KeyNextHop
+	KeyAS
+	KeyVRF
+	KeyMPLS
+	KeyTunnel
+)
+
+var keyFieldNames = map[string]KeyFields{
+	"interfaces": KeyInterfaces,
+	"tos":        KeyTOS,
+	"vlan":       KeyVLAN,
+	"next_hop":   KeyNextHop,
+	"as":         KeyAS,
+	"vrf":        KeyVRF,
+	"mpls":       KeyMPLS,
+	"tunnel":     KeyTunnel,
+}
+
+// DefaultKeyFields are the optional key fields used if none are configured
+var DefaultKeyFields = []string{"interfaces", "tos", "vlan"}
+
+// ParseKeyFields converts a list of key field names into a `KeyFields` set
+func ParseKeyFields(names []string) (KeyFields, error) {
+	var ret KeyFields
+	for _, n := range names {
+		f, ok := keyFieldNames[n]
+		if !ok {
+			return 0, errors.Errorf("Unknown aggregation key field %q", n)
+		}
+
+		ret |= f
+	}
+
+	return ret, nil
+}
+
+// Key identifies the flows aggregated into one. The agent, 5-tuple, ICMP type/code and
+// fragment flag are always part of the key. Other fields are only set if selected.
+type Key struct {
+	Timestamp int64
+	Agent     bnet.IP
+	SubAgent  uint32
+	Src       bnet.IP
+	Dst       bnet.IP
+	Sport     uint16
+	Dport     uint16
+	Protocol  uint8
+	ICMPType  uint8
+	ICMPCode  uint8
+	Fragment  bool
+
+	IntIn      string
+	IntOut     string
+	TOS        uint8
+	VLANIn     uint16
+	VLANOut    uint16
+	VLANOuter  uint16
+	VLANInner  uint16
+	NextHop    bnet.IP
+	SrcAs      uint32
+	DstAs      uint32
+	NextAs     uint32
+	VRFIn      uint64
+	VRFOut     uint64
+	MPLSTop    uint32
+	MPLSBottom uint32
+	TunnelType uint8
+	TunnelKey  uint32
+	Outer      flow.FiveTuple
+	Inner      flow.FiveTuple
+}
+
+// FlowToKey gets the aggregation key of fl consisting of the base fields and the optional fields in fields
+func FlowToKey(fl *flow.Flow, fields KeyFields) Key {
+	k := Key{
+		Timestamp: fl.Timestamp,
+		Agent:     fl.Agent,
+		SubAgent:  fl.SubAgentID,
+		Src:       fl.SrcAddr,
+		Dst:       fl.DstAddr,
+		Sport:     fl.SrcPort,
+		Dport:     fl.DstPort,
+		Protocol:  fl.Protocol,
+		ICMPType:  fl.ICMPType,
+		ICMPCode:  fl.ICMPCode,
+		Fragment:  fl.Fragment,
+	}
+
+	if fields&KeyInterfaces != 0 {
+		k.IntIn = fl.IntIn
+		k.IntOut = fl.IntOut
+	}
+
+	if fields&KeyTOS != 0 {
+		k.TOS = fl.TOS
+	}
+
+	if fields&KeyVLAN != 0 {
+		k.VLANIn = fl.VLANIn
+		k.VLANOut = fl.VLANOut
+		k.VLANOuter = fl.VLANOuter
+		k.VLANInner = fl.VLANInner
+	}
+
+	if fields&KeyNextHop != 0 {
+		k.NextHop = fl.NextHop
+	}
+
+	if fields&KeyAS != 0 {
+		k.SrcAs = fl.SrcAs
+		k.DstAs = fl.DstAs
+		k.NextAs = fl.NextAs
+	}
+
+	if fields&KeyVRF != 0 {
+		k.VRFIn = fl.VRFIn
+		k.VRFOut = fl.VRFOut
+	}
+
+	if fields&KeyMPLS != 0 {
+		k.MPLSTop = fl.MPLSTopLabel
+		k.MPLSBottom = fl.MPLSBottomLabel
+	}
+
+	if fields&KeyTunnel != 0 {
+		k.TunnelType = fl.TunnelType
+		k.TunnelKey = fl.TunnelKey
+		k.Outer = fl.Outer
+		k.Inner = fl.Inner
+	}
+
+	return k
+}
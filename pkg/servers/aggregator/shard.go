@@ -0,0 +1,157 @@
+package aggregator
+
+import (
+	"math"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+)
+
+// shard aggregates the flows of a subset of keys. Its state is only accessed by its service goroutine.
+type shard struct {
+	a       *Aggregator
+	windows map[int64]map[Key]*flow.Flow
+	late    []*flow.Flow
+	ingress chan *flow.Flow
+}
+
+func newShard(a *Aggregator) *shard {
+	return &shard{
+		a:       a,
+		windows: make(map[int64]map[Key]*flow.Flow),
+		ingress: make(chan *flow.Flow, shardQueueLength),
+	}
+}
+
+// shardIndex gets the shard of fl. It's based on fields that are part of every key, so
+// flows sharing a key are always assigned to the same shard.
+func shardIndex(fl *flow.Flow, n int) int {
+	h := uint64(14695981039346656037)
+	for _, v := range [...]uint64{
+		fl.Agent.Higher(),
+		fl.Agent.Lower(),
+		fl.SrcAddr.Higher(),
+		fl.SrcAddr.Lower(),
+		fl.DstAddr.Higher(),
+		fl.DstAddr.Lower(),
+		uint64(fl.SrcPort)<<24 | uint64(fl.DstPort)<<8 | uint64(fl.Protocol),
+	} {
+		h ^= v
+		h *= 1099511628211
+	}
+
+	h ^= h >> 32
+	return int(h % uint64(n))
+}
+
+// service aggregates ingested flows and flushes closed windows periodically until stopCh is closed
+func (s *shard) service(stopCh chan struct{}) {
+	t := time.NewTicker(flushInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case fl := <-s.ingress:
+			s.ingest(fl, time.Now())
+		case now := <-t.C:
+			s.flush(s.closedBefore(now))
+		case <-stopCh:
+			s.drain()
+			s.flush(math.MaxInt64)
+			return
+		}
+	}
+}
+
+// drain ingests the flows left in the queue
+func (s *shard) drain() {
+	now := time.Now()
+	for {
+		select {
+		case fl := <-s.ingress:
+			s.ingest(fl, now)
+		default:
+			return
+		}
+	}
+}
+
+func (s *shard) ingest(fl *flow.Flow, now time.Time) {
+	currentWindow := now.Unix() - now.Unix()%s.a.window
+
+	if fl.Timestamp == 0 {
+		fl.Timestamp = currentWindow
+	}
+
+	fl.Timestamp -= fl.Timestamp % s.a.window
+
+	// Flows from the future are caused by exporters with clocks running ahead. Don't keep
+	// windows open for them.
+	if fl.Timestamp > currentWindow {
+		futureFlows.WithLabelValues(fl.Agent.String()).Inc()
+		fl.Timestamp = currentWindow
+	}
+
+	if fl.Timestamp < s.closedBefore(now) {
+		lateFlows.WithLabelValues(fl.Agent.String()).Inc()
+		if !s.a.cfg.DropLateFlows {
+			s.late = append(s.late, fl)
+		}
+
+		return
+	}
+
+	s.add(fl)
+}
+
+// closedBefore gets the start of the oldest window that is still open at now
+func (s *shard) closedBefore(now time.Time) int64 {
+	return s.a.closedBefore(now)
+}
+
+func (s *shard) add(fl *flow.Flow) {
+	w, exists := s.windows[fl.Timestamp]
+	if !exists {
+		w = make(map[Key]*flow.Flow)
+		s.windows[fl.Timestamp] = w
+	}
+
+	k := FlowToKey(fl, s.a.keyFields)
+	if _, exists := w[k]; !exists {
+		w[k] = fl
+		return
+	}
+
+	w[k].Add(fl)
+}
+
+// flush emits the flows of all windows starting before closedBefore and the late flows
+func (s *shard) flush(closedBefore int64) {
+	n := len(s.late)
+	for ts, w := range s.windows {
+		if ts < closedBefore {
+			n += len(w)
+		}
+	}
+
+	if n == 0 {
+		return
+	}
+
+	ret := make([]*flow.Flow, 0, n)
+	for ts, w := range s.windows {
+		if ts >= closedBefore {
+			continue
+		}
+
+		for _, fl := range w {
+			ret = append(ret, fl)
+		}
+		delete(s.windows, ts)
+	}
+
+	ret = append(ret, s.late...)
+	s.late = nil
+
+	s.a.output <- ret
+}
@@ -0,0 +1,72 @@
+package ipfix
+
+import (
+	"sync"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+const (
+	// maxSequenceRewind is the number of data records the sequence number may go
+	// backwards (e.g. due to reordering) before the exporter is considered restarted
+	maxSequenceRewind = 100000
+
+	// maxExportTimeRewind is the number of seconds the export time may go
+	// backwards before the exporter is considered restarted
+	maxExportTimeRewind = 60
+)
+
+type exporterState struct {
+	sequenceNumber uint32
+	exportTime     uint32
+}
+
+// exporterStateCache keeps the last seen sequence number and export time
+// per observation domain in order to detect exporter restarts
+type exporterStateCache struct {
+	data   map[sampleRateCacheKey]*exporterState
+	dataMu sync.Mutex
+}
+
+func newExporterStateCache() *exporterStateCache {
+	return &exporterStateCache{
+		data: make(map[sampleRateCacheKey]*exporterState),
+	}
+}
+
+// update records sequence number and export time of a message and returns
+// true if they indicate that the exporter has been restarted
+func (esc *exporterStateCache) update(agent bnet.IP, observationDomainID uint32, sequenceNumber uint32, exportTime uint32) bool {
+	esc.dataMu.Lock()
+	defer esc.dataMu.Unlock()
+
+	k := newSampleRateCacheKey(agent, observationDomainID)
+	s, found := esc.data[k]
+	if !found {
+		esc.data[k] = &exporterState{
+			sequenceNumber: sequenceNumber,
+			exportTime:     exportTime,
+		}
+		return false
+	}
+
+	restarted := false
+	if int32(sequenceNumber-s.sequenceNumber) < -maxSequenceRewind {
+		restarted = true
+	}
+
+	if int64(exportTime) < int64(s.exportTime)-maxExportTimeRewind {
+		restarted = true
+	}
+
+	// Out of order messages must not move the state backwards
+	if restarted || int32(sequenceNumber-s.sequenceNumber) > 0 {
+		s.sequenceNumber = sequenceNumber
+	}
+
+	if restarted || exportTime > s.exportTime {
+		s.exportTime = exportTime
+	}
+
+	return restarted
+}
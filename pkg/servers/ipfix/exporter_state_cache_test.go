@@ -0,0 +1,68 @@
+package ipfix
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestExporterStateCacheUpdate(t *testing.T) {
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+
+	tests := []struct {
+		name           string
+		sequenceNumber uint32
+		exportTime     uint32
+		expected       bool
+	}{
+		{
+			name:           "first message",
+			sequenceNumber: 1000000,
+			exportTime:     1000,
+			expected:       false,
+		},
+		{
+			name:           "regular message",
+			sequenceNumber: 1000500,
+			exportTime:     1001,
+			expected:       false,
+		},
+		{
+			name:           "reordered message",
+			sequenceNumber: 1000200,
+			exportTime:     1000,
+			expected:       false,
+		},
+		{
+			name:           "sequence number reset",
+			sequenceNumber: 10,
+			exportTime:     1010,
+			expected:       true,
+		},
+		{
+			name:           "sequence number wrap",
+			sequenceNumber: 4294967000,
+			exportTime:     1011,
+			expected:       false,
+		},
+		{
+			name:           "sequence number after wrap",
+			sequenceNumber: 100,
+			exportTime:     1012,
+			expected:       false,
+		},
+		{
+			name:           "export time going backwards",
+			sequenceNumber: 200,
+			exportTime:     500,
+			expected:       true,
+		},
+	}
+
+	esc := newExporterStateCache()
+	for _, test := range tests {
+		assert.Equal(t, test.expected, esc.update(agent, 1, test.sequenceNumber, test.exportTime), test.name)
+	}
+}
@@ -1,23 +1,35 @@
 package ipfix
 
 import (
-	"io"
-	"net"
 	"runtime/debug"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	bnet "github.com/bio-routing/bio-rd/net"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
 	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
 	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/flowhouse/pkg/servers/sequence"
+	"github.com/bio-routing/flowhouse/pkg/servers/udpreceiver"
 	"github.com/bio-routing/tflow2/convert"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	log "github.com/sirupsen/logrus"
 )
 
+var labels []string
+
+func init() {
+	labels = []string{
+		"agent",
+	}
+}
+
 type InterfaceResolver interface {
 	Resolve(agent bnet.IP, ifID uint32) string
 }
@@ -47,107 +59,293 @@ type fieldMap struct {
 	srcMask6               int
 	dstMask6               int
 	samplingInterval       int
+	flowStartSeconds       int
+	flowEndSeconds         int
+	flowStartMs            int
+	flowEndMs              int
+	flowStartSysUpTime     int
+	flowEndSysUpTime       int
+	systemInitTime         int
+	application            int
+	vrfNameIn              int
+	vrfNameOut             int
+	tcpFlags               int
+	icmpTypeCode           int
+	icmpType               int
+	icmpCode               int
+
+	// elements holds the Information Element describing string fields by index
+	elements map[int]*ipfix.InformationElement
+}
+
+// Names of Information Elements that are mapped onto flow fields
+const (
+	applicationNameElement = "applicationName"
+	ingressVRFNameElement  = "ingressVRFName"
+	egressVRFNameElement   = "egressVRFName"
+)
+
+// applicationNameIE describes the IANA applicationName Information Element
+var applicationNameIE = &ipfix.InformationElement{
+	ElementID: ipfix.ApplicationName,
+	Name:      applicationNameElement,
+	Type:      ipfix.DataTypeString,
+}
+
+// EnterpriseElement describes an enterprise-specific Information Element. Elements named
+// applicationName, ingressVRFName or egressVRFName are mapped onto the corresponding flow fields.
+type EnterpriseElement struct {
+	EnterpriseNumber uint32 `yaml:"enterprise_number"`
+	ElementID        uint16 `yaml:"element_id"`
+	Name             string `yaml:"name"`
+	Type             string `yaml:"type"`
+}
+
+// decodedFlowSet is a data set decoded using the template it refers to
+type decodedFlowSet struct {
+	template []*ipfix.TemplateRecord
+	isOpts   bool
+	records  []ipfix.FlowDataRecord
 }
 
 type IPFIXServer struct {
+	cfg *Config
+
 	// tmplCache is used to save received flow templates
 	// for later lookup in order to decode netflow packets
 	tmplCache       *templateCache
-	conn            *net.UDPConn
+	receiver        *udpreceiver.Receiver
 	ifResolver      InterfaceResolver
 	output          chan []*flow.Flow
 	wg              sync.WaitGroup
 	stopCh          chan struct{}
 	aggregator      *aggregator.Aggregator
 	sampleRateCache *sampleRateCache
+	sysInitCache    *systemInitTimeCache
+	exporterStates  *exporterStateCache
+	sequences       *sequence.Tracker
+	registry        *ipfix.Registry
+	snapshotter     *snapshotter
+
+	flowSetsMissingTemplate *prometheus.CounterVec
+	templatesWithdrawn      *prometheus.CounterVec
+	templatesExpired        prometheus.Counter
+	exporterRestarts        *prometheus.CounterVec
+	lostRecords             *prometheus.CounterVec
+	rejectedPackets         *prometheus.CounterVec
+}
+
+// Config is the configuration of an IPFIX server
+type Config struct {
+	// EnterpriseElements are enterprise-specific Information Elements to decode
+	EnterpriseElements []*EnterpriseElement
+
+	// TemplateTimeout is the time after which templates that have not been
+	// refreshed by the exporter are discarded. 0 disables expiry.
+	TemplateTimeout time.Duration
+
+	// SnapshotFile is the file templates, sample rates and system init times are persisted to.
+	// Persisting is disabled if empty.
+	SnapshotFile string
+
+	// SnapshotMaxAge is the maximum age of templates restored from the snapshot file.
+	// 0 disables the limit.
+	SnapshotMaxAge time.Duration
+
+	// StoreLossRatio enables recording the data record loss ratio of the observation domain with each flow
+	StoreLossRatio bool
+
+	// AgentFilter restricts the agents datagrams are accepted from. nil accepts all agents.
+	AgentFilter *agentfilter.Filter
+
+	// Receiver configures the UDP sockets
+	Receiver udpreceiver.Config
+
+	// Aggregator configures the aggregation of flows
+	Aggregator aggregator.Config
 }
 
 // New creates and starts a new `IPFIXServer` instance
-func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver InterfaceResolver) (*IPFIXServer, error) {
+func New(listen string, numSockets int, output chan []*flow.Flow, ifResolver InterfaceResolver, cfg *Config) (*IPFIXServer, error) {
+	registry, err := newRegistry(cfg.EnterpriseElements)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create enterprise element registry")
+	}
+
+	agg, err := aggregator.New(output, cfg.Aggregator)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create aggregator")
+	}
+
 	ipf := &IPFIXServer{
-		tmplCache:       newTemplateCache(),
+		cfg:             cfg,
+		tmplCache:       newTemplateCache(cfg.TemplateTimeout),
 		ifResolver:      ifResolver,
 		stopCh:          make(chan struct{}),
 		output:          output,
-		aggregator:      aggregator.New(output),
+		aggregator:      agg,
 		sampleRateCache: newSampleRateCache(),
+		sysInitCache:    newSystemInitTimeCache(),
+		exporterStates:  newExporterStateCache(),
+		sequences:       sequence.New(maxSequenceRewind, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
+		registry:        registry,
+		flowSetsMissingTemplate: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "flow_sets_missing_template",
+			Help:      "Data sets dropped due to unknown template",
+		}, labels),
+		templatesWithdrawn: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "templates_withdrawn",
+			Help:      "Template withdrawals received",
+		}, labels),
+		templatesExpired: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "templates_expired",
+			Help:      "Templates discarded due to timeout",
+		}),
+		exporterRestarts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "exporter_restarts",
+			Help:      "Exporter restarts detected",
+		}, labels),
+		lostRecords: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "lost_data_records",
+			Help:      "Data records missing in the sequence of an observation domain",
+		}, labels),
+		rejectedPackets: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "ipfix",
+			Name:      "rejected_packets",
+			Help:      "Packets rejected due to unknown agent or rate limiting. Unknown agents are not labelled.",
+		}, []string{"agent", "reason"}),
 	}
 
-	addr, err := net.ResolveUDPAddr("udp", listen)
-	if err != nil {
-		return nil, errors.Wrap(err, "Unable to resolve UDP address")
+	if cfg.SnapshotFile != "" {
+		ipf.snapshotter = newSnapshotter(cfg.SnapshotFile, cfg.SnapshotMaxAge, ipf.tmplCache, ipf.sampleRateCache, ipf.sysInitCache)
+		err := ipf.snapshotter.load(time.Now())
+		if err != nil {
+			log.WithError(err).Error("Unable to restore IPFIX caches")
+		}
 	}
 
-	con, err := net.ListenUDP("udp", addr)
+	r, err := udpreceiver.New(listen, numSockets, cfg.Receiver, ipf.handlePacket, promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "ipfix",
+		Name:      "socket_drops",
+		Help:      "Datagrams dropped by the kernel due to full socket receive buffers",
+	}))
 	if err != nil {
-		return nil, errors.Wrap(err, "ListenUDP failed")
+		return nil, errors.Wrap(err, "Unable to create UDP receiver")
 	}
-	ipf.conn = con
+	ipf.receiver = r
 
-	ipf.startService(numReaders)
+	ipf.startService()
 	return ipf, nil
 }
 
-func (ipf *IPFIXServer) startService(numReaders int) {
-	for i := 0; i < numReaders; i++ {
+// newRegistry creates an Information Element registry containing elements
+func newRegistry(elements []*EnterpriseElement) (*ipfix.Registry, error) {
+	r := ipfix.NewRegistry()
+	for _, e := range elements {
+		dt, err := ipfix.ParseDataType(e.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid element %d/%d", e.EnterpriseNumber, e.ElementID)
+		}
+
+		r.Register(&ipfix.InformationElement{
+			EnterpriseNumber: e.EnterpriseNumber,
+			ElementID:        e.ElementID,
+			Name:             e.Name,
+			Type:             dt,
+		})
+	}
+
+	return r, nil
+}
+
+func (ipf *IPFIXServer) startService() {
+	if ipf.snapshotter != nil {
 		ipf.wg.Add(1)
 		go func() {
 			defer ipf.wg.Done()
-			err := ipf.packetWorker()
-			if err != nil {
-				log.WithError(err).Error("packetWorker failed")
-			}
+			ipf.snapshotter.run(ipf.stopCh)
+		}()
+	}
+
+	if ipf.tmplCache.timeout != 0 {
+		ipf.wg.Add(1)
+		go func() {
+			defer ipf.wg.Done()
+			ipf.templateExpiryWorker()
 		}()
 	}
+
+	ipf.receiver.Start()
 }
 
-// Stop closes the socket and stops the workers
+// Stop closes the sockets and stops the workers
 func (ipf *IPFIXServer) Stop() {
 	log.Info("Stopping IPFIX server")
 	debug.PrintStack()
 	close(ipf.stopCh)
-	ipf.conn.Close()
+	ipf.receiver.Stop()
 	ipf.aggregator.Stop()
 	ipf.wg.Wait()
-}
-
-// packetWorker reads sflow packet from socket and handsoff processing to ???
-func (ipf *IPFIXServer) packetWorker() error {
-	buffer := make([]byte, 8960)
-	for {
-		if ipf.stopped() {
-			return nil
-		}
-
-		length, remote, err := ipf.conn.ReadFromUDP(buffer)
-		if err == io.EOF {
-			return nil
-		}
 
+	if ipf.snapshotter != nil {
+		err := ipf.snapshotter.save(time.Now())
 		if err != nil {
-			return errors.Wrap(err, "ReadFromUDP failed")
+			log.WithError(err).Error("Unable to write IPFIX cache snapshot")
 		}
+	}
+}
 
-		remote4 := remote.IP.To4()
-		if remote4 != nil {
-			remote.IP = remote4
-		}
+// handlePacket hands off a datagram received from agent to processPacket
+func (ipf *IPFIXServer) handlePacket(agent bnet.IP, buffer []byte) {
+	if !ipf.accept(agent, time.Now()) {
+		return
+	}
 
-		remoteAddr, err := bnet.IPFromBytes([]byte(remote.IP))
-		if err != nil {
-			return errors.Wrapf(err, "Unable to convert net.IP to bnet.IP: %q", remote)
-		}
+	ipf.processPacket(agent, buffer)
+}
 
-		ipf.processPacket(remoteAddr, buffer[:length])
+// accept checks if the agent filter accepts a datagram of agent
+func (ipf *IPFIXServer) accept(agent bnet.IP, now time.Time) bool {
+	if ipf.cfg.AgentFilter == nil {
+		return true
+	}
+
+	ok, reason := ipf.cfg.AgentFilter.Check(agent, now)
+	if !ok {
+		ipf.rejectedPackets.WithLabelValues(ipf.cfg.AgentFilter.AgentLabel(agent), reason).Inc()
 	}
+
+	return ok
 }
 
-func (ipf *IPFIXServer) stopped() bool {
-	select {
-	case <-ipf.stopCh:
-		return true
-	default:
-		return false
+// templateExpiryWorker periodically removes timed out templates from the template cache
+func (ipf *IPFIXServer) templateExpiryWorker() {
+	t := time.NewTicker(ipf.tmplCache.timeout / 2)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ipf.stopCh:
+			return
+		case now := <-t.C:
+			n := ipf.tmplCache.expire(now)
+			if n > 0 {
+				log.Infof("Expired %d IPFIX templates", n)
+				ipf.templatesExpired.Add(float64(n))
+			}
+		}
 	}
 }
 
@@ -158,19 +356,45 @@ func (ipf *IPFIXServer) processPacket(agent bnet.IP, buffer []byte) {
 		return
 	}
 
+	if ipf.exporterStates.update(agent, pkt.Header.DomainID, pkt.Header.SequenceNumber, pkt.Header.ExportTime) {
+		log.WithFields(log.Fields{
+			"agent":              agent.String(),
+			"observation_domain": pkt.Header.DomainID,
+			"sequence_number":    pkt.Header.SequenceNumber,
+			"export_time":        pkt.Header.ExportTime,
+		}).Info("IPFIX exporter restart detected. Flushing templates.")
+		ipf.exporterRestarts.WithLabelValues(agent.String()).Inc()
+		ipf.tmplCache.flush(agent, pkt.Header.DomainID)
+		ipf.sequences.Reset(sequence.Key{Agent: agent, Domain: pkt.Header.DomainID})
+		ipf.cachesChanged()
+	}
+
 	ipf.updateTemplateCache(agent, pkt)
-	ipf.processFlowSets(agent, pkt.Header.DomainID, pkt.DataFlowSets(), int64(pkt.Header.ExportTime))
+	sets, complete := ipf.decodeFlowSets(agent, pkt.Header.DomainID, pkt.DataFlowSets())
+	lossRatio := ipf.trackSequence(agent, pkt.Header, sets, complete, time.Now())
+	if !ipf.storeLossRatio() {
+		lossRatio = 0
+	}
+
+	for _, set := range sets {
+		ipf.processFlowSet(set.template, set.records, agent, pkt.Header.DomainID, int64(pkt.Header.ExportTime), set.isOpts, lossRatio)
+	}
 }
 
-// processFlowSets iterates over flowSets and calls processFlowSet() for each flow set
-func (ipf *IPFIXServer) processFlowSets(remote bnet.IP, observationDomainID uint32, flowSets []*ipfix.FlowSet, ts int64) {
+// decodeFlowSets decodes flowSets using the cached templates. Returns false if
+// any of the sets could not be decoded.
+func (ipf *IPFIXServer) decodeFlowSets(remote bnet.IP, observationDomainID uint32, flowSets []*ipfix.FlowSet) ([]*decodedFlowSet, bool) {
 	addr := remote.String()
+	complete := true
+	res := make([]*decodedFlowSet, 0, len(flowSets))
 	for _, set := range flowSets {
 		template, isOpts := ipf.tmplCache.get(remote, observationDomainID, set.Header.SetID)
 
 		if template == nil {
 			templateKey := makeTemplateKey(addr, observationDomainID, set.Header.SetID)
 			log.Debugf("Template for given FlowSet not found: %s", templateKey)
+			ipf.flowSetsMissingTemplate.WithLabelValues(addr).Inc()
+			complete = false
 
 			continue
 		}
@@ -178,125 +402,253 @@ func (ipf *IPFIXServer) processFlowSets(remote bnet.IP, observationDomainID uint
 		records := ipfix.DecodeFlowSet(*set, template)
 		if records == nil {
 			log.Warning("Error decoding FlowSet")
+			complete = false
 			continue
 		}
 
-		ipf.processFlowSet(template, records, remote, observationDomainID, ts, isOpts)
+		res = append(res, &decodedFlowSet{
+			template: template,
+			isOpts:   isOpts,
+			records:  records,
+		})
+	}
+
+	return res, complete
+}
+
+// trackSequence checks the sequence number of a message for gaps and returns the loss ratio of the observation domain.
+// IPFIX sequence numbers count data records. If not all sets of the message could be decoded the number of records
+// is unknown and tracking restarts with the next message.
+func (ipf *IPFIXServer) trackSequence(agent bnet.IP, hdr *ipfix.Header, sets []*decodedFlowSet, complete bool, now time.Time) float32 {
+	k := sequence.Key{
+		Agent:  agent,
+		Domain: hdr.DomainID,
+	}
+
+	if !complete {
+		ipf.sequences.Reset(k)
+		return 0
+	}
+
+	n := 0
+	for _, set := range sets {
+		n += len(set.records)
+	}
+
+	res := ipf.sequences.Observe(k, hdr.SequenceNumber, uint32(n), now)
+	if res.Lost > 0 {
+		ipf.lostRecords.WithLabelValues(agent.String()).Add(float64(res.Lost))
 	}
+
+	return res.LossRatio
+}
+
+func (ipf *IPFIXServer) storeLossRatio() bool {
+	return ipf.cfg != nil && ipf.cfg.StoreLossRatio
 }
 
-// process generates Flow elements from records and pushes them into the `receiver` channel
-func (ipf *IPFIXServer) processFlowSet(template []*ipfix.TemplateRecord, records []ipfix.FlowDataRecord, agent bnet.IP, observationDomainID uint32, ts int64, isOpts bool) {
-	fm := generateFieldMap(template)
+// processFlowSet generates Flow elements from records and pushes them into the aggregator
+func (ipf *IPFIXServer) processFlowSet(template []*ipfix.TemplateRecord, records []ipfix.FlowDataRecord, agent bnet.IP, observationDomainID uint32, ts int64, isOpts bool, lossRatio float32) {
+	fm := generateFieldMap(template, ipf.registry)
 
 	for _, r := range records {
 		if isOpts {
-			if fm.samplingInterval > 0 {
+			if fm.samplingInterval >= 0 {
 				sampleRate := convert.Uint32(r.Values[fm.samplingInterval])
-				ipf.sampleRateCache.set(agent, observationDomainID, sampleRate)
+				if ipf.sampleRateCache.get(agent, observationDomainID) != sampleRate {
+					ipf.sampleRateCache.set(agent, observationDomainID, sampleRate)
+					ipf.cachesChanged()
+				}
+			}
+
+			if fm.systemInitTime >= 0 {
+				initTime := convert.Uint64(r.Values[fm.systemInitTime])
+				if t, found := ipf.sysInitCache.get(agent, observationDomainID); !found || t != initTime {
+					ipf.sysInitCache.set(agent, observationDomainID, initTime)
+					ipf.cachesChanged()
+				}
 			}
 
 			continue
 		}
 
-		fl := &flow.Flow{
-			Agent:     agent,
-			Timestamp: ts,
-		}
+		fl := ipf.recordToFlow(fm, r, agent, ts)
+		fl.Samplerate = uint64(ipf.sampleRateCache.get(agent, observationDomainID))
+		fl.LossRatio = lossRatio
 
-		if fm.family >= 0 {
-			fl.Family = uint8(fm.family)
+		start, end, ok := ipf.flowTimes(fm, r, agent, observationDomainID)
+		if !ok {
+			ipf.aggregator.Ingest(fl)
+			continue
 		}
 
-		if fm.packets >= 0 {
-			fl.Packets = convert.Uint64(r.Values[fm.packets])
+		for _, part := range ipf.aggregator.SplitFlow(fl, start, end) {
+			ipf.aggregator.Ingest(part)
 		}
+	}
+}
 
-		if fm.size >= 0 {
-			fl.Size = uint64(convert.Uint32(r.Values[fm.size]))
-		}
+// flowTimes gets the start and end time (unix time in milliseconds) of the flow described by r
+func (ipf *IPFIXServer) flowTimes(fm *fieldMap, r ipfix.FlowDataRecord, agent bnet.IP, observationDomainID uint32) (int64, int64, bool) {
+	if fm.flowStartMs >= 0 && fm.flowEndMs >= 0 {
+		return int64(convert.Uint64(r.Values[fm.flowStartMs])), int64(convert.Uint64(r.Values[fm.flowEndMs])), true
+	}
 
-		if fm.protocol >= 0 {
-			fl.Protocol = uint8(convert.Uint16(r.Values[fm.protocol]))
-		}
+	if fm.flowStartSeconds >= 0 && fm.flowEndSeconds >= 0 {
+		return int64(convert.Uint64(r.Values[fm.flowStartSeconds])) * 1000, int64(convert.Uint64(r.Values[fm.flowEndSeconds])) * 1000, true
+	}
 
-		if fm.intIn >= 0 {
-			fl.IntIn = ipf.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intIn]))
+	if fm.flowStartSysUpTime >= 0 && fm.flowEndSysUpTime >= 0 {
+		initTime, found := ipf.sysInitCache.get(agent, observationDomainID)
+		if !found {
+			return 0, 0, false
 		}
 
-		if fm.intOut >= 0 {
-			fl.IntOut = ipf.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intOut]))
-		}
+		start := int64(initTime) + int64(convert.Uint32(r.Values[fm.flowStartSysUpTime]))
+		end := int64(initTime) + int64(convert.Uint32(r.Values[fm.flowEndSysUpTime]))
+		return start, end, true
+	}
 
-		if fm.srcPort >= 0 {
-			fl.SrcPort = convert.Uint16(r.Values[fm.srcPort])
-		}
+	return 0, 0, false
+}
 
-		if fm.dstPort >= 0 {
-			fl.DstPort = convert.Uint16(r.Values[fm.dstPort])
-		}
+// recordToFlow converts a flow data record into a flow using field map fm
+func (ipf *IPFIXServer) recordToFlow(fm *fieldMap, r ipfix.FlowDataRecord, agent bnet.IP, ts int64) *flow.Flow {
+	fl := &flow.Flow{
+		Agent:     agent,
+		Timestamp: ts,
+	}
 
-		if fm.srcAddr >= 0 {
-			fl.SrcAddr = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.srcAddr]))
-		}
+	if fm.family >= 0 {
+		fl.Family = uint8(fm.family)
+	}
 
-		if fm.dstAddr >= 0 {
-			fl.DstAddr = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.dstAddr]))
-		}
+	if fm.packets >= 0 {
+		fl.Packets = convert.Uint64(r.Values[fm.packets])
+	}
 
-		if fm.nextHop >= 0 {
-			fl.NextHop = bnet.IPv4FromBytes(convert.Reverse(r.Values[fm.nextHop]))
-		}
+	if fm.size >= 0 {
+		fl.Size = convert.Uint64(r.Values[fm.size])
+	}
 
-		if fm.srcTos >= 0 {
-			fl.TOS = uint8(r.Values[fm.srcTos][0])
-		}
+	if fm.protocol >= 0 {
+		fl.Protocol = uint8(convert.Uint16(r.Values[fm.protocol]))
+	}
 
-		if fm.dstAsn >= 0 {
-			fl.DstAs = convert.Uint32(r.Values[fm.dstAsn])
-		}
+	if fm.intIn >= 0 {
+		fl.IntIn = ipf.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intIn]))
+	}
 
-		if fm.srcAsn >= 0 {
-			fl.SrcAs = convert.Uint32(r.Values[fm.srcAsn])
-		}
+	if fm.intOut >= 0 {
+		fl.IntOut = ipf.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intOut]))
+	}
 
-		if fm.srcMask > 0 {
-			mask := uint8(r.Values[fm.srcMask][0])
-			p := bnet.NewPfx(fl.SrcAddr, mask)
-			p.BaseAddr()
-			fl.SrcPfx = bnet.NewPfx(*p.BaseAddr(), mask)
-		}
+	if fm.srcPort >= 0 {
+		fl.SrcPort = convert.Uint16(r.Values[fm.srcPort])
+	}
 
-		if fm.dstMask > 0 {
-			mask := uint8(r.Values[fm.dstMask][0])
-			p := bnet.NewPfx(fl.DstAddr, mask)
-			p.BaseAddr()
-			fl.DstPfx = bnet.NewPfx(*p.BaseAddr(), mask)
-		}
+	if fm.dstPort >= 0 {
+		fl.DstPort = convert.Uint16(r.Values[fm.dstPort])
+	}
 
-		if fm.srcMask6 > 0 {
-			mask := uint8(r.Values[fm.srcMask6][0])
-			p := bnet.NewPfx(fl.SrcAddr, mask)
-			p.BaseAddr()
-			fl.SrcPfx = bnet.NewPfx(*p.BaseAddr(), mask)
-		}
+	if fm.srcAddr >= 0 {
+		fl.SrcAddr = addrFromBytes(r.Values[fm.srcAddr])
+	}
 
-		if fm.dstMask6 > 0 {
-			mask := uint8(r.Values[fm.dstMask6][0])
-			p := bnet.NewPfx(fl.DstAddr, mask)
-			p.BaseAddr()
-			fl.DstPfx = bnet.NewPfx(*p.BaseAddr(), mask)
-		}
+	if fm.dstAddr >= 0 {
+		fl.DstAddr = addrFromBytes(r.Values[fm.dstAddr])
+	}
 
-		fl.Samplerate = uint64(ipf.sampleRateCache.get(agent, observationDomainID))
+	if fm.nextHop >= 0 {
+		fl.NextHop = addrFromBytes(r.Values[fm.nextHop])
+	}
+
+	if fm.srcTos >= 0 && len(r.Values[fm.srcTos]) > 0 {
+		fl.TOS = uint8(r.Values[fm.srcTos][0])
+	}
+
+	if fm.tcpFlags >= 0 {
+		// tcpControlBits may be exported with 2 bytes (RFC7125), the classic flags are the lower 8 bits
+		fl.TCPFlags = uint8(convert.Uint16(r.Values[fm.tcpFlags]))
+	}
+
+	if fm.icmpTypeCode >= 0 {
+		typeCode := convert.Uint16(r.Values[fm.icmpTypeCode])
+		fl.ICMPType = uint8(typeCode >> 8)
+		fl.ICMPCode = uint8(typeCode)
+	}
+
+	if fm.icmpType >= 0 {
+		fl.ICMPType = uint8(convert.Uint16(r.Values[fm.icmpType]))
+	}
+
+	if fm.icmpCode >= 0 {
+		fl.ICMPCode = uint8(convert.Uint16(r.Values[fm.icmpCode]))
+	}
+
+	if fm.dstAsn >= 0 {
+		fl.DstAs = convert.Uint32(r.Values[fm.dstAsn])
+	}
+
+	if fm.srcAsn >= 0 {
+		fl.SrcAs = convert.Uint32(r.Values[fm.srcAsn])
+	}
 
-		ipf.aggregator.GetIngress() <- fl
+	if fm.srcMask >= 0 && len(r.Values[fm.srcMask]) > 0 && fl.Family == 4 {
+		fl.SrcPfx = makePrefix(fl.SrcAddr, uint8(r.Values[fm.srcMask][0]))
 	}
+
+	if fm.dstMask >= 0 && len(r.Values[fm.dstMask]) > 0 && fl.Family == 4 {
+		fl.DstPfx = makePrefix(fl.DstAddr, uint8(r.Values[fm.dstMask][0]))
+	}
+
+	if fm.srcMask6 >= 0 && len(r.Values[fm.srcMask6]) > 0 && fl.Family == 6 {
+		fl.SrcPfx = makePrefix(fl.SrcAddr, uint8(r.Values[fm.srcMask6][0]))
+	}
+
+	if fm.dstMask6 >= 0 && len(r.Values[fm.dstMask6]) > 0 && fl.Family == 6 {
+		fl.DstPfx = makePrefix(fl.DstAddr, uint8(r.Values[fm.dstMask6][0]))
+	}
+
+	if fm.application >= 0 {
+		fl.Application = fm.stringValue(r, fm.application)
+	}
+
+	if fm.vrfNameIn >= 0 {
+		fl.VRFNameIn = fm.stringValue(r, fm.vrfNameIn)
+	}
+
+	if fm.vrfNameOut >= 0 {
+		fl.VRFNameOut = fm.stringValue(r, fm.vrfNameOut)
+	}
+
+	return fl
+}
+
+// stringValue converts the value at index i into a string according to its Information Element
+func (fm *fieldMap) stringValue(r ipfix.FlowDataRecord, i int) string {
+	return fm.elements[i].String(r.Values[i])
+}
+
+// addrFromBytes converts a reversed IPv4 or IPv6 address field into an IP
+func addrFromBytes(b []byte) bnet.IP {
+	addr, err := bnet.IPFromBytes(convert.Reverse(b))
+	if err != nil {
+		log.WithError(err).Debug("Unable to decode address")
+	}
+
+	return addr
+}
+
+func makePrefix(addr bnet.IP, mask uint8) bnet.Prefix {
+	p := bnet.NewPfx(addr, mask)
+	return bnet.NewPfx(*p.BaseAddr(), mask)
 }
 
 // generateFieldMap processes a TemplateRecord and populates a fieldMap accordingly
-// the FieldMap can then be used to read fields from a flow
-func generateFieldMap(template []*ipfix.TemplateRecord) *fieldMap {
+// the FieldMap can then be used to read fields from a flow. Enterprise-specific
+// fields are looked up in registry.
+func generateFieldMap(template []*ipfix.TemplateRecord, registry *ipfix.Registry) *fieldMap {
 	fm := fieldMap{
 		srcAddr:                -1,
 		dstAddr:                -1,
@@ -320,12 +672,32 @@ func generateFieldMap(template []*ipfix.TemplateRecord) *fieldMap {
 		srcMask6:               -1,
 		dstMask6:               -1,
 		samplingInterval:       -1,
+		flowStartSeconds:       -1,
+		flowEndSeconds:         -1,
+		flowStartMs:            -1,
+		flowEndMs:              -1,
+		flowStartSysUpTime:     -1,
+		flowEndSysUpTime:       -1,
+		systemInitTime:         -1,
+		application:            -1,
+		vrfNameIn:              -1,
+		vrfNameOut:             -1,
+		tcpFlags:               -1,
+		icmpTypeCode:           -1,
+		icmpType:               -1,
+		icmpCode:               -1,
+		elements:               make(map[int]*ipfix.InformationElement),
 	}
 
 	i := -1
 	for _, f := range template {
 		i++
 
+		if ie := registry.Lookup(f); ie != nil {
+			fm.mapElement(i, ie)
+			continue
+		}
+
 		switch f.Type {
 		case ipfix.IPv4SrcAddr:
 			fm.srcAddr = i
@@ -335,8 +707,10 @@ func generateFieldMap(template []*ipfix.TemplateRecord) *fieldMap {
 			fm.family = 6
 		case ipfix.IPv4DstAddr:
 			fm.dstAddr = i
+			fm.family = 4
 		case ipfix.IPv6DstAddr:
 			fm.dstAddr = i
+			fm.family = 6
 		case ipfix.InBytes:
 			fm.size = i
 		case ipfix.Protocol:
@@ -373,21 +747,96 @@ func generateFieldMap(template []*ipfix.TemplateRecord) *fieldMap {
 			fm.dstMask6 = i
 		case ipfix.SamplingInterval:
 			fm.samplingInterval = i
+		case ipfix.FlowStartSeconds:
+			fm.flowStartSeconds = i
+		case ipfix.FlowEndSeconds:
+			fm.flowEndSeconds = i
+		case ipfix.FlowStartMilliseconds:
+			fm.flowStartMs = i
+		case ipfix.FlowEndMilliseconds:
+			fm.flowEndMs = i
+		case ipfix.FirstSwitched:
+			fm.flowStartSysUpTime = i
+		case ipfix.LastSwitched:
+			fm.flowEndSysUpTime = i
+		case ipfix.SystemInitTimeMillis:
+			fm.systemInitTime = i
+		case ipfix.TCPFlags:
+			fm.tcpFlags = i
+		case ipfix.IcmpType, ipfix.IcmpTypeCodeIPv6:
+			fm.icmpTypeCode = i
+		case ipfix.IcmpTypeIPv4, ipfix.IcmpTypeIPv6:
+			fm.icmpType = i
+		case ipfix.IcmpCodeIPv4, ipfix.IcmpCodeIPv6:
+			fm.icmpCode = i
+		case ipfix.ApplicationName:
+			fm.mapElement(i, applicationNameIE)
 		}
 	}
 
 	return &fm
 }
 
+// mapElement maps the Information Element ie at index i onto a flow field
+func (fm *fieldMap) mapElement(i int, ie *ipfix.InformationElement) {
+	switch ie.Name {
+	case applicationNameElement:
+		fm.application = i
+	case ingressVRFNameElement:
+		fm.vrfNameIn = i
+	case egressVRFNameElement:
+		fm.vrfNameOut = i
+	default:
+		return
+	}
+
+	fm.elements[i] = ie
+}
+
+// cachesChanged schedules persisting the template, sample rate and system init time caches
+func (ipf *IPFIXServer) cachesChanged() {
+	if ipf.snapshotter != nil {
+		ipf.snapshotter.markDirty()
+	}
+}
+
 // updateTemplateCache updates the template cache
 func (ipf *IPFIXServer) updateTemplateCache(remote bnet.IP, p *ipfix.Packet) {
+	if len(p.GetTemplateRecords())+len(p.GetOptionTemplateRecords()) > 0 {
+		ipf.cachesChanged()
+	}
+
 	templRecs := p.GetTemplateRecords()
 	for _, tr := range templRecs {
+		if tr.IsWithdrawAll() {
+			ipf.templatesWithdrawn.WithLabelValues(remote.String()).Inc()
+			ipf.tmplCache.withdrawAll(remote, p.Header.DomainID, false)
+			continue
+		}
+
+		if tr.IsWithdrawal() {
+			ipf.templatesWithdrawn.WithLabelValues(remote.String()).Inc()
+			ipf.tmplCache.withdraw(remote, p.Header.DomainID, tr.Header.TemplateID)
+			continue
+		}
+
 		ipf.tmplCache.set(remote, p.Header.DomainID, tr.Header.TemplateID, tr.Records, false)
 	}
 
 	optTemplRecs := p.GetOptionTemplateRecords()
 	for _, tr := range optTemplRecs {
+		if tr.IsWithdrawAll() {
+			ipf.templatesWithdrawn.WithLabelValues(remote.String()).Inc()
+			ipf.tmplCache.withdrawAll(remote, p.Header.DomainID, true)
+			continue
+		}
+
+		if tr.IsWithdrawal() {
+			ipf.templatesWithdrawn.WithLabelValues(remote.String()).Inc()
+			ipf.tmplCache.withdraw(remote, p.Header.DomainID, tr.Header.TemplateID)
+			continue
+		}
+
 		ipf.tmplCache.set(remote, p.Header.DomainID, tr.Header.TemplateID, tr.Records, true)
 	}
 }
@@ -0,0 +1,284 @@
+package ipfix
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/bio-routing/flowhouse/pkg/servers/sequence"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type mockResolver struct{}
+
+func (m *mockResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	return fmt.Sprintf("if%d", ifID)
+}
+
+func TestRecordToFlowIPv6(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x8b, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x30, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x0a, // Field count
+		0x00, 0x1b, 0x00, 0x10, // IPv6SrcAddr
+		0x00, 0x1c, 0x00, 0x10, // IPv6DstAddr
+		0x00, 0x3e, 0x00, 0x10, // IPv6NextHop
+		0x00, 0x1d, 0x00, 0x01, // IPv6SrcMask
+		0x00, 0x1e, 0x00, 0x01, // IPv6DstMask
+		0x00, 0x04, 0x00, 0x01, // Protocol
+		0x00, 0x07, 0x00, 0x02, // L4SrcPort
+		0x00, 0x0b, 0x00, 0x02, // L4DstPort
+		0x00, 0x01, 0x00, 0x08, // InBytes
+		0x00, 0x02, 0x00, 0x08, // InPkts
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x4b, // FlowSet length
+		0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // 2001:db8::1
+		0x20, 0x01, 0x0d, 0xb8, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, // 2001:db8:1::2
+		0xfe, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // fe80::1
+		0x30,       // IPv6SrcMask
+		0x40,       // IPv6DstMask
+		0x06,       // Protocol
+		0x01, 0xbb, // L4SrcPort
+		0xc3, 0x50, // L4DstPort
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0xdc, // InBytes
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, // InPkts
+	}
+
+	p, err := ipfix.Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	template := p.Templates[0].Records
+	records := ipfix.DecodeFlowSet(*p.FlowSets[0], template)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	ipf := &IPFIXServer{
+		ifResolver: &mockResolver{},
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	fl := ipf.recordToFlow(generateFieldMap(template, ipfix.NewRegistry()), records[0], agent, int64(p.Header.ExportTime))
+
+	assert.Equal(t, uint8(6), fl.Family)
+	assert.Equal(t, "2001:DB8:0:0:0:0:0:1", fl.SrcAddr.String())
+	assert.Equal(t, "2001:DB8:1:0:0:0:0:2", fl.DstAddr.String())
+	assert.Equal(t, "FE80:0:0:0:0:0:0:1", fl.NextHop.String())
+	assert.Equal(t, "2001:DB8:0:0:0:0:0:0/48", fl.SrcPfx.String())
+	assert.Equal(t, "2001:DB8:1:0:0:0:0:0/64", fl.DstPfx.String())
+	assert.Equal(t, uint8(6), fl.Protocol)
+	assert.Equal(t, uint16(443), fl.SrcPort)
+	assert.Equal(t, uint16(50000), fl.DstPort)
+	assert.Equal(t, uint64(1500), fl.Size)
+	assert.Equal(t, uint64(1), fl.Packets)
+}
+
+func TestRecordToFlowEnterpriseElements(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x47, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x20, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x04, // Field count
+		0x00, 0x08, 0x00, 0x04, // IPv4SrcAddr
+		0x80, 0x01, 0xff, 0xff, // Enterprise element 1, variable length
+		0x00, 0x00, 0x0a, 0x4c, // Enterprise number 2636
+		0x80, 0x02, 0x00, 0x04, // Enterprise element 2, not registered
+		0x00, 0x00, 0x0a, 0x4c, // Enterprise number 2636
+		0x00, 0x60, 0xff, 0xff, // ApplicationName, variable length
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x17, // FlowSet length
+		0x0a, 0x00, 0x00, 0x01, // 10.0.0.1
+		0x04, 'b', 'l', 'u', 'e', // VRF name
+		0x00, 0x00, 0x00, 0x00, // Unknown element
+		0xff, 0x00, 0x03, 'd', 'n', 's', // Application name
+	}
+
+	p, err := ipfix.Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	template := p.Templates[0].Records
+	records := ipfix.DecodeFlowSet(*p.FlowSets[0], template)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	registry, err := newRegistry([]*EnterpriseElement{
+		{
+			EnterpriseNumber: 2636,
+			ElementID:        1,
+			Name:             "ingressVRFName",
+			Type:             "string",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	ipf := &IPFIXServer{
+		ifResolver: &mockResolver{},
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	fl := ipf.recordToFlow(generateFieldMap(template, registry), records[0], agent, int64(p.Header.ExportTime))
+
+	assert.Equal(t, "10.0.0.1", fl.SrcAddr.String())
+	assert.Equal(t, "blue", fl.VRFNameIn)
+	assert.Equal(t, "", fl.VRFNameOut)
+	assert.Equal(t, "dns", fl.Application)
+}
+
+func TestRecordToFlowTCPFlagsICMP(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x3d, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x1c, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x05, // Field count
+		0x00, 0x08, 0x00, 0x04, // IPv4SrcAddr
+		0x00, 0x0c, 0x00, 0x04, // IPv4DstAddr
+		0x00, 0x04, 0x00, 0x01, // Protocol
+		0x00, 0x06, 0x00, 0x02, // TCPFlags
+		0x00, 0x20, 0x00, 0x02, // IcmpType (icmpTypeCodeIPv4)
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x11, // FlowSet length
+		192, 0, 2, 1, // IPv4SrcAddr
+		198, 51, 100, 2, // IPv4DstAddr
+		0x01,       // Protocol
+		0x00, 0x12, // TCPFlags (SYN, ACK)
+		0x03, 0x0d, // ICMP type 3, code 13
+	}
+
+	p, err := ipfix.Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	template := p.Templates[0].Records
+	records := ipfix.DecodeFlowSet(*p.FlowSets[0], template)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	ipf := &IPFIXServer{
+		ifResolver: &mockResolver{},
+	}
+
+	fl := ipf.recordToFlow(generateFieldMap(template, ipfix.NewRegistry()), records[0], bnet.IPv4FromOctets(192, 0, 2, 1), 0)
+	assert.Equal(t, uint8(1), fl.Protocol)
+	assert.Equal(t, uint8(0x12), fl.TCPFlags)
+	assert.Equal(t, uint8(3), fl.ICMPType)
+	assert.Equal(t, uint8(13), fl.ICMPCode)
+}
+
+func TestRecordToFlowEmptyVariableLengthFields(t *testing.T) {
+	input := []byte{
+		0x00, 0x0a, // Version
+		0x00, 0x33, // Length
+		0x68, 0x3d, 0x5a, 0xc1, // Timestamp
+		0x00, 0x00, 0x00, 0x01, // FlowSequence
+		0x00, 0x00, 0x00, 0x01, // Observation Domain ID
+		0x00, 0x02, // FlowSet ID = 2 = template
+		0x00, 0x18, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x04, // Field count
+		0x00, 0x08, 0x00, 0x04, // IPv4SrcAddr
+		0x00, 0x05, 0xff, 0xff, // SrcTos, variable length
+		0x00, 0x09, 0xff, 0xff, // SrcMask, variable length
+		0x00, 0x0d, 0xff, 0xff, // DstMask, variable length
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x0b, // FlowSet length
+		192, 0, 2, 1, // IPv4SrcAddr
+		0x00, // SrcTos
+		0x00, // SrcMask
+		0x00, // DstMask
+	}
+
+	p, err := ipfix.Decode(input)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	template := p.Templates[0].Records
+	records := ipfix.DecodeFlowSet(*p.FlowSets[0], template)
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+
+	ipf := &IPFIXServer{
+		ifResolver: &mockResolver{},
+	}
+
+	fl := ipf.recordToFlow(generateFieldMap(template, ipfix.NewRegistry()), records[0], bnet.IPv4FromOctets(192, 0, 2, 1), 0)
+	assert.Equal(t, "192.0.2.1", fl.SrcAddr.String())
+	assert.Equal(t, uint8(0), fl.TOS)
+	assert.Equal(t, bnet.Prefix{}, fl.SrcPfx)
+}
+
+func TestTrackSequence(t *testing.T) {
+	ipf := &IPFIXServer{
+		sequences:   sequence.New(maxSequenceRewind, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
+		lostRecords: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, labels),
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	now := time.Unix(1700000000, 0)
+	sets := func(n int) []*decodedFlowSet {
+		return []*decodedFlowSet{
+			{
+				records: make([]ipfix.FlowDataRecord, n),
+			},
+		}
+	}
+
+	messages := []struct {
+		sequenceNumber uint32
+		records        int
+		complete       bool
+		offset         time.Duration
+		expected       float32
+	}{
+		{sequenceNumber: 100, records: 10, complete: true},
+		{sequenceNumber: 110, records: 10, complete: true},
+		{sequenceNumber: 140, records: 10, complete: true},
+		{sequenceNumber: 150, records: 10, complete: true, offset: 2 * time.Second, expected: float32(20) / float32(60)},
+		{sequenceNumber: 160, records: 0, complete: false},
+		{sequenceNumber: 200, records: 10, complete: true, offset: 3 * time.Second},
+		{sequenceNumber: 210, records: 10, complete: true, offset: 4 * time.Second},
+	}
+
+	for i, m := range messages {
+		hdr := &ipfix.Header{
+			DomainID:       1,
+			SequenceNumber: m.sequenceNumber,
+		}
+
+		lossRatio := ipf.trackSequence(agent, hdr, sets(m.records), m.complete, now.Add(m.offset))
+		assert.Equalf(t, m.expected, lossRatio, "message %d", i)
+	}
+
+	assert.Equal(t, float64(20), testutil.ToFloat64(ipf.lostRecords.WithLabelValues("192.0.2.1")))
+}
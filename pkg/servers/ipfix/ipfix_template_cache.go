@@ -2,6 +2,7 @@ package ipfix
 
 import (
 	"sync"
+	"time"
 
 	bnet "github.com/bio-routing/bio-rd/net"
 	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
@@ -24,25 +25,37 @@ func newTemplateCacheKey(agent bnet.IP, observationDomain uint32, templateID uin
 type templateCache struct {
 	cache map[templateCacheKey]*templateCacheEntry
 	lock  sync.RWMutex
+
+	// timeout is the time after which templates that have not been refreshed
+	// are considered invalid. A timeout of 0 disables expiry.
+	timeout time.Duration
 }
 
 type templateCacheEntry struct {
 	isOptionsTemplate bool
 	records           []*ipfix.TemplateRecord
+	updated           time.Time
 }
 
 // newTemplateCache creates and initializes a new `templateCache` instance
-func newTemplateCache() *templateCache {
+func newTemplateCache(timeout time.Duration) *templateCache {
 	return &templateCache{
-		cache: make(map[templateCacheKey]*templateCacheEntry),
+		cache:   make(map[templateCacheKey]*templateCacheEntry),
+		timeout: timeout,
 	}
 }
 
 func (c *templateCache) set(rtr bnet.IP, domainID uint32, templateID uint16, records []*ipfix.TemplateRecord, opts bool) {
+	c.restore(rtr, domainID, templateID, records, opts, time.Now())
+}
+
+// restore adds a template that has last been refreshed at `updated`
+func (c *templateCache) restore(rtr bnet.IP, domainID uint32, templateID uint16, records []*ipfix.TemplateRecord, opts bool, updated time.Time) {
 	k := newTemplateCacheKey(rtr, domainID, templateID)
 	v := &templateCacheEntry{
 		isOptionsTemplate: opts,
 		records:           records,
+		updated:           updated,
 	}
 
 	c.lock.Lock()
@@ -58,9 +71,81 @@ func (c *templateCache) get(rtr bnet.IP, domainID uint32, templateID uint16) ([]
 	defer c.lock.RUnlock()
 
 	e, found := c.cache[k]
-	if !found {
+	if !found || c.isExpired(e, time.Now()) {
 		return nil, false
 	}
 
 	return e.records, e.isOptionsTemplate
 }
+
+// withdraw removes a single template
+func (c *templateCache) withdraw(rtr bnet.IP, domainID uint32, templateID uint16) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.cache, newTemplateCacheKey(rtr, domainID, templateID))
+}
+
+// withdrawAll removes all (options) templates of an observation domain
+func (c *templateCache) withdrawAll(rtr bnet.IP, domainID uint32, opts bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for k, e := range c.cache {
+		if k.agent == rtr && k.observationDomain == domainID && e.isOptionsTemplate == opts {
+			delete(c.cache, k)
+		}
+	}
+}
+
+// flush removes all templates of an observation domain
+func (c *templateCache) flush(rtr bnet.IP, domainID uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for k := range c.cache {
+		if k.agent == rtr && k.observationDomain == domainID {
+			delete(c.cache, k)
+		}
+	}
+}
+
+// expire removes all templates that have timed out at `now` and returns the number of removed templates
+func (c *templateCache) expire(now time.Time) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	n := 0
+	for k, e := range c.cache {
+		if c.isExpired(e, now) {
+			delete(c.cache, k)
+			n++
+		}
+	}
+
+	return n
+}
+
+func (c *templateCache) isExpired(e *templateCacheEntry, now time.Time) bool {
+	return c.timeout != 0 && now.Sub(e.updated) > c.timeout
+}
+
+// dump gets all templates for persisting them
+func (c *templateCache) dump() []*templateSnapshot {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	res := make([]*templateSnapshot, 0, len(c.cache))
+	for k, e := range c.cache {
+		res = append(res, &templateSnapshot{
+			Agent:             k.agent.String(),
+			ObservationDomain: k.observationDomain,
+			TemplateID:        k.templateID,
+			IsOptionsTemplate: e.isOptionsTemplate,
+			Records:           e.records,
+			Updated:           e.updated,
+		})
+	}
+
+	return res
+}
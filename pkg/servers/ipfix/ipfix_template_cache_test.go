@@ -0,0 +1,70 @@
+package ipfix
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestTemplateCacheWithdraw(t *testing.T) {
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	records := []*ipfix.TemplateRecord{
+		{
+			Type:   ipfix.IPv4SrcAddr,
+			Length: 4,
+		},
+	}
+
+	c := newTemplateCache(0)
+	c.set(agent, 1, 256, records, false)
+	c.set(agent, 1, 257, records, false)
+	c.set(agent, 1, 512, records, true)
+	c.set(agent, 2, 256, records, false)
+
+	c.withdraw(agent, 1, 256)
+	tmpl, _ := c.get(agent, 1, 256)
+	assert.Nil(t, tmpl)
+
+	tmpl, _ = c.get(agent, 1, 257)
+	assert.Equal(t, records, tmpl)
+
+	c.withdrawAll(agent, 1, false)
+	tmpl, _ = c.get(agent, 1, 257)
+	assert.Nil(t, tmpl)
+
+	tmpl, opts := c.get(agent, 1, 512)
+	assert.Equal(t, records, tmpl)
+	assert.True(t, opts)
+
+	c.flush(agent, 1)
+	tmpl, _ = c.get(agent, 1, 512)
+	assert.Nil(t, tmpl)
+
+	tmpl, _ = c.get(agent, 2, 256)
+	assert.Equal(t, records, tmpl)
+}
+
+func TestTemplateCacheExpire(t *testing.T) {
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	records := []*ipfix.TemplateRecord{
+		{
+			Type:   ipfix.IPv4SrcAddr,
+			Length: 4,
+		},
+	}
+
+	c := newTemplateCache(time.Minute)
+	c.set(agent, 1, 256, records, false)
+
+	assert.Equal(t, 0, c.expire(time.Now()))
+	tmpl, _ := c.get(agent, 1, 256)
+	assert.Equal(t, records, tmpl)
+
+	assert.Equal(t, 1, c.expire(time.Now().Add(2*time.Minute)))
+	tmpl, _ = c.get(agent, 1, 256)
+	assert.Nil(t, tmpl)
+}
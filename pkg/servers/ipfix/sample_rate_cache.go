@@ -42,3 +42,20 @@ func (src *sampleRateCache) set(agent bnet.IP, observationDomainID uint32, rate
 
 	src.data[newSampleRateCacheKey(agent, observationDomainID)] = rate
 }
+
+// dump gets all sample rates for persisting them
+func (src *sampleRateCache) dump() []*sampleRateSnapshot {
+	src.dataMu.RLock()
+	defer src.dataMu.RUnlock()
+
+	res := make([]*sampleRateSnapshot, 0, len(src.data))
+	for k, rate := range src.data {
+		res = append(res, &sampleRateSnapshot{
+			Agent:             k.agent.String(),
+			ObservationDomain: k.observationDomainID,
+			SampleRate:        rate,
+		})
+	}
+
+	return res
+}
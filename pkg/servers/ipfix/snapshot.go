@@ -0,0 +1,196 @@
+package ipfix
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshotInterval is the maximum frequency the snapshot file is written at
+const snapshotInterval = 10 * time.Second
+
+// snapshot is the on disk representation of the template, sample rate and system init time caches
+type snapshot struct {
+	Created         time.Time                 `json:"created"`
+	Templates       []*templateSnapshot       `json:"templates"`
+	SampleRates     []*sampleRateSnapshot     `json:"sample_rates"`
+	SystemInitTimes []*systemInitTimeSnapshot `json:"system_init_times"`
+}
+
+type templateSnapshot struct {
+	Agent             string                  `json:"agent"`
+	ObservationDomain uint32                  `json:"observation_domain"`
+	TemplateID        uint16                  `json:"template_id"`
+	IsOptionsTemplate bool                    `json:"is_options_template"`
+	Records           []*ipfix.TemplateRecord `json:"records"`
+	Updated           time.Time               `json:"updated"`
+}
+
+type sampleRateSnapshot struct {
+	Agent             string `json:"agent"`
+	ObservationDomain uint32 `json:"observation_domain"`
+	SampleRate        uint32 `json:"sample_rate"`
+}
+
+type systemInitTimeSnapshot struct {
+	Agent             string `json:"agent"`
+	ObservationDomain uint32 `json:"observation_domain"`
+	InitTime          uint64 `json:"init_time"`
+}
+
+// snapshotter persists the template, sample rate and system init time caches
+// to a file so decoding can resume immediately after a restart
+type snapshotter struct {
+	path            string
+	maxAge          time.Duration
+	tmplCache       *templateCache
+	sampleRateCache *sampleRateCache
+	sysInitCache    *systemInitTimeCache
+	dirty           int32
+}
+
+func newSnapshotter(path string, maxAge time.Duration, tmplCache *templateCache, sampleRateCache *sampleRateCache, sysInitCache *systemInitTimeCache) *snapshotter {
+	return &snapshotter{
+		path:            path,
+		maxAge:          maxAge,
+		tmplCache:       tmplCache,
+		sampleRateCache: sampleRateCache,
+		sysInitCache:    sysInitCache,
+	}
+}
+
+// markDirty schedules writing the snapshot file
+func (s *snapshotter) markDirty() {
+	atomic.StoreInt32(&s.dirty, 1)
+}
+
+// run writes the snapshot file whenever the caches changed until stopCh is closed
+func (s *snapshotter) run(stopCh chan struct{}) {
+	t := time.NewTicker(snapshotInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			if atomic.SwapInt32(&s.dirty, 0) == 0 {
+				continue
+			}
+
+			err := s.save(time.Now())
+			if err != nil {
+				log.WithError(err).Error("Unable to write IPFIX cache snapshot")
+			}
+		}
+	}
+}
+
+// save writes the snapshot file. The file is replaced atomically.
+func (s *snapshotter) save(now time.Time) error {
+	b, err := json.Marshal(&snapshot{
+		Created:         now,
+		Templates:       s.tmplCache.dump(),
+		SampleRates:     s.sampleRateCache.dump(),
+		SystemInitTimes: s.sysInitCache.dump(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Unable to marshal snapshot")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return errors.Wrap(err, "Unable to create temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(b)
+	if err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "Write failed")
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrap(err, "Close failed")
+	}
+
+	err = os.Rename(tmp.Name(), s.path)
+	if err != nil {
+		return errors.Wrap(err, "Rename failed")
+	}
+
+	return nil
+}
+
+// load restores the caches from the snapshot file. Entries older than maxAge are ignored.
+// A missing snapshot file is not considered an error.
+func (s *snapshotter) load(now time.Time) error {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return errors.Wrap(err, "Unable to read file")
+	}
+
+	snap := &snapshot{}
+	err = json.Unmarshal(b, snap)
+	if err != nil {
+		return errors.Wrap(err, "Unable to unmarshal snapshot")
+	}
+
+	if s.isStale(snap.Created, now) {
+		log.Infof("Ignoring stale IPFIX cache snapshot from %s", snap.Created)
+		return nil
+	}
+
+	n := 0
+	for _, t := range snap.Templates {
+		if s.isStale(t.Updated, now) {
+			continue
+		}
+
+		agent, err := bnet.IPFromString(t.Agent)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid agent address %q", t.Agent)
+		}
+
+		s.tmplCache.restore(agent, t.ObservationDomain, t.TemplateID, t.Records, t.IsOptionsTemplate, t.Updated)
+		n++
+	}
+
+	for _, sr := range snap.SampleRates {
+		agent, err := bnet.IPFromString(sr.Agent)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid agent address %q", sr.Agent)
+		}
+
+		s.sampleRateCache.set(agent, sr.ObservationDomain, sr.SampleRate)
+	}
+
+	for _, sit := range snap.SystemInitTimes {
+		agent, err := bnet.IPFromString(sit.Agent)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid agent address %q", sit.Agent)
+		}
+
+		s.sysInitCache.set(agent, sit.ObservationDomain, sit.InitTime)
+	}
+
+	log.Infof("Restored %d IPFIX templates, %d sample rates and %d system init times from %s", n, len(snap.SampleRates), len(snap.SystemInitTimes), s.path)
+	return nil
+}
+
+func (s *snapshotter) isStale(t time.Time, now time.Time) bool {
+	return s.maxAge != 0 && now.Sub(t) > s.maxAge
+}
@@ -0,0 +1,83 @@
+package ipfix
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/ipfix"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestSnapshotSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	agent4 := bnet.IPv4FromOctets(192, 0, 2, 1)
+	agent6, _ := bnet.IPFromString("2001:db8::1")
+	records := []*ipfix.TemplateRecord{
+		{
+			Type:   ipfix.IPv4SrcAddr,
+			Length: 4,
+		},
+		{
+			Type:             0x8001,
+			Length:           ipfix.VariableLength,
+			EnterpriseNumber: 2636,
+		},
+	}
+
+	now := time.Now()
+	tc := newTemplateCache(0)
+	tc.restore(agent4, 1, 256, records, false, now)
+	tc.restore(agent6, 2, 512, records, true, now)
+	tc.restore(agent4, 1, 257, records, false, now.Add(-2*time.Hour))
+	src := newSampleRateCache()
+	src.set(agent4, 1, 1000)
+	sitc := newSystemInitTimeCache()
+	sitc.set(agent6, 2, 1600000000000)
+
+	err := newSnapshotter(path, time.Hour, tc, src, sitc).save(now)
+	if err != nil {
+		t.Fatalf("unable to save snapshot: %v", err)
+	}
+
+	tcRestored := newTemplateCache(0)
+	srcRestored := newSampleRateCache()
+	sitcRestored := newSystemInitTimeCache()
+	err = newSnapshotter(path, time.Hour, tcRestored, srcRestored, sitcRestored).load(now)
+	if err != nil {
+		t.Fatalf("unable to load snapshot: %v", err)
+	}
+
+	tmpl, opts := tcRestored.get(agent4, 1, 256)
+	assert.Equal(t, records, tmpl)
+	assert.False(t, opts)
+
+	tmpl, opts = tcRestored.get(agent6, 2, 512)
+	assert.Equal(t, records, tmpl)
+	assert.True(t, opts)
+
+	tmpl, _ = tcRestored.get(agent4, 1, 257)
+	assert.Nil(t, tmpl, "stale template")
+
+	assert.Equal(t, uint32(1000), srcRestored.get(agent4, 1))
+
+	initTime, found := sitcRestored.get(agent6, 2)
+	assert.True(t, found)
+	assert.Equal(t, uint64(1600000000000), initTime)
+
+	tcStale := newTemplateCache(0)
+	err = newSnapshotter(path, time.Hour, tcStale, newSampleRateCache(), newSystemInitTimeCache()).load(now.Add(2 * time.Hour))
+	if err != nil {
+		t.Fatalf("unable to load snapshot: %v", err)
+	}
+
+	tmpl, _ = tcStale.get(agent4, 1, 256)
+	assert.Nil(t, tmpl, "stale snapshot")
+}
+
+func TestSnapshotLoadMissingFile(t *testing.T) {
+	s := newSnapshotter("/nonexistent/snapshot.json", time.Hour, newTemplateCache(0), newSampleRateCache(), newSystemInitTimeCache())
+	assert.NoError(t, s.load(time.Now()))
+}
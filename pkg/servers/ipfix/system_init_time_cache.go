@@ -0,0 +1,53 @@
+package ipfix
+
+import (
+	"sync"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// systemInitTimeCache keeps the boot time (unix time in milliseconds) exporters
+// announce in options data records. It is required to convert flowStartSysUpTime
+// and flowEndSysUpTime into absolute timestamps.
+type systemInitTimeCache struct {
+	data   map[sampleRateCacheKey]uint64
+	dataMu sync.RWMutex
+}
+
+func newSystemInitTimeCache() *systemInitTimeCache {
+	return &systemInitTimeCache{
+		data: make(map[sampleRateCacheKey]uint64),
+	}
+}
+
+func (sitc *systemInitTimeCache) get(agent bnet.IP, observationDomainID uint32) (uint64, bool) {
+	sitc.dataMu.RLock()
+	defer sitc.dataMu.RUnlock()
+
+	t, found := sitc.data[newSampleRateCacheKey(agent, observationDomainID)]
+	return t, found
+}
+
+func (sitc *systemInitTimeCache) set(agent bnet.IP, observationDomainID uint32, initTime uint64) {
+	sitc.dataMu.Lock()
+	defer sitc.dataMu.Unlock()
+
+	sitc.data[newSampleRateCacheKey(agent, observationDomainID)] = initTime
+}
+
+// dump gets all system init times for persisting them
+func (sitc *systemInitTimeCache) dump() []*systemInitTimeSnapshot {
+	sitc.dataMu.RLock()
+	defer sitc.dataMu.RUnlock()
+
+	res := make([]*systemInitTimeSnapshot, 0, len(sitc.data))
+	for k, t := range sitc.data {
+		res = append(res, &systemInitTimeSnapshot{
+			Agent:             k.agent.String(),
+			ObservationDomain: k.observationDomainID,
+			InitTime:          t,
+		})
+	}
+
+	return res
+}
@@ -0,0 +1,166 @@
+package nf5
+
+import (
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/nf5"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/flowhouse/pkg/servers/udpreceiver"
+	"github.com/bio-routing/tflow2/convert"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type InterfaceResolver interface {
+	Resolve(agent bnet.IP, ifID uint32) string
+}
+
+// Config is the configuration of a `NetflowV5Server`
+type Config struct {
+	// AgentFilter restricts the agents datagrams are accepted from. nil accepts all agents.
+	AgentFilter *agentfilter.Filter
+
+	// Receiver configures the UDP sockets
+	Receiver udpreceiver.Config
+
+	// Aggregator configures the aggregation of flows
+	Aggregator aggregator.Config
+}
+
+// NetflowV5Server represents a NetFlow v5 collector instance
+type NetflowV5Server struct {
+	cfg             *Config
+	receiver        *udpreceiver.Receiver
+	ifResolver      InterfaceResolver
+	aggregator      *aggregator.Aggregator
+	rejectedPackets *prometheus.CounterVec
+}
+
+// New creates and starts a new `NetflowV5Server` instance
+func New(listen string, numSockets int, output chan []*flow.Flow, ifResolver InterfaceResolver, cfg *Config) (*NetflowV5Server, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	agg, err := aggregator.New(output, cfg.Aggregator)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create aggregator")
+	}
+
+	nfs := &NetflowV5Server{
+		cfg:        cfg,
+		ifResolver: ifResolver,
+		aggregator: agg,
+		rejectedPackets: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "netflow_v5",
+			Name:      "rejected_packets",
+			Help:      "Packets rejected due to unknown agent or rate limiting. Unknown agents are not labelled.",
+		}, []string{"agent", "reason"}),
+	}
+
+	r, err := udpreceiver.New(listen, numSockets, cfg.Receiver, nfs.handlePacket, promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "netflow_v5",
+		Name:      "socket_drops",
+		Help:      "Datagrams dropped by the kernel due to full socket receive buffers",
+	}))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create UDP receiver")
+	}
+	nfs.receiver = r
+
+	nfs.receiver.Start()
+	return nfs, nil
+}
+
+// Stop closes the sockets and stops the workers
+func (nfs *NetflowV5Server) Stop() {
+	log.Info("Stopping NetFlow v5 server")
+	nfs.receiver.Stop()
+	nfs.aggregator.Stop()
+}
+
+// handlePacket hands off a datagram received from agent to processPacket
+func (nfs *NetflowV5Server) handlePacket(agent bnet.IP, buffer []byte) {
+	if !nfs.accept(agent, time.Now()) {
+		return
+	}
+
+	nfs.processPacket(agent, buffer)
+}
+
+// accept checks if the agent filter accepts a datagram of agent
+func (nfs *NetflowV5Server) accept(agent bnet.IP, now time.Time) bool {
+	if nfs.cfg.AgentFilter == nil {
+		return true
+	}
+
+	ok, reason := nfs.cfg.AgentFilter.Check(agent, now)
+	if !ok {
+		nfs.rejectedPackets.WithLabelValues(nfs.cfg.AgentFilter.AgentLabel(agent), reason).Inc()
+	}
+
+	return ok
+}
+
+func (nfs *NetflowV5Server) processPacket(agent bnet.IP, buffer []byte) {
+	pkt, err := nf5.Decode(buffer)
+	if err != nil {
+		log.WithError(err).Error("Unable to decode NetFlow v5 packet")
+		return
+	}
+
+	for _, r := range pkt.Records {
+		fl := nfs.recordToFlow(agent, pkt.Header, r)
+		for _, part := range nfs.aggregator.SplitFlow(fl, switchedTime(pkt.Header, r.First), switchedTime(pkt.Header, r.Last)) {
+			nfs.aggregator.Ingest(part)
+		}
+	}
+}
+
+// switchedTime converts a First/Last sysUpTime value into a unix timestamp in milliseconds.
+// The offset to the export time is computed modulo 2^32 so the sysUpTime wrap after ~49.7 days is handled.
+func switchedTime(hdr *nf5.Header, switched uint32) int64 {
+	return int64(hdr.UnixSecs)*1000 + int64(int32(switched-hdr.SysUpTime))
+}
+
+// recordToFlow converts a NetFlow v5 record into a flow
+func (nfs *NetflowV5Server) recordToFlow(agent bnet.IP, hdr *nf5.Header, r *nf5.FlowRecord) *flow.Flow {
+	fl := &flow.Flow{
+		Agent:      agent,
+		Family:     4,
+		Timestamp:  int64(hdr.UnixSecs),
+		Samplerate: uint64(hdr.SampleRate()),
+		SrcAddr:    bnet.IPv4FromBytes(convert.Reverse(r.SrcAddr[:])),
+		DstAddr:    bnet.IPv4FromBytes(convert.Reverse(r.DstAddr[:])),
+		NextHop:    bnet.IPv4FromBytes(convert.Reverse(r.NextHop[:])),
+		IntIn:      nfs.ifResolver.Resolve(agent, uint32(r.Input)),
+		IntOut:     nfs.ifResolver.Resolve(agent, uint32(r.Output)),
+		Packets:    uint64(r.DPkts),
+		Size:       uint64(r.DOctets),
+		Protocol:   r.Protocol,
+		TCPFlags:   r.TCPFlags,
+		TOS:        r.TOS,
+		SrcPort:    r.SrcPort,
+		DstPort:    r.DstPort,
+		SrcAs:      uint32(r.SrcAs),
+		DstAs:      uint32(r.DstAs),
+	}
+
+	// A sampling interval of 0 is kept as an unknown sample rate, the same way NetFlow v9
+	// and IPFIX flows without sampling options are stored
+	srcPfx := bnet.NewPfx(fl.SrcAddr, r.SrcMask)
+	fl.SrcPfx = bnet.NewPfx(*srcPfx.BaseAddr(), r.SrcMask)
+
+	dstPfx := bnet.NewPfx(fl.DstAddr, r.DstMask)
+	fl.DstPfx = bnet.NewPfx(*dstPfx.BaseAddr(), r.DstMask)
+
+	return fl
+}
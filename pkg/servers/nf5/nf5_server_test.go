@@ -0,0 +1,97 @@
+package nf5
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/bio-routing/flowhouse/pkg/packet/nf5"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type mockResolver struct{}
+
+func (m *mockResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	return fmt.Sprintf("if%d", ifID)
+}
+
+func TestRecordToFlow(t *testing.T) {
+	nfs := &NetflowV5Server{
+		ifResolver: &mockResolver{},
+	}
+
+	hdr := &nf5.Header{
+		Version:   5,
+		UnixSecs:  1748851393,
+		Count:     1,
+		SysUpTime: 10000,
+	}
+
+	r := &nf5.FlowRecord{
+		SrcAddr:  [4]byte{1, 2, 0, 192},
+		DstAddr:  [4]byte{2, 100, 51, 198},
+		NextHop:  [4]byte{3, 113, 0, 203},
+		Input:    10,
+		Output:   20,
+		DPkts:    3,
+		DOctets:  1500,
+		SrcPort:  50000,
+		DstPort:  443,
+		Protocol: 6,
+		TCPFlags: 0x12,
+		TOS:      0xb8,
+		SrcAs:    65000,
+		DstAs:    65001,
+		SrcMask:  24,
+		DstMask:  16,
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 254)
+	fl := nfs.recordToFlow(agent, hdr, r)
+
+	assert.Equal(t, "192.0.2.1", fl.SrcAddr.String())
+	assert.Equal(t, "198.51.100.2", fl.DstAddr.String())
+	assert.Equal(t, "203.0.113.3", fl.NextHop.String())
+	assert.Equal(t, "192.0.2.0/24", fl.SrcPfx.String())
+	assert.Equal(t, "198.51.0.0/16", fl.DstPfx.String())
+	assert.Equal(t, "if10", fl.IntIn)
+	assert.Equal(t, "if20", fl.IntOut)
+	assert.Equal(t, uint64(3), fl.Packets)
+	assert.Equal(t, uint64(1500), fl.Size)
+	assert.Equal(t, uint8(0x12), fl.TCPFlags)
+	assert.Equal(t, uint64(0), fl.Samplerate)
+	assert.Equal(t, uint8(4), fl.Family)
+	assert.Equal(t, int64(1748851393), fl.Timestamp)
+}
+
+func TestSwitchedTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		sysUpTime uint32
+		switched  uint32
+		expected  int64
+	}{
+		{
+			name:      "Before export",
+			sysUpTime: 10000,
+			switched:  4000,
+			expected:  1748851393000 - 6000,
+		},
+		{
+			name:      "sysUpTime wrapped after the flow started",
+			sysUpTime: 1000,
+			switched:  0xffffe000,
+			expected:  1748851393000 - 9192,
+		},
+	}
+
+	for _, test := range tests {
+		hdr := &nf5.Header{
+			UnixSecs:  1748851393,
+			SysUpTime: test.sysUpTime,
+		}
+
+		assert.Equal(t, test.expected, switchedTime(hdr, test.switched), test.name)
+	}
+}
@@ -0,0 +1,433 @@
+package nf9
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/nf9"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/flowhouse/pkg/servers/udpreceiver"
+	"github.com/bio-routing/tflow2/convert"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type InterfaceResolver interface {
+	Resolve(agent bnet.IP, ifID uint32) string
+}
+
+// fieldMap describes what information is at what index in the slice
+// that we get from decoding a netflow packet
+type fieldMap struct {
+	srcAddr               int
+	dstAddr               int
+	protocol              int
+	packets               int
+	size                  int
+	intIn                 int
+	intOut                int
+	nextHop               int
+	family                int
+	srcAsn                int
+	dstAsn                int
+	srcPort               int
+	dstPort               int
+	srcTos                int
+	srcMask               int
+	dstMask               int
+	srcMask6              int
+	dstMask6              int
+	samplingInterval      int
+	samplerRandomInterval int
+	firstSwitched         int
+	lastSwitched          int
+}
+
+// Config is the configuration of a `NetflowV9Server`
+type Config struct {
+	// AgentFilter restricts the agents datagrams are accepted from. nil accepts all agents.
+	AgentFilter *agentfilter.Filter
+
+	// Receiver configures the UDP sockets
+	Receiver udpreceiver.Config
+
+	// Aggregator configures the aggregation of flows
+	Aggregator aggregator.Config
+}
+
+// NetflowV9Server represents a NetFlow v9 collector instance
+type NetflowV9Server struct {
+	cfg *Config
+
+	// tmplCache is used to save received flow templates
+	// for later lookup in order to decode netflow packets
+	tmplCache       *templateCache
+	receiver        *udpreceiver.Receiver
+	ifResolver      InterfaceResolver
+	output          chan []*flow.Flow
+	aggregator      *aggregator.Aggregator
+	sampleRateCache *sampleRateCache
+	rejectedPackets *prometheus.CounterVec
+}
+
+// New creates and starts a new `NetflowV9Server` instance
+func New(listen string, numSockets int, output chan []*flow.Flow, ifResolver InterfaceResolver, cfg *Config) (*NetflowV9Server, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+
+	agg, err := aggregator.New(output, cfg.Aggregator)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create aggregator")
+	}
+
+	nfs := &NetflowV9Server{
+		cfg:             cfg,
+		tmplCache:       newTemplateCache(),
+		ifResolver:      ifResolver,
+		output:          output,
+		aggregator:      agg,
+		sampleRateCache: newSampleRateCache(),
+		rejectedPackets: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "netflow_v9",
+			Name:      "rejected_packets",
+			Help:      "Packets rejected due to unknown agent or rate limiting. Unknown agents are not labelled.",
+		}, []string{"agent", "reason"}),
+	}
+
+	r, err := udpreceiver.New(listen, numSockets, cfg.Receiver, nfs.handlePacket, promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "netflow_v9",
+		Name:      "socket_drops",
+		Help:      "Datagrams dropped by the kernel due to full socket receive buffers",
+	}))
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create UDP receiver")
+	}
+	nfs.receiver = r
+
+	nfs.receiver.Start()
+	return nfs, nil
+}
+
+// Stop closes the sockets and stops the workers
+func (nfs *NetflowV9Server) Stop() {
+	log.Info("Stopping NetFlow v9 server")
+	nfs.receiver.Stop()
+	nfs.aggregator.Stop()
+}
+
+// handlePacket hands off a datagram received from agent to processPacket
+func (nfs *NetflowV9Server) handlePacket(agent bnet.IP, buffer []byte) {
+	if !nfs.accept(agent, time.Now()) {
+		return
+	}
+
+	nfs.processPacket(agent, buffer)
+}
+
+// accept checks if the agent filter accepts a datagram of agent
+func (nfs *NetflowV9Server) accept(agent bnet.IP, now time.Time) bool {
+	if nfs.cfg.AgentFilter == nil {
+		return true
+	}
+
+	ok, reason := nfs.cfg.AgentFilter.Check(agent, now)
+	if !ok {
+		nfs.rejectedPackets.WithLabelValues(nfs.cfg.AgentFilter.AgentLabel(agent), reason).Inc()
+	}
+
+	return ok
+}
+
+func (nfs *NetflowV9Server) processPacket(agent bnet.IP, buffer []byte) {
+	pkt, err := nf9.Decode(buffer, agent.ToNetIP())
+	if err != nil {
+		log.WithError(err).Error("Unable to decode NetFlow v9 packet")
+		return
+	}
+
+	nfs.updateTemplateCache(agent, pkt)
+	nfs.processFlowSets(agent, pkt.Header, pkt.DataFlowSets())
+}
+
+// processFlowSets iterates over flowSets and calls processFlowSet() for each flow set
+func (nfs *NetflowV9Server) processFlowSets(remote bnet.IP, hdr *nf9.Header, flowSets []*nf9.FlowSet) {
+	addr := remote.String()
+	sourceID := hdr.SourceID
+	for _, set := range flowSets {
+		template, isOpts := nfs.tmplCache.get(remote, sourceID, set.Header.FlowSetID)
+
+		if template == nil {
+			templateKey := makeTemplateKey(addr, sourceID, set.Header.FlowSetID)
+			log.Debugf("Template for given FlowSet not found: %s", templateKey)
+
+			continue
+		}
+
+		records := nf9.DecodeFlowSet(template, *set)
+		if records == nil {
+			log.Warning("Error decoding FlowSet")
+			continue
+		}
+
+		nfs.processFlowSet(template, records, remote, hdr, isOpts)
+	}
+}
+
+// processFlowSet generates Flow elements from records and pushes them into the aggregator
+func (nfs *NetflowV9Server) processFlowSet(template []*nf9.TemplateRecord, records []nf9.FlowDataRecord, agent bnet.IP, hdr *nf9.Header, isOpts bool) {
+	fm := generateFieldMap(template)
+	sourceID := hdr.SourceID
+	ts := int64(hdr.UnixSecs)
+
+	for _, r := range records {
+		if isOpts {
+			if fm.samplingInterval >= 0 {
+				nfs.sampleRateCache.set(agent, sourceID, convert.Uint32(r.Values[fm.samplingInterval]))
+			} else if fm.samplerRandomInterval >= 0 {
+				nfs.sampleRateCache.set(agent, sourceID, convert.Uint32(r.Values[fm.samplerRandomInterval]))
+			}
+
+			continue
+		}
+
+		fl := &flow.Flow{
+			Agent:     agent,
+			Timestamp: ts,
+		}
+
+		if fm.family >= 0 {
+			fl.Family = uint8(fm.family)
+		}
+
+		if fm.packets >= 0 {
+			fl.Packets = convert.Uint64(r.Values[fm.packets])
+		}
+
+		if fm.size >= 0 {
+			fl.Size = convert.Uint64(r.Values[fm.size])
+		}
+
+		if fm.protocol >= 0 {
+			fl.Protocol = uint8(convert.Uint16(r.Values[fm.protocol]))
+		}
+
+		if fm.intIn >= 0 {
+			fl.IntIn = nfs.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intIn]))
+		}
+
+		if fm.intOut >= 0 {
+			fl.IntOut = nfs.ifResolver.Resolve(agent, convert.Uint32(r.Values[fm.intOut]))
+		}
+
+		if fm.srcPort >= 0 {
+			fl.SrcPort = convert.Uint16(r.Values[fm.srcPort])
+		}
+
+		if fm.dstPort >= 0 {
+			fl.DstPort = convert.Uint16(r.Values[fm.dstPort])
+		}
+
+		if fm.srcAddr >= 0 {
+			fl.SrcAddr, _ = bnet.IPFromBytes(convert.Reverse(r.Values[fm.srcAddr]))
+		}
+
+		if fm.dstAddr >= 0 {
+			fl.DstAddr, _ = bnet.IPFromBytes(convert.Reverse(r.Values[fm.dstAddr]))
+		}
+
+		if fm.nextHop >= 0 {
+			fl.NextHop, _ = bnet.IPFromBytes(convert.Reverse(r.Values[fm.nextHop]))
+		}
+
+		if fm.srcTos >= 0 && len(r.Values[fm.srcTos]) > 0 {
+			fl.TOS = uint8(r.Values[fm.srcTos][0])
+		}
+
+		if fm.dstAsn >= 0 {
+			fl.DstAs = convert.Uint32(r.Values[fm.dstAsn])
+		}
+
+		if fm.srcAsn >= 0 {
+			fl.SrcAs = convert.Uint32(r.Values[fm.srcAsn])
+		}
+
+		if fm.srcMask >= 0 && len(r.Values[fm.srcMask]) > 0 {
+			fl.SrcPfx = makePrefix(fl.SrcAddr, uint8(r.Values[fm.srcMask][0]))
+		}
+
+		if fm.dstMask >= 0 && len(r.Values[fm.dstMask]) > 0 {
+			fl.DstPfx = makePrefix(fl.DstAddr, uint8(r.Values[fm.dstMask][0]))
+		}
+
+		if fm.srcMask6 >= 0 && len(r.Values[fm.srcMask6]) > 0 {
+			fl.SrcPfx = makePrefix(fl.SrcAddr, uint8(r.Values[fm.srcMask6][0]))
+		}
+
+		if fm.dstMask6 >= 0 && len(r.Values[fm.dstMask6]) > 0 {
+			fl.DstPfx = makePrefix(fl.DstAddr, uint8(r.Values[fm.dstMask6][0]))
+		}
+
+		if fm.samplingInterval >= 0 {
+			fl.Samplerate = uint64(convert.Uint32(r.Values[fm.samplingInterval]))
+		} else {
+			fl.Samplerate = uint64(nfs.sampleRateCache.get(agent, sourceID))
+		}
+
+		if fm.firstSwitched < 0 || fm.lastSwitched < 0 {
+			nfs.aggregator.Ingest(fl)
+			continue
+		}
+
+		start := switchedTime(hdr, convert.Uint32(r.Values[fm.firstSwitched]))
+		end := switchedTime(hdr, convert.Uint32(r.Values[fm.lastSwitched]))
+		for _, part := range nfs.aggregator.SplitFlow(fl, start, end) {
+			nfs.aggregator.Ingest(part)
+		}
+	}
+}
+
+// switchedTime converts a FirstSwitched/LastSwitched sysUpTime value into a unix timestamp in milliseconds.
+// The offset to the export time is computed modulo 2^32 so the sysUpTime wrap after ~49.7 days is handled.
+func switchedTime(hdr *nf9.Header, switched uint32) int64 {
+	return int64(hdr.UnixSecs)*1000 + int64(int32(switched-hdr.SysUpTime))
+}
+
+func makePrefix(addr bnet.IP, mask uint8) bnet.Prefix {
+	p := bnet.NewPfx(addr, mask)
+	return bnet.NewPfx(*p.BaseAddr(), mask)
+}
+
+// generateFieldMap processes a TemplateRecord and populates a fieldMap accordingly
+// the FieldMap can then be used to read fields from a flow
+func generateFieldMap(template []*nf9.TemplateRecord) *fieldMap {
+	fm := fieldMap{
+		srcAddr:               -1,
+		dstAddr:               -1,
+		protocol:              -1,
+		packets:               -1,
+		size:                  -1,
+		intIn:                 -1,
+		intOut:                -1,
+		nextHop:               -1,
+		family:                -1,
+		srcAsn:                -1,
+		dstAsn:                -1,
+		srcPort:               -1,
+		dstPort:               -1,
+		srcTos:                -1,
+		srcMask:               -1,
+		dstMask:               -1,
+		srcMask6:              -1,
+		dstMask6:              -1,
+		samplingInterval:      -1,
+		samplerRandomInterval: -1,
+		firstSwitched:         -1,
+		lastSwitched:          -1,
+	}
+
+	i := -1
+	for _, f := range template {
+		i++
+
+		switch f.Type {
+		case nf9.IPv4SrcAddr:
+			fm.srcAddr = i
+			fm.family = 4
+		case nf9.IPv6SrcAddr:
+			fm.srcAddr = i
+			fm.family = 6
+		case nf9.IPv4DstAddr:
+			fm.dstAddr = i
+		case nf9.IPv6DstAddr:
+			fm.dstAddr = i
+		case nf9.InBytes:
+			fm.size = i
+		case nf9.Protocol:
+			fm.protocol = i
+		case nf9.InPkts:
+			fm.packets = i
+		case nf9.InputSnmp:
+			fm.intIn = i
+		case nf9.OutputSnmp:
+			fm.intOut = i
+		case nf9.IPv4NextHop:
+			fm.nextHop = i
+		case nf9.IPv6NextHop:
+			fm.nextHop = i
+		case nf9.L4SrcPort:
+			fm.srcPort = i
+		case nf9.L4DstPort:
+			fm.dstPort = i
+		case nf9.SrcAs:
+			fm.srcAsn = i
+		case nf9.DstAs:
+			fm.dstAsn = i
+		case nf9.SrcTos:
+			fm.srcTos = i
+		case nf9.SrcMask:
+			fm.srcMask = i
+		case nf9.DstMask:
+			fm.dstMask = i
+		case nf9.IPv6SrcMask:
+			fm.srcMask6 = i
+		case nf9.IPv6DstMask:
+			fm.dstMask6 = i
+		case nf9.SamplingInterval:
+			fm.samplingInterval = i
+		case nf9.FlowSamplerRandomInterval:
+			fm.samplerRandomInterval = i
+		case nf9.FirstSwitched:
+			fm.firstSwitched = i
+		case nf9.LastSwitched:
+			fm.lastSwitched = i
+		}
+	}
+
+	return &fm
+}
+
+// updateTemplateCache updates the template cache
+func (nfs *NetflowV9Server) updateTemplateCache(remote bnet.IP, p *nf9.Packet) {
+	for _, tr := range p.GetTemplateRecords() {
+		if !tr.IsOptionsTemplate {
+			nfs.tmplCache.set(remote, p.Header.SourceID, tr.Header.TemplateID, tr.Records, false)
+			continue
+		}
+
+		nfs.tmplCache.set(remote, p.Header.SourceID, tr.Header.TemplateID, optionsTemplateFields(tr), true)
+	}
+}
+
+// optionsTemplateFields returns the fields of an options data record in wire order.
+// Scope fields are prepended with type 0 (reserved) so their values are skipped
+// while mapping fields but still accounted for when decoding the record.
+func optionsTemplateFields(tr *nf9.TemplateRecords) []*nf9.TemplateRecord {
+	fields := make([]*nf9.TemplateRecord, 0, len(tr.OptionScopes)+len(tr.Records))
+	for _, s := range tr.OptionScopes {
+		fields = append(fields, &nf9.TemplateRecord{
+			Length: s.ScopeFieldLength,
+		})
+	}
+
+	return append(fields, tr.Records...)
+}
+
+// makeTemplateKey creates a string of the 3 tuple router address, source id and template id
+func makeTemplateKey(addr string, sourceID uint32, templateID uint16) string {
+	keyParts := []string{
+		addr,
+		strconv.Itoa(int(sourceID)),
+		strconv.Itoa(int(templateID)),
+	}
+	return strings.Join(keyParts, "|")
+}
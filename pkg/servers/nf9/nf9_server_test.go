@@ -0,0 +1,202 @@
+package nf9
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/nf9"
+	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type mockResolver struct{}
+
+func (m *mockResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	return fmt.Sprintf("if%d", ifID)
+}
+
+func TestOptionsTemplateFields(t *testing.T) {
+	tr := &nf9.TemplateRecords{
+		OptionScopes: []*nf9.OptionScope{
+			{
+				ScopeFieldType:   1,
+				ScopeFieldLength: 4,
+			},
+		},
+		Records: []*nf9.TemplateRecord{
+			{
+				Type:   nf9.SamplingInterval,
+				Length: 4,
+			},
+			{
+				Type:   nf9.SamplingAlgorithm,
+				Length: 1,
+			},
+		},
+		IsOptionsTemplate: true,
+	}
+
+	fields := optionsTemplateFields(tr)
+	assert.Equal(t, []*nf9.TemplateRecord{
+		{
+			Type:   0,
+			Length: 4,
+		},
+		{
+			Type:   nf9.SamplingInterval,
+			Length: 4,
+		},
+		{
+			Type:   nf9.SamplingAlgorithm,
+			Length: 1,
+		},
+	}, fields)
+
+	fm := generateFieldMap(fields)
+	assert.Equal(t, 1, fm.samplingInterval)
+	assert.Equal(t, -1, fm.size)
+}
+
+func TestSwitchedTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		sysUpTime uint32
+		switched  uint32
+		expected  int64
+	}{
+		{
+			name:      "Before export",
+			sysUpTime: 10000,
+			switched:  4000,
+			expected:  1748851393000 - 6000,
+		},
+		{
+			name:      "sysUpTime wrapped after the flow started",
+			sysUpTime: 1000,
+			switched:  0xffffe000,
+			expected:  1748851393000 - 9192,
+		},
+	}
+
+	for _, test := range tests {
+		hdr := &nf9.Header{
+			UnixSecs:  1748851393,
+			SysUpTime: test.sysUpTime,
+		}
+
+		assert.Equal(t, test.expected, switchedTime(hdr, test.switched), test.name)
+	}
+}
+
+func TestProcessPacket(t *testing.T) {
+	input := []byte{
+		0x00, 0x09, // Version
+		0x00, 0x04, // Count
+		0x00, 0x00, 0x03, 0xe8, // SysUpTime = 1000
+		0x68, 0x3d, 0x5a, 0xc1, // UnixSecs
+		0x00, 0x00, 0x00, 0x01, // Sequence
+		0x00, 0x00, 0x00, 0x01, // SourceID
+		0x00, 0x00, // FlowSet ID = 0 = template
+		0x00, 0x3c, // FlowSet length
+		0x01, 0x00, // Template ID
+		0x00, 0x0d, // Field count
+		0x00, 0x08, 0x00, 0x04, // IPv4SrcAddr
+		0x00, 0x0c, 0x00, 0x04, // IPv4DstAddr
+		0x00, 0x09, 0x00, 0x01, // SrcMask
+		0x00, 0x0d, 0x00, 0x01, // DstMask
+		0x00, 0x01, 0x00, 0x04, // InBytes
+		0x00, 0x02, 0x00, 0x04, // InPkts
+		0x00, 0x04, 0x00, 0x01, // Protocol
+		0x00, 0x07, 0x00, 0x02, // L4SrcPort
+		0x00, 0x0b, 0x00, 0x02, // L4DstPort
+		0x00, 0x0a, 0x00, 0x02, // InputSnmp
+		0x00, 0x0e, 0x00, 0x02, // OutputSnmp
+		0x00, 0x16, 0x00, 0x04, // FirstSwitched
+		0x00, 0x15, 0x00, 0x04, // LastSwitched
+		0x00, 0x01, // FlowSet ID = 1 = options template
+		0x00, 0x12, // FlowSet length
+		0x01, 0x01, // Template ID
+		0x00, 0x04, // Option scope length
+		0x00, 0x04, // Option length
+		0x00, 0x01, 0x00, 0x04, // Scope System
+		0x00, 0x22, 0x00, 0x04, // SamplingInterval
+		0x01, 0x01, // FlowSet ID = 257
+		0x00, 0x0c, // FlowSet length
+		0xc0, 0x00, 0x02, 0x01, // System
+		0x00, 0x00, 0x03, 0xe8, // SamplingInterval = 1000
+		0x01, 0x00, // FlowSet ID = 256
+		0x00, 0x27, // FlowSet length
+		0xc0, 0x00, 0x02, 0x0a, // 192.0.2.10
+		0xc6, 0x33, 0x64, 0x14, // 198.51.100.20
+		0x18,                   // SrcMask
+		0x10,                   // DstMask
+		0x00, 0x00, 0x0b, 0xb8, // InBytes
+		0x00, 0x00, 0x00, 0x02, // InPkts
+		0x11,       // Protocol
+		0x00, 0x35, // L4SrcPort
+		0x9c, 0x40, // L4DstPort
+		0x00, 0x01, // InputSnmp
+		0x00, 0x02, // OutputSnmp
+		0xff, 0xff, 0xc0, 0x00, // FirstSwitched, before the sysUpTime wrap
+		0xff, 0xff, 0xf0, 0x00, // LastSwitched, before the sysUpTime wrap
+	}
+
+	output := make(chan []*flow.Flow, 1)
+	// Keep the windows of the export time open
+	agg, err := aggregator.New(output, aggregator.Config{
+		Lateness: time.Since(time.Unix(1748851393, 0)) + time.Hour,
+		Shards:   1,
+	})
+	if err != nil {
+		t.Fatalf("unable to create aggregator: %v", err)
+	}
+
+	nfs := &NetflowV9Server{
+		cfg:             &Config{},
+		tmplCache:       newTemplateCache(),
+		ifResolver:      &mockResolver{},
+		output:          output,
+		aggregator:      agg,
+		sampleRateCache: newSampleRateCache(),
+	}
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	nfs.processPacket(agent, input)
+	agg.Stop()
+
+	flows := <-output
+	if len(flows) != 2 {
+		t.Fatalf("expected 2 flows, got %d", len(flows))
+	}
+
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].Timestamp < flows[j].Timestamp
+	})
+
+	fl := flows[0]
+	assert.Equal(t, agent, fl.Agent)
+	assert.Equal(t, uint8(4), fl.Family)
+	assert.Equal(t, "192.0.2.10", fl.SrcAddr.String())
+	assert.Equal(t, "198.51.100.20", fl.DstAddr.String())
+	assert.Equal(t, "192.0.2.0/24", fl.SrcPfx.String())
+	assert.Equal(t, "198.51.0.0/16", fl.DstPfx.String())
+	assert.Equal(t, uint8(17), fl.Protocol)
+	assert.Equal(t, uint16(53), fl.SrcPort)
+	assert.Equal(t, uint16(40000), fl.DstPort)
+	assert.Equal(t, "if1", fl.IntIn)
+	assert.Equal(t, "if2", fl.IntOut)
+	assert.Equal(t, uint64(1000), fl.Samplerate)
+
+	// FirstSwitched is 17384ms and LastSwitched 5096ms before the export time
+	// so the flow is split across the windows starting at 1748851370 and 1748851380
+	assert.Equal(t, int64(1748851370), flows[0].Timestamp)
+	assert.Equal(t, uint64(1070), flows[0].Size)
+	assert.Equal(t, int64(1748851380), flows[1].Timestamp)
+	assert.Equal(t, uint64(1930), flows[1].Size)
+	assert.Equal(t, uint64(2), flows[0].Packets+flows[1].Packets)
+}
@@ -0,0 +1,66 @@
+package nf9
+
+import (
+	"sync"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/packet/nf9"
+)
+
+type templateCacheKey struct {
+	agent      bnet.IP
+	sourceID   uint32
+	templateID uint16
+}
+
+func newTemplateCacheKey(agent bnet.IP, sourceID uint32, templateID uint16) templateCacheKey {
+	return templateCacheKey{
+		agent:      agent,
+		sourceID:   sourceID,
+		templateID: templateID,
+	}
+}
+
+type templateCache struct {
+	cache map[templateCacheKey]*templateCacheEntry
+	lock  sync.RWMutex
+}
+
+type templateCacheEntry struct {
+	isOptionsTemplate bool
+	records           []*nf9.TemplateRecord
+}
+
+// newTemplateCache creates and initializes a new `templateCache` instance
+func newTemplateCache() *templateCache {
+	return &templateCache{
+		cache: make(map[templateCacheKey]*templateCacheEntry),
+	}
+}
+
+func (c *templateCache) set(rtr bnet.IP, sourceID uint32, templateID uint16, records []*nf9.TemplateRecord, opts bool) {
+	k := newTemplateCacheKey(rtr, sourceID, templateID)
+	v := &templateCacheEntry{
+		isOptionsTemplate: opts,
+		records:           records,
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.cache[k] = v
+}
+
+func (c *templateCache) get(rtr bnet.IP, sourceID uint32, templateID uint16) ([]*nf9.TemplateRecord, bool) {
+	k := newTemplateCacheKey(rtr, sourceID, templateID)
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	e, found := c.cache[k]
+	if !found {
+		return nil, false
+	}
+
+	return e.records, e.isOptionsTemplate
+}
@@ -0,0 +1,275 @@
+// Package sequence tracks sequence numbers of flow export streams in order to detect lost datagrams and samples
+package sequence
+
+import (
+	"sync"
+	"time"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+const (
+	// DefaultReorderTimeout is the time a gap in the sequence may be filled by late datagrams before it is counted as lost
+	DefaultReorderTimeout = time.Second
+
+	// DefaultRatioInterval is the length of the intervals the loss ratio is calculated over
+	DefaultRatioInterval = time.Minute
+
+	// maxGaps is the number of unfilled gaps kept per stream. If exceeded the oldest gap is counted as lost.
+	maxGaps = 64
+
+	// idleTimeout is the time after which streams without observations are forgotten
+	idleTimeout = 10 * time.Minute
+
+	// maxStreams is the number of streams tracked. New streams are not tracked while it is exceeded.
+	maxStreams = 65536
+)
+
+// Key identifies a sequence number stream
+type Key struct {
+	Agent bnet.IP
+
+	// Domain is the sFlow sub-agent ID or the IPFIX observation domain ID
+	Domain uint32
+
+	// Source is the sFlow data source of samples. 0 for datagram and IPFIX streams.
+	Source uint32
+}
+
+// Result is the outcome of an observation
+type Result struct {
+	// Lost is the number of items found to be lost during the observation
+	Lost uint64
+
+	// Restarted is true if the sequence number jumped too far to be explained by loss or reordering
+	Restarted bool
+
+	// LossRatio is the ratio of lost items of the stream over the current and the previous interval
+	LossRatio float32
+}
+
+// gap is a range [from, to) of sequence numbers that have not been received yet
+type gap struct {
+	from     uint32
+	to       uint32
+	detected time.Time
+}
+
+func (g gap) size() uint64 {
+	return uint64(g.to - g.from)
+}
+
+type interval struct {
+	start    time.Time
+	received uint64
+	lost     uint64
+}
+
+type stream struct {
+	next     uint32
+	gaps     []gap
+	current  interval
+	prev     interval
+	lastSeen time.Time
+}
+
+// Tracker tracks the sequence numbers of a set of streams
+type Tracker struct {
+	maxGap         uint32
+	reorderTimeout time.Duration
+	ratioInterval  time.Duration
+	idleTimeout    time.Duration
+	maxStreams     int
+	streams        map[Key]*stream
+	lastExpiry     time.Time
+	streamsMu      sync.Mutex
+}
+
+// New creates a new tracker. Gaps or rewinds of more than maxGap sequence numbers are considered a restart of the stream.
+func New(maxGap uint32, reorderTimeout time.Duration, ratioInterval time.Duration) *Tracker {
+	return &Tracker{
+		maxGap:         maxGap,
+		reorderTimeout: reorderTimeout,
+		ratioInterval:  ratioInterval,
+		idleTimeout:    idleTimeout,
+		maxStreams:     maxStreams,
+		streams:        make(map[Key]*stream),
+	}
+}
+
+// Observe records the receipt of n items on stream k, the first of them having sequence number seq
+func (t *Tracker) Observe(k Key, seq uint32, n uint32, now time.Time) Result {
+	t.streamsMu.Lock()
+	defer t.streamsMu.Unlock()
+
+	if now.Sub(t.lastExpiry) >= t.idleTimeout {
+		t.expire(now)
+	}
+
+	s, found := t.streams[k]
+	if !found {
+		if len(t.streams) >= t.maxStreams {
+			t.expire(now)
+		}
+
+		if len(t.streams) >= t.maxStreams {
+			return Result{}
+		}
+
+		s = &stream{
+			next: seq + n,
+			current: interval{
+				start:    now,
+				received: uint64(n),
+			},
+			lastSeen: now,
+		}
+		t.streams[k] = s
+		return Result{}
+	}
+
+	s.lastSeen = now
+	t.rotate(s, now)
+
+	res := Result{}
+	res.Lost += t.expireGaps(s, now)
+
+	d := int32(seq - s.next)
+	switch {
+	case d == 0:
+		s.next = seq + n
+	case d > 0 && uint32(d) <= t.maxGap:
+		s.gaps = append(s.gaps, gap{
+			from:     s.next,
+			to:       seq,
+			detected: now,
+		})
+		s.next = seq + n
+
+		if len(s.gaps) > maxGaps {
+			res.Lost += s.gaps[0].size()
+			s.gaps = s.gaps[1:]
+		}
+	case d < 0 && uint32(-d) <= t.maxGap:
+		s.fill(seq, seq+n)
+	default:
+		s.gaps = nil
+		s.next = seq + n
+		res.Restarted = true
+	}
+
+	s.current.received += uint64(n)
+	s.current.lost += res.Lost
+	res.LossRatio = s.lossRatio()
+
+	return res
+}
+
+// Reset forgets the state of stream k. The next observation will start tracking it from scratch.
+func (t *Tracker) Reset(k Key) {
+	t.streamsMu.Lock()
+	defer t.streamsMu.Unlock()
+
+	delete(t.streams, k)
+}
+
+// LossRatio gets the loss ratio of stream k
+func (t *Tracker) LossRatio(k Key) float32 {
+	t.streamsMu.Lock()
+	defer t.streamsMu.Unlock()
+
+	s, found := t.streams[k]
+	if !found {
+		return 0
+	}
+
+	return s.lossRatio()
+}
+
+// expire removes streams without observations for longer than the idle timeout
+func (t *Tracker) expire(now time.Time) {
+	for k, s := range t.streams {
+		if now.Sub(s.lastSeen) >= t.idleTimeout {
+			delete(t.streams, k)
+		}
+	}
+
+	t.lastExpiry = now
+}
+
+// rotate starts a new interval if the current one is over
+func (t *Tracker) rotate(s *stream, now time.Time) {
+	elapsed := now.Sub(s.current.start)
+	if elapsed < t.ratioInterval {
+		return
+	}
+
+	s.prev = s.current
+	if elapsed >= 2*t.ratioInterval {
+		s.prev = interval{}
+	}
+
+	s.current = interval{
+		start: now,
+	}
+}
+
+// expireGaps removes gaps older than the reorder timeout and returns the number of lost items
+func (t *Tracker) expireGaps(s *stream, now time.Time) uint64 {
+	lost := uint64(0)
+	i := 0
+	for ; i < len(s.gaps); i++ {
+		if now.Sub(s.gaps[i].detected) < t.reorderTimeout {
+			break
+		}
+
+		lost += s.gaps[i].size()
+	}
+
+	s.gaps = s.gaps[i:]
+	return lost
+}
+
+// fill removes the range [from, to) of late arriving items from the gaps of the stream
+func (s *stream) fill(from uint32, to uint32) {
+	res := make([]gap, 0, len(s.gaps)+1)
+	for _, g := range s.gaps {
+		// Ranges are compared relative to the start of the gap to cope with wrap arounds
+		start := from - g.from
+		end := to - g.from
+		size := g.to - g.from
+
+		if int32(end) <= 0 || (int32(start) >= 0 && start >= size) {
+			res = append(res, g)
+			continue
+		}
+
+		if int32(start) > 0 {
+			res = append(res, gap{
+				from:     g.from,
+				to:       from,
+				detected: g.detected,
+			})
+		}
+
+		if int32(end) >= 0 && end < size {
+			res = append(res, gap{
+				from:     to,
+				to:       g.to,
+				detected: g.detected,
+			})
+		}
+	}
+
+	s.gaps = res
+}
+
+func (s *stream) lossRatio() float32 {
+	lost := s.current.lost + s.prev.lost
+	total := lost + s.current.received + s.prev.received
+	if total == 0 {
+		return 0
+	}
+
+	return float32(lost) / float32(total)
+}
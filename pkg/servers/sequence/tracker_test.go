@@ -0,0 +1,219 @@
+package sequence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+type observation struct {
+	seq      uint32
+	n        uint32
+	offset   time.Duration
+	expected Result
+}
+
+func TestObserve(t *testing.T) {
+	tests := []struct {
+		name         string
+		observations []observation
+	}{
+		{
+			name: "in sequence",
+			observations: []observation{
+				{seq: 100, n: 1},
+				{seq: 101, n: 1},
+				{seq: 102, n: 10},
+				{seq: 112, n: 1},
+			},
+		},
+		{
+			name: "loss is counted after reorder timeout",
+			observations: []observation{
+				{seq: 100, n: 1},
+				{seq: 104, n: 1},
+				{seq: 105, n: 1, offset: 500 * time.Millisecond},
+				{
+					seq:    106,
+					n:      1,
+					offset: 2 * time.Second,
+					expected: Result{
+						Lost:      3,
+						LossRatio: float32(3) / float32(7),
+					},
+				},
+			},
+		},
+		{
+			name: "reordered datagrams fill gap",
+			observations: []observation{
+				{seq: 100, n: 1},
+				{seq: 103, n: 1},
+				{seq: 101, n: 1},
+				{seq: 102, n: 1},
+				{seq: 104, n: 1, offset: 2 * time.Second},
+			},
+		},
+		{
+			name: "partially filled gap",
+			observations: []observation{
+				{seq: 100, n: 10},
+				{seq: 150, n: 10},
+				{seq: 120, n: 10},
+				{
+					seq:    160,
+					n:      10,
+					offset: 2 * time.Second,
+					expected: Result{
+						Lost:      30,
+						LossRatio: float32(30) / float32(70),
+					},
+				},
+			},
+		},
+		{
+			name: "duplicate",
+			observations: []observation{
+				{seq: 100, n: 1},
+				{seq: 101, n: 1},
+				{seq: 101, n: 1},
+				{seq: 102, n: 1, offset: 2 * time.Second},
+			},
+		},
+		{
+			name: "wrap around",
+			observations: []observation{
+				{seq: 4294967294, n: 1},
+				{seq: 4294967295, n: 1},
+				{seq: 1, n: 1},
+				{seq: 0, n: 1},
+				{seq: 2, n: 1, offset: 2 * time.Second},
+			},
+		},
+		{
+			name: "restart",
+			observations: []observation{
+				{seq: 5000000, n: 1},
+				{seq: 5000001, n: 1},
+				{
+					seq: 1,
+					n:   1,
+					expected: Result{
+						Restarted: true,
+					},
+				},
+				{seq: 2, n: 1, offset: 2 * time.Second},
+			},
+		},
+		{
+			name: "loss ratio of previous interval",
+			observations: []observation{
+				{seq: 1, n: 1},
+				{seq: 3, n: 1},
+				{
+					seq:    4,
+					n:      1,
+					offset: 70 * time.Second,
+					expected: Result{
+						Lost:      1,
+						LossRatio: 0.25,
+					},
+				},
+				{
+					seq:    5,
+					n:      1,
+					offset: 300 * time.Second,
+					expected: Result{
+						LossRatio: 0,
+					},
+				},
+			},
+		},
+	}
+
+	k := Key{
+		Agent:  bnet.IPv4FromOctets(192, 0, 2, 1),
+		Domain: 1,
+	}
+
+	for _, test := range tests {
+		tr := New(100000, DefaultReorderTimeout, DefaultRatioInterval)
+		start := time.Unix(1700000000, 0)
+		for i, o := range test.observations {
+			res := tr.Observe(k, o.seq, o.n, start.Add(o.offset))
+			assert.Equalf(t, o.expected, res, "Test %q observation %d", test.name, i)
+		}
+	}
+}
+
+func TestObserveTooManyGaps(t *testing.T) {
+	tr := New(100000, DefaultReorderTimeout, DefaultRatioInterval)
+	k := Key{
+		Agent: bnet.IPv4FromOctets(192, 0, 2, 1),
+	}
+
+	now := time.Unix(1700000000, 0)
+	lost := uint64(0)
+	for i := uint32(0); i <= maxGaps+1; i++ {
+		lost += tr.Observe(k, i*2, 1, now).Lost
+	}
+
+	assert.Equal(t, uint64(1), lost)
+	assert.Len(t, tr.streams[k].gaps, maxGaps)
+}
+
+func TestReset(t *testing.T) {
+	tr := New(100000, DefaultReorderTimeout, DefaultRatioInterval)
+	k := Key{
+		Agent:  bnet.IPv4FromOctets(192, 0, 2, 1),
+		Source: 3,
+	}
+
+	now := time.Unix(1700000000, 0)
+	tr.Observe(k, 100, 1, now)
+	tr.Reset(k)
+
+	assert.Equal(t, Result{}, tr.Observe(k, 200, 1, now.Add(2*time.Second)))
+	assert.Equal(t, float32(0), tr.LossRatio(k))
+}
+
+func TestExpireIdleStreams(t *testing.T) {
+	tr := New(100000, DefaultReorderTimeout, DefaultRatioInterval)
+	idle := Key{
+		Agent: bnet.IPv4FromOctets(192, 0, 2, 1),
+	}
+	busy := Key{
+		Agent: bnet.IPv4FromOctets(192, 0, 2, 2),
+	}
+
+	now := time.Unix(1700000000, 0)
+	tr.Observe(idle, 1, 1, now)
+	tr.Observe(busy, 1, 1, now)
+	tr.Observe(busy, 2, 1, now.Add(idleTimeout/2))
+	tr.Observe(busy, 3, 1, now.Add(idleTimeout))
+
+	assert.NotContains(t, tr.streams, idle)
+	assert.Contains(t, tr.streams, busy)
+}
+
+func TestMaxStreams(t *testing.T) {
+	tr := New(100000, DefaultReorderTimeout, DefaultRatioInterval)
+	tr.maxStreams = 2
+
+	now := time.Unix(1700000000, 0)
+	for i := uint8(1); i <= 3; i++ {
+		tr.Observe(Key{Agent: bnet.IPv4FromOctets(192, 0, 2, i)}, 1, 1, now)
+	}
+
+	assert.Len(t, tr.streams, 2)
+	assert.NotContains(t, tr.streams, Key{Agent: bnet.IPv4FromOctets(192, 0, 2, 3)})
+
+	// Idle streams make room for new ones
+	k := Key{Agent: bnet.IPv4FromOctets(192, 0, 2, 4)}
+	tr.Observe(k, 1, 1, now.Add(idleTimeout/2))
+	tr.Observe(k, 1, 1, now.Add(idleTimeout))
+	assert.Contains(t, tr.streams, k)
+}
@@ -3,10 +3,8 @@ package sflow
 
 import (
 	"fmt"
-	"io"
-	"net"
 	"runtime/debug"
-	"sync"
+	"strconv"
 	"time"
 	"unsafe"
 
@@ -16,13 +14,30 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 
 	bnet "github.com/bio-routing/bio-rd/net"
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
 	"github.com/bio-routing/flowhouse/pkg/models/flow"
 	"github.com/bio-routing/flowhouse/pkg/packet/packet"
 	"github.com/bio-routing/flowhouse/pkg/packet/sflow"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
 	"github.com/bio-routing/flowhouse/pkg/servers/aggregator"
+	"github.com/bio-routing/flowhouse/pkg/servers/sequence"
+	"github.com/bio-routing/flowhouse/pkg/servers/udpreceiver"
 	log "github.com/sirupsen/logrus"
 )
 
+// Agent identities of sflow datagrams
+const (
+	// AgentIdentitySource identifies agents by the source address of datagrams
+	AgentIdentitySource = "source"
+
+	// AgentIdentityAgentAddress identifies agents by the agent address of the sflow header
+	AgentIdentityAgentAddress = "agent_address"
+)
+
+// maxSequenceGap is the number of datagrams or samples the sequence number may skip or go
+// backwards before the agent or data source is considered restarted
+const maxSequenceGap = 100000
+
 var labels []string
 
 func init() {
@@ -35,18 +50,48 @@ type InterfaceResolver interface {
 	Resolve(agent bnet.IP, ifID uint32) string
 }
 
+// Config is the configuration of an `SflowServer`
+type Config struct {
+	// DecapsulateTunnels enables decoding the inner packet of GRE, VXLAN and IP-in-IP tunnels
+	DecapsulateTunnels bool
+
+	// TunnelPrimaryInner makes the inner 5-tuple of decapsulated packets the primary flow key.
+	// Otherwise the outer 5-tuple is used.
+	TunnelPrimaryInner bool
+
+	// StoreLossRatio enables recording the sample loss ratio of the data source with each flow
+	StoreLossRatio bool
+
+	// AgentFilter restricts the agents datagrams are accepted from. Agents are identified by the
+	// source address of the datagram or the agent address of the sflow header. nil accepts all agents.
+	AgentFilter *agentfilter.Filter
+
+	// AgentIdentity selects whether agents are identified by the source address of datagrams
+	// (AgentIdentitySource, default) or the agent address of the sflow header (AgentIdentityAgentAddress)
+	AgentIdentity string
+
+	// Receiver configures the UDP sockets
+	Receiver udpreceiver.Config
+
+	// Aggregator configures the aggregation of flows
+	Aggregator aggregator.Config
+}
+
 // SflowServer represents a sflow Collector instance
 type SflowServer struct {
+	cfg                      *Config
 	aggregator               *aggregator.Aggregator
-	conn                     *net.UDPConn
+	counterOutput            chan []*counters.InterfaceCounters
+	receiver                 *udpreceiver.Receiver
 	ifResolver               InterfaceResolver
-	wg                       sync.WaitGroup
-	stopCh                   chan struct{}
+	datagramSequences        *sequence.Tracker
+	sampleSequences          *sequence.Tracker
 	packetsReceived          *prometheus.CounterVec
 	flowSamplesReceived      *prometheus.CounterVec
 	flowNoRawPktHeader       *prometheus.CounterVec
 	flowNoData               *prometheus.CounterVec
 	flowUnknownProtocol      *prometheus.CounterVec
+	flowMPLSDecodeErrors     *prometheus.CounterVec
 	flowEthernetDecodeErrors *prometheus.CounterVec
 	flowUnknownEtherType     *prometheus.CounterVec
 	flowDot1qDecodeErrors    *prometheus.CounterVec
@@ -54,13 +99,30 @@ type SflowServer struct {
 	flowIPv6DecodeErrors     *prometheus.CounterVec
 	flowTCPDecodeErros       *prometheus.CounterVec
 	flowUDPDecodeErros       *prometheus.CounterVec
+	flowICMPDecodeErrors     *prometheus.CounterVec
+	flowTunnelDecodeErrors   *prometheus.CounterVec
+	counterSamplesReceived   *prometheus.CounterVec
+	counterSamplesDropped    prometheus.Counter
+	lostDatagrams            *prometheus.CounterVec
+	lostSamples              *prometheus.CounterVec
+	sequenceRestarts         *prometheus.CounterVec
+	rejectedPackets          *prometheus.CounterVec
 }
 
 // New creates and starts a new `SflowServer` instance
-func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver InterfaceResolver) (*SflowServer, error) {
+func New(listen string, numSockets int, output chan []*flow.Flow, counterOutput chan []*counters.InterfaceCounters, ifResolver InterfaceResolver, cfg *Config) (*SflowServer, error) {
+	agg, err := aggregator.New(output, cfg.Aggregator)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create aggregator")
+	}
+
 	sfs := &SflowServer{
-		aggregator: aggregator.New(output),
-		ifResolver: ifResolver,
+		cfg:               cfg,
+		aggregator:        agg,
+		counterOutput:     counterOutput,
+		ifResolver:        ifResolver,
+		datagramSequences: sequence.New(maxSequenceGap, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
+		sampleSequences:   sequence.New(maxSequenceGap, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
 		packetsReceived: promauto.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "flowhouse",
 			Subsystem: "sflow",
@@ -90,7 +152,7 @@ func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver Int
 			Subsystem: "sflow",
 			Name:      "flow_samples_unknown_protocol",
 			Help:      "Flow samples unknown protocol",
-		}, labels),
+		}, []string{"agent", "header_protocol"}),
 		flowEthernetDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "flowhouse",
 			Subsystem: "sflow",
@@ -109,6 +171,12 @@ func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver Int
 			Name:      "flow_samples_dot1q_decode_errors",
 			Help:      "Flow samples Dot1Q decode errors",
 		}, labels),
+		flowMPLSDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "flow_samples_mpls_decode_errors",
+			Help:      "Flow samples MPLS decode errors",
+		}, labels),
 		flowIPv4DecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
 			Namespace: "flowhouse",
 			Subsystem: "sflow",
@@ -133,100 +201,106 @@ func New(listen string, numReaders int, output chan []*flow.Flow, ifResolver Int
 			Name:      "flow_samples_udp_decode_errors",
 			Help:      "Flow samples UDP decode errors",
 		}, labels),
-		stopCh: make(chan struct{}),
-	}
-
-	addr, err := net.ResolveUDPAddr("udp", listen)
-	if err != nil {
-		return nil, errors.Wrap(err, "Unable to resolve UDP address")
+		flowICMPDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "flow_samples_icmp_decode_errors",
+			Help:      "Flow samples ICMP decode errors",
+		}, labels),
+		flowTunnelDecodeErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "flow_samples_tunnel_decode_errors",
+			Help:      "Flow samples tunnel decode errors",
+		}, labels),
+		counterSamplesReceived: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "counter_samples_received",
+			Help:      "Counter samples received",
+		}, labels),
+		counterSamplesDropped: promauto.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "counter_samples_dropped",
+			Help:      "Counter samples dropped due to a full insert queue",
+		}),
+		lostDatagrams: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "lost_datagrams",
+			Help:      "Datagrams missing in the sequence of an agent",
+		}, labels),
+		lostSamples: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "lost_flow_samples",
+			Help:      "Flow samples missing in the sequence of a data source",
+		}, labels),
+		sequenceRestarts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "sequence_restarts",
+			Help:      "Datagram sequence restarts (e.g. due to agent reboots)",
+		}, labels),
+		rejectedPackets: promauto.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "sflow",
+			Name:      "rejected_packets",
+			Help:      "Packets rejected due to unknown agent or rate limiting. Unknown agents are not labelled.",
+		}, []string{"agent", "reason"}),
 	}
 
-	con, err := net.ListenUDP("udp", addr)
+	r, err := udpreceiver.New(listen, numSockets, cfg.Receiver, sfs.handlePacket, promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "flowhouse",
+		Subsystem: "sflow",
+		Name:      "socket_drops",
+		Help:      "Datagrams dropped by the kernel due to full socket receive buffers",
+	}))
 	if err != nil {
-		return nil, errors.Wrap(err, "ListenUDP failed")
+		return nil, errors.Wrap(err, "Unable to create UDP receiver")
 	}
-	sfs.conn = con
+	sfs.receiver = r
 
-	sfs.startService(numReaders)
+	sfs.receiver.Start()
 	return sfs, nil
 }
 
-func (sfs *SflowServer) startService(numReaders int) {
-	for i := 0; i < numReaders; i++ {
-		sfs.wg.Add(1)
-		go func() {
-			defer sfs.wg.Done()
-			err := sfs.packetWorker()
-			if err != nil {
-				log.WithError(err).Error("packetWorker failed")
-			}
-		}()
-	}
-}
-
-// Stop closes the socket and stops the workers
+// Stop closes the sockets and stops the workers
 func (sfs *SflowServer) Stop() {
 	log.Info("Stopping SflowServer")
 	debug.PrintStack()
-	close(sfs.stopCh)
+	sfs.receiver.Stop()
 	sfs.aggregator.Stop()
-	sfs.conn.Close()
-	sfs.wg.Wait()
 }
 
-// packetWorker reads sflow packet from socket and handsoff processing to ???
-func (sfs *SflowServer) packetWorker() error {
-	buffer := make([]byte, 8960)
-	for {
-		if sfs.stopped() {
-			return nil
-		}
-
-		length, remote, err := sfs.conn.ReadFromUDP(buffer)
-		if err == io.EOF {
-			return nil
-		}
-
-		if err != nil {
-			return errors.Wrap(err, "ReadFromUDP failed")
-		}
-
-		remote4 := remote.IP.To4()
-		if remote4 != nil {
-			remote.IP = remote4
-		}
-
-		remoteAddr, err := bnet.IPFromBytes([]byte(remote.IP))
-		if err != nil {
-			return errors.Wrapf(err, "Unable to convert net.IP to bnet.IP: %q", remote)
-		}
-
-		sfs.packetsReceived.WithLabelValues(remoteAddr.String()).Inc()
-		sfs.processPacket(remoteAddr, buffer[:length])
+// handlePacket counts a datagram received from remote and hands it off to processPacket
+func (sfs *SflowServer) handlePacket(remote bnet.IP, buffer []byte) {
+	sfs.packetsReceived.WithLabelValues(remote.String()).Inc()
+	if !sfs.accept(remote, buffer, time.Now()) {
+		return
 	}
-}
 
-func (sfs *SflowServer) stopped() bool {
-	select {
-	case <-sfs.stopCh:
-		return true
-	default:
-		return false
-	}
+	sfs.processPacket(remote, buffer)
 }
 
 // processPacket takes a raw sflow packet, send it to the decoder and passes the decoded packet to the aggregator
-func (sfs *SflowServer) processPacket(agent bnet.IP, buffer []byte) {
-	agentStr := agent.String()
-
+func (sfs *SflowServer) processPacket(remote bnet.IP, buffer []byte) {
 	p, err := sflow.Decode(buffer)
 	if err != nil {
 		log.WithError(err).Error("Unable to decode sflow packet")
 		return
 	}
 
+	now := time.Now()
+	agent := sfs.agentIdentity(remote, p.Header)
+	agentStr := agent.String()
+	subAgentID := p.Header.SubAgentID
+	sfs.trackDatagram(agent, p.Header, now)
+
 	for _, fs := range p.FlowSamples {
 		sfs.flowSamplesReceived.WithLabelValues(agentStr).Inc()
+		lossRatio := sfs.trackFlowSample(agent, subAgentID, fs.FlowSampleHeader, now)
 
 		if fs.RawPacketHeader == nil {
 			sfs.flowNoRawPktHeader.WithLabelValues(agentStr).Inc()
@@ -238,36 +312,19 @@ func (sfs *SflowServer) processPacket(agent bnet.IP, buffer []byte) {
 			continue
 		}
 
-		if fs.RawPacketHeader.HeaderProtocol != 1 {
-			sfs.flowUnknownProtocol.WithLabelValues(agentStr).Inc()
-			continue
-		}
-
-		ether, err := packet.DecodeEthernet(fs.Data, fs.RawPacketHeader.OriginalPacketLength)
-		if err != nil {
-			sfs.flowEthernetDecodeErrors.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode ethernet packet")
-			continue
-		}
-		fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfEthernetII)
-		fs.DataLen -= uint32(packet.SizeOfEthernetII)
-
 		fl := &flow.Flow{
 			Agent:      agent,
-			IntIn:      sfs.ifResolver.Resolve(agent, fs.FlowSampleHeader.InputIf),
-			IntOut:     sfs.ifResolver.Resolve(agent, fs.FlowSampleHeader.OutputIf),
+			SubAgentID: subAgentID,
+			IntIn:      sfs.resolveInterface(agent, subAgentID, fs.FlowSampleHeader.InputIf),
+			IntOut:     sfs.resolveInterface(agent, subAgentID, fs.FlowSampleHeader.OutputIf),
 			Size:       uint64(fs.RawPacketHeader.FrameLength),
 			Packets:    1,
-			Timestamp:  time.Now().Unix(),
+			Timestamp:  now.Unix(),
 			Samplerate: uint64(fs.FlowSampleHeader.SamplingRate),
 		}
 
-		if fl.IntIn == "" {
-			fl.IntIn += fmt.Sprintf("%d", fs.FlowSampleHeader.InputIf)
-		}
-
-		if fl.IntOut == "" {
-			fl.IntOut += fmt.Sprintf("%d", fs.FlowSampleHeader.OutputIf)
+		if sfs.storeLossRatio() {
+			fl.LossRatio = lossRatio
 		}
 
 		if fs.ExtendedRouterData != nil {
@@ -277,13 +334,309 @@ func (sfs *SflowServer) processPacket(agent bnet.IP, buffer []byte) {
 			}
 		}
 
+		if fs.ExtendedGatewayData != nil {
+			setGatewayData(fl, fs.ExtendedGatewayData)
+		}
+
 		if fs.ExtendedSwitchData != nil {
-			fl.IntIn += fmt.Sprintf(".%d", fs.ExtendedSwitchData.IncomingVLAN)
-			fl.IntOut += fmt.Sprintf(".%d", fs.ExtendedSwitchData.OutgoingVLAN)
+			fl.VLANIn = uint16(fs.ExtendedSwitchData.IncomingVLAN)
+			fl.VLANOut = uint16(fs.ExtendedSwitchData.OutgoingVLAN)
+		}
+
+		if !sfs.processRawPacketHeader(agentStr, fs, fl) {
+			continue
 		}
 
-		sfs.processEthernet(agentStr, ether.EtherType, fs, fl)
-		sfs.aggregator.GetIngress() <- fl
+		sfs.aggregator.Ingest(fl)
+	}
+
+	sfs.processCounterSamples(agent, subAgentID, p.CounterSamples)
+}
+
+// accept checks if the agent filter accepts a datagram received from remote before it is decoded.
+// Agents not known by remote are looked up by the agent address of the datagrams header.
+func (sfs *SflowServer) accept(remote bnet.IP, buffer []byte, now time.Time) bool {
+	if sfs.cfg == nil || sfs.cfg.AgentFilter == nil {
+		return true
+	}
+
+	f := sfs.cfg.AgentFilter
+	agent := remote
+	if !f.Known(remote) {
+		agent = rawAgentAddress(buffer)
+		if !f.Known(agent) {
+			f.Reject(remote, agentfilter.ReasonUnknownAgent)
+			sfs.rejectedPackets.WithLabelValues("", agentfilter.ReasonUnknownAgent).Inc()
+			return false
+		}
+	}
+
+	if !f.Allow(remote, now) {
+		f.Reject(remote, agentfilter.ReasonRateLimited)
+		sfs.rejectedPackets.WithLabelValues(f.AgentLabel(agent), agentfilter.ReasonRateLimited).Inc()
+		return false
+	}
+
+	return true
+}
+
+// rawAgentAddress gets the agent address of a datagram that has not been decoded yet
+func rawAgentAddress(buffer []byte) bnet.IP {
+	addr, err := sflow.AgentAddress(buffer)
+	if err != nil {
+		log.WithError(err).Debug("Unable to read agent address")
+		return bnet.IP{}
+	}
+
+	ip, err := bnet.IPFromBytes([]byte(addr))
+	if err != nil {
+		log.WithError(err).Debug("Unable to convert agent address")
+	}
+
+	return ip
+}
+
+// headerAgentAddress gets the agent address of an sflow header
+func headerAgentAddress(hdr *sflow.Header) bnet.IP {
+	addr, err := bnet.IPFromBytes([]byte(hdr.AgentAddress))
+	if err != nil {
+		log.WithError(err).Debug("Unable to convert agent address")
+	}
+
+	return addr
+}
+
+// agentIdentity gets the address identifying the agent of a datagram received from remote
+func (sfs *SflowServer) agentIdentity(remote bnet.IP, hdr *sflow.Header) bnet.IP {
+	if sfs.cfg == nil || sfs.cfg.AgentIdentity != AgentIdentityAgentAddress {
+		return remote
+	}
+
+	if len(hdr.AgentAddress) == 0 || hdr.AgentAddress.IsUnspecified() {
+		return remote
+	}
+
+	return headerAgentAddress(hdr)
+}
+
+// resolveInterface resolves the interface index of an agent into its name. Unresolved interfaces are
+// named by their index, prefixed by the sub-agent ID for sub-agents other than 0 (e.g. line cards).
+func (sfs *SflowServer) resolveInterface(agent bnet.IP, subAgentID uint32, ifIndex uint32) string {
+	name := sfs.ifResolver.Resolve(agent, ifIndex)
+	if name != "" {
+		return name
+	}
+
+	if subAgentID != 0 {
+		return fmt.Sprintf("%d/%d", subAgentID, ifIndex)
+	}
+
+	return fmt.Sprintf("%d", ifIndex)
+}
+
+// trackDatagram checks the sequence number of a datagram for gaps
+func (sfs *SflowServer) trackDatagram(agent bnet.IP, hdr *sflow.Header, now time.Time) {
+	k := sequence.Key{
+		Agent:  agent,
+		Domain: hdr.SubAgentID,
+	}
+
+	res := sfs.datagramSequences.Observe(k, hdr.SequenceNumber, 1, now)
+	if res.Lost > 0 {
+		sfs.lostDatagrams.WithLabelValues(agent.String()).Add(float64(res.Lost))
+	}
+
+	if res.Restarted {
+		log.WithFields(log.Fields{
+			"agent":           agent.String(),
+			"sub_agent":       hdr.SubAgentID,
+			"sequence_number": hdr.SequenceNumber,
+		}).Info("sflow datagram sequence restarted")
+		sfs.sequenceRestarts.WithLabelValues(agent.String()).Inc()
+	}
+}
+
+// trackFlowSample checks the sequence number of a flow sample for gaps and returns the loss ratio of its data source
+func (sfs *SflowServer) trackFlowSample(agent bnet.IP, subAgentID uint32, fsh *sflow.FlowSampleHeader, now time.Time) float32 {
+	k := sequence.Key{
+		Agent:  agent,
+		Domain: subAgentID,
+		Source: fsh.SourceIDClassIndex,
+	}
+
+	res := sfs.sampleSequences.Observe(k, fsh.SequenceNumber, 1, now)
+	if res.Lost > 0 {
+		sfs.lostSamples.WithLabelValues(agent.String()).Add(float64(res.Lost))
+	}
+
+	return res.LossRatio
+}
+
+func (sfs *SflowServer) storeLossRatio() bool {
+	return sfs.cfg != nil && sfs.cfg.StoreLossRatio
+}
+
+// setGatewayData sets the BGP attributes of the extended gateway data on a flow
+func setGatewayData(fl *flow.Flow, egd *sflow.ExtendedGatewayData) {
+	fl.SrcAs = egd.SrcAS
+	fl.DstAs = egd.AS
+	fl.ASPath = egd.FlatASPath()
+	fl.Communities = egd.Communities
+
+	if len(fl.ASPath) > 0 {
+		fl.NextAs = fl.ASPath[0]
+		fl.DstAs = fl.ASPath[len(fl.ASPath)-1]
+	}
+}
+
+// processCounterSamples converts interface counter samples and passes them to the counter output
+func (sfs *SflowServer) processCounterSamples(agent bnet.IP, subAgentID uint32, samples []*sflow.CounterSample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	agentStr := agent.String()
+	ts := time.Now().Unix()
+	res := make([]*counters.InterfaceCounters, 0, len(samples))
+	for _, cs := range samples {
+		sfs.counterSamplesReceived.WithLabelValues(agentStr).Inc()
+
+		ic := sfs.counterSampleToInterfaceCounters(agent, subAgentID, ts, cs)
+		if ic == nil {
+			continue
+		}
+
+		res = append(res, ic)
+	}
+
+	if len(res) == 0 || sfs.counterOutput == nil {
+		return
+	}
+
+	// Blocking would stall the receiving of datagrams while inserts are slow
+	select {
+	case sfs.counterOutput <- res:
+	default:
+		sfs.counterSamplesDropped.Add(float64(len(res)))
+	}
+}
+
+// counterSampleToInterfaceCounters converts a counter sample into interface counters.
+// Returns nil if the sample does not contain generic interface counters.
+func (sfs *SflowServer) counterSampleToInterfaceCounters(agent bnet.IP, subAgentID uint32, ts int64, cs *sflow.CounterSample) *counters.InterfaceCounters {
+	gc := cs.GenericInterfaceCounters
+	if gc == nil {
+		return nil
+	}
+
+	ic := &counters.InterfaceCounters{
+		Agent:            agent,
+		Timestamp:        ts,
+		IfIndex:          gc.IfIndex,
+		IntName:          sfs.resolveInterface(agent, subAgentID, gc.IfIndex),
+		IfType:           gc.IfType,
+		IfSpeed:          gc.IfSpeed,
+		IfDirection:      gc.IfDirection,
+		IfStatus:         gc.IfStatus,
+		InOctets:         gc.IfInOctets,
+		InUcastPkts:      gc.IfInUcastPkts,
+		InMulticastPkts:  gc.IfInMulticastPkts,
+		InBroadcastPkts:  gc.IfInBroadcastPkts,
+		InDiscards:       gc.IfInDiscards,
+		InErrors:         gc.IfInErrors,
+		InUnknownProtos:  gc.IfInUnknownProtos,
+		OutOctets:        gc.IfOutOctets,
+		OutUcastPkts:     gc.IfOutUcastPkts,
+		OutMulticastPkts: gc.IfOutMulticastPkts,
+		OutBroadcastPkts: gc.IfOutBroadcastPkts,
+		OutDiscards:      gc.IfOutDiscards,
+		OutErrors:        gc.IfOutErrors,
+	}
+
+	ec := cs.EthernetCounters
+	if ec != nil {
+		ic.AlignmentErrors = ec.Dot3StatsAlignmentErrors
+		ic.FCSErrors = ec.Dot3StatsFCSErrors
+		ic.SingleCollisions = ec.Dot3StatsSingleCollisionFrames
+		ic.MultipleCollisions = ec.Dot3StatsMultipleCollisionFrames
+		ic.LateCollisions = ec.Dot3StatsLateCollisions
+		ic.FrameTooLongs = ec.Dot3StatsFrameTooLongs
+		ic.SymbolErrors = ec.Dot3StatsSymbolErrors
+	}
+
+	return ic
+}
+
+// processRawPacketHeader decodes the sampled header according to its header protocol.
+// Returns false if the header protocol is not supported or the header could not be decoded.
+func (sfs *SflowServer) processRawPacketHeader(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) bool {
+	switch fs.RawPacketHeader.HeaderProtocol {
+	case sflow.HeaderProtocolEthernet:
+		return sfs.processEthernetFrame(agentStr, fs, fl)
+	case sflow.HeaderProtocolIPv4:
+		sfs.processIPv4Packet(agentStr, fs, fl)
+	case sflow.HeaderProtocolIPv6:
+		sfs.processIPv6Packet(agentStr, fs, fl)
+	case sflow.HeaderProtocolMPLS:
+		sfs.processMPLSPacket(agentStr, fs, fl)
+	default:
+		sfs.flowUnknownProtocol.WithLabelValues(agentStr, strconv.Itoa(int(fs.RawPacketHeader.HeaderProtocol))).Inc()
+		return false
+	}
+
+	return true
+}
+
+func (sfs *SflowServer) processMPLSPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+	labels, err := packet.DecodeMPLS(fs.Data, fs.DataLen)
+	if err != nil {
+		sfs.flowMPLSDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to decode MPLS label stack")
+		return
+	}
+	setMPLSLabels(fl, labels)
+
+	stackSize := uintptr(len(labels)) * packet.SizeOfMPLSLabel
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - stackSize)
+	fs.DataLen -= uint32(stackSize)
+
+	version, err := packet.DecodeIPVersion(fs.Data, fs.DataLen)
+	if err != nil {
+		sfs.flowMPLSDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to decode MPLS payload")
+		return
+	}
+
+	switch version {
+	case 4:
+		sfs.processIPv4Packet(agentStr, fs, fl)
+	case 6:
+		sfs.processIPv6Packet(agentStr, fs, fl)
+	default:
+		sfs.flowMPLSDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.Debugf("Unknown MPLS payload IP version: %d", version)
+	}
+}
+
+func (sfs *SflowServer) processEthernetFrame(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) bool {
+	ether, err := packet.DecodeEthernet(fs.Data, fs.DataLen)
+	if err != nil {
+		sfs.flowEthernetDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to decode ethernet packet")
+		return false
+	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfEthernetII)
+	fs.DataLen -= uint32(packet.SizeOfEthernetII)
+
+	sfs.processEthernet(agentStr, ether.EtherType, fs, fl)
+	return true
+}
+
+// setMPLSLabels sets the top and bottom label of a label stack on a flow
+func setMPLSLabels(fl *flow.Flow, labels []packet.MPLSLabel) {
+	fl.MPLSTopLabel = labels[0].Label
+	if len(labels) > 1 {
+		fl.MPLSBottomLabel = labels[len(labels)-1].Label
 	}
 }
 
@@ -294,24 +647,40 @@ func (sfs *SflowServer) processEthernet(agentStr string, ethType uint16, fs *sfl
 		sfs.processIPv6Packet(agentStr, fs, fl)
 	} else if ethType == packet.EtherTypeARP || ethType == packet.EtherTypeLACP {
 		return
-	} else if ethType == packet.EtherTypeIEEE8021Q {
-		sfs.processDot1QPacket(agentStr, fs, fl)
+	} else if packet.IsVLANTag(ethType) {
+		sfs.processDot1QPacket(agentStr, ethType, fs, fl)
+	} else if ethType == packet.EtherTypeMPLSUnicast || ethType == packet.EtherTypeMPLSMulticast {
+		sfs.processMPLSPacket(agentStr, fs, fl)
 	} else {
 		sfs.flowUnknownEtherType.WithLabelValues(agentStr).Inc()
 		log.Debugf("Unknown EtherType: 0x%x", ethType)
 	}
 }
 
-func (sfs *SflowServer) processDot1QPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
-	dot1q, err := packet.DecodeDot1Q(fs.Data, fs.DataLen)
-	if err != nil {
-		sfs.flowDot1qDecodeErrors.WithLabelValues(agentStr).Inc()
-		log.WithError(err).Debug("Unable to decode dot1q header")
+// processDot1QPacket decodes a stack of 802.1Q/802.1ad tags. The first two VLAN IDs
+// are recorded as outer and inner VLAN.
+func (sfs *SflowServer) processDot1QPacket(agentStr string, ethType uint16, fs *sflow.FlowSample, fl *flow.Flow) {
+	for tags := 0; packet.IsVLANTag(ethType); tags++ {
+		dot1q, err := packet.DecodeDot1Q(fs.Data, fs.DataLen)
+		if err != nil {
+			sfs.flowDot1qDecodeErrors.WithLabelValues(agentStr).Inc()
+			log.WithError(err).Debug("Unable to decode dot1q header")
+			return
+		}
+		fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfDot1Q)
+		fs.DataLen -= uint32(packet.SizeOfDot1Q)
+
+		switch tags {
+		case 0:
+			fl.VLANOuter = dot1q.VLANID()
+		case 1:
+			fl.VLANInner = dot1q.VLANID()
+		}
+
+		ethType = dot1q.EtherType
 	}
-	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfDot1Q)
-	fs.DataLen -= uint32(packet.SizeOfDot1Q)
 
-	sfs.processEthernet(agentStr, dot1q.EtherType, fs, fl)
+	sfs.processEthernet(agentStr, ethType, fs, fl)
 }
 
 func (sfs *SflowServer) processIPv4Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
@@ -320,26 +689,30 @@ func (sfs *SflowServer) processIPv4Packet(agentStr string, fs *sflow.FlowSample,
 	if err != nil {
 		sfs.flowIPv4DecodeErrors.WithLabelValues(agentStr).Inc()
 		log.WithError(err).Debug("Unable to decode IPv4 packet")
+		return
 	}
-	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfIPv4Header)
-	fs.DataLen -= uint32(packet.SizeOfIPv4Header)
 
 	fl.TOS = ipv4.TOS
 	fl.SrcAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv4.SrcAddr[:]))
 	fl.DstAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv4.DstAddr[:]))
 	fl.Protocol = uint8(ipv4.Protocol)
-	switch ipv4.Protocol {
-	case packet.TCP:
-		if err := getTCP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowTCPDecodeErros.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode TCP")
-		}
-	case packet.UDP:
-		if err := getUDP(fs.Data, fs.DataLen, fl); err != nil {
-			sfs.flowUDPDecodeErros.WithLabelValues(agentStr).Inc()
-			log.WithError(err).Debug("Unable to decode UDP")
-		}
+	fl.Fragment = ipv4.IsFragment()
+
+	// Non-first fragments carry no upper-layer header
+	if ipv4.FragmentOffset() != 0 {
+		return
+	}
+
+	hdrLen := ipv4.HeaderLength()
+	if hdrLen < uint32(packet.SizeOfIPv4Header) || hdrLen > fs.DataLen {
+		sfs.flowIPv4DecodeErrors.WithLabelValues(agentStr).Inc()
+		log.Debugf("Invalid IPv4 header length: %d", hdrLen)
+		return
 	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - uintptr(hdrLen))
+	fs.DataLen -= hdrLen
+
+	sfs.processTransport(agentStr, fs, fl)
 }
 
 func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
@@ -348,6 +721,7 @@ func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample,
 	if err != nil {
 		sfs.flowIPv6DecodeErrors.WithLabelValues(agentStr).Inc()
 		log.WithError(err).Debug("Unable to decode IPv6 packet")
+		return
 	}
 	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfIPv6Header)
 	fs.DataLen -= uint32(packet.SizeOfIPv6Header)
@@ -356,7 +730,30 @@ func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample,
 	fl.SrcAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv6.SrcAddr[:]))
 	fl.DstAddr, _ = bnet.IPFromBytes(convert.Reverse(ipv6.DstAddr[:]))
 	fl.Protocol = uint8(ipv6.NextHeader)
-	switch ipv6.NextHeader {
+
+	payload, err := packet.WalkIPv6ExtensionHeaders(fs.Data, fs.DataLen, ipv6.NextHeader)
+	if err != nil {
+		sfs.flowIPv6DecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to walk IPv6 extension headers")
+		return
+	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - uintptr(payload.Length))
+	fs.DataLen -= payload.Length
+
+	fl.Protocol = payload.Protocol
+	fl.Fragment = payload.Fragment
+
+	// Non-first fragments carry no upper-layer header
+	if payload.FragmentOffset != 0 {
+		return
+	}
+
+	sfs.processTransport(agentStr, fs, fl)
+}
+
+// processTransport decodes the payload of an IP packet according to fl.Protocol
+func (sfs *SflowServer) processTransport(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+	switch fl.Protocol {
 	case packet.TCP:
 		if err := getTCP(fs.Data, fs.DataLen, fl); err != nil {
 			sfs.flowTCPDecodeErros.WithLabelValues(agentStr).Inc()
@@ -366,10 +763,128 @@ func (sfs *SflowServer) processIPv6Packet(agentStr string, fs *sflow.FlowSample,
 		if err := getUDP(fs.Data, fs.DataLen, fl); err != nil {
 			sfs.flowUDPDecodeErros.WithLabelValues(agentStr).Inc()
 			log.WithError(err).Debug("Unable to decode UDP")
+			return
+		}
+
+		if fl.DstPort == packet.VXLANPort && sfs.decapsulateTunnels() {
+			sfs.processVXLANPacket(agentStr, fs, fl)
+		}
+	case packet.ICMP, packet.ICMPv6:
+		if err := getICMP(fs.Data, fs.DataLen, fl); err != nil {
+			sfs.flowICMPDecodeErrors.WithLabelValues(agentStr).Inc()
+			log.WithError(err).Debug("Unable to decode ICMP")
+		}
+	case packet.GRE:
+		if sfs.decapsulateTunnels() {
+			sfs.processGREPacket(agentStr, fs, fl)
+		}
+	case packet.IPIP:
+		if sfs.decapsulateTunnels() {
+			sfs.decapsulate(fl, flow.TunnelTypeIPIP, 0, func() {
+				sfs.processIPv4Packet(agentStr, fs, fl)
+			})
+		}
+	case packet.IPv6Encap:
+		if sfs.decapsulateTunnels() {
+			sfs.decapsulate(fl, flow.TunnelTypeIPIP, 0, func() {
+				sfs.processIPv6Packet(agentStr, fs, fl)
+			})
 		}
 	}
 }
 
+func (sfs *SflowServer) decapsulateTunnels() bool {
+	return sfs.cfg != nil && sfs.cfg.DecapsulateTunnels
+}
+
+func (sfs *SflowServer) processGREPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+	gre, err := packet.DecodeGRE(fs.Data, fs.DataLen)
+	if err != nil {
+		sfs.flowTunnelDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to decode GRE header")
+		return
+	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - uintptr(gre.Length))
+	fs.DataLen -= gre.Length
+
+	switch gre.ProtocolType {
+	case packet.EtherTypeIPv4:
+		sfs.decapsulate(fl, flow.TunnelTypeGRE, gre.Key, func() {
+			sfs.processIPv4Packet(agentStr, fs, fl)
+		})
+	case packet.EtherTypeIPv6:
+		sfs.decapsulate(fl, flow.TunnelTypeGRE, gre.Key, func() {
+			sfs.processIPv6Packet(agentStr, fs, fl)
+		})
+	case packet.EtherTypeTransparentEthernetBridging:
+		sfs.decapsulate(fl, flow.TunnelTypeGRE, gre.Key, func() {
+			sfs.processEthernetFrame(agentStr, fs, fl)
+		})
+	default:
+		sfs.flowTunnelDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.Debugf("Unknown GRE protocol type: 0x%x", gre.ProtocolType)
+	}
+}
+
+func (sfs *SflowServer) processVXLANPacket(agentStr string, fs *sflow.FlowSample, fl *flow.Flow) {
+	if packet.SizeOfUDPHeader > uintptr(fs.DataLen) {
+		return
+	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfUDPHeader)
+	fs.DataLen -= uint32(packet.SizeOfUDPHeader)
+
+	vxlan, err := packet.DecodeVXLAN(fs.Data, fs.DataLen)
+	if err != nil {
+		sfs.flowTunnelDecodeErrors.WithLabelValues(agentStr).Inc()
+		log.WithError(err).Debug("Unable to decode VXLAN header")
+		return
+	}
+	fs.Data = unsafe.Pointer(uintptr(fs.Data) - packet.SizeOfVXLANHeader)
+	fs.DataLen -= uint32(packet.SizeOfVXLANHeader)
+
+	sfs.decapsulate(fl, flow.TunnelTypeVXLAN, vxlan.VNI, func() {
+		sfs.processEthernetFrame(agentStr, fs, fl)
+	})
+}
+
+// decapsulate processes the inner packet of a tunnel using processInner and records the outer
+// and inner 5-tuple on the flow. The primary 5-tuple of the flow is chosen by TunnelPrimaryInner.
+// With the outer packet being primary the VLAN IDs and MPLS labels of inner frames are discarded.
+func (sfs *SflowServer) decapsulate(fl *flow.Flow, tunnelType uint8, key uint32, processInner func()) {
+	outer := fl.FiveTuple()
+	outerFamily := fl.Family
+	outerTOS := fl.TOS
+	outerTCPFlags := fl.TCPFlags
+	outerICMPType := fl.ICMPType
+	outerICMPCode := fl.ICMPCode
+	outerFragment := fl.Fragment
+	outerVLANOuter := fl.VLANOuter
+	outerVLANInner := fl.VLANInner
+	outerMPLSTopLabel := fl.MPLSTopLabel
+	outerMPLSBottomLabel := fl.MPLSBottomLabel
+
+	processInner()
+
+	fl.TunnelType = tunnelType
+	fl.TunnelKey = key
+	fl.Outer = outer
+	fl.Inner = fl.FiveTuple()
+
+	if !sfs.cfg.TunnelPrimaryInner {
+		fl.SetFiveTuple(outer)
+		fl.Family = outerFamily
+		fl.TOS = outerTOS
+		fl.TCPFlags = outerTCPFlags
+		fl.ICMPType = outerICMPType
+		fl.ICMPCode = outerICMPCode
+		fl.Fragment = outerFragment
+		fl.VLANOuter = outerVLANOuter
+		fl.VLANInner = outerVLANInner
+		fl.MPLSTopLabel = outerMPLSTopLabel
+		fl.MPLSBottomLabel = outerMPLSBottomLabel
+	}
+}
+
 func extractTrafficClass(versionTrafficClassFlowLabel uint32) uint8 {
 	versionTrafficClassFlowLabel &= 0x0FF00000
 	return uint8(versionTrafficClassFlowLabel >> 20)
@@ -395,6 +910,19 @@ func getTCP(tcpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
 
 	fl.SrcPort = tcp.SrcPort
 	fl.DstPort = tcp.DstPort
+	fl.TCPFlags = tcp.Flags
+
+	return nil
+}
+
+func getICMP(icmpPtr unsafe.Pointer, length uint32, fl *flow.Flow) error {
+	icmp, err := packet.DecodeICMP(icmpPtr, length)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode ICMP header")
+	}
+
+	fl.ICMPType = icmp.Type
+	fl.ICMPCode = icmp.Code
 
 	return nil
 }
@@ -2,8 +2,20 @@ package sflow
 
 import (
 	"testing"
+	"time"
 
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/bio-routing/flowhouse/pkg/packet/packet"
+	"github.com/bio-routing/flowhouse/pkg/packet/sflow"
+	"github.com/bio-routing/flowhouse/pkg/servers/agentfilter"
+	"github.com/bio-routing/flowhouse/pkg/servers/sequence"
 	"github.com/bio-routing/tflow2/convert"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
 )
 
 func TestExtractTrafficClass(t *testing.T) {
@@ -33,3 +45,734 @@ func TestExtractTrafficClass(t *testing.T) {
 		})
 	}
 }
+
+type mockResolver struct{}
+
+func (m *mockResolver) Resolve(agent bnet.IP, ifID uint32) string {
+	if ifID == 1 {
+		return "xe-0/0/1"
+	}
+
+	return ""
+}
+
+func TestCounterSampleToInterfaceCounters(t *testing.T) {
+	sfs := &SflowServer{
+		ifResolver: &mockResolver{},
+	}
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+
+	ic := sfs.counterSampleToInterfaceCounters(agent, 0, 1000, &sflow.CounterSample{
+		GenericInterfaceCounters: &sflow.GenericInterfaceCounters{
+			IfIndex:     1,
+			IfSpeed:     10000000000,
+			IfInOctets:  100,
+			IfOutOctets: 200,
+		},
+		EthernetCounters: &sflow.EthernetCounters{
+			Dot3StatsFCSErrors: 3,
+		},
+	})
+	assert.Equal(t, &counters.InterfaceCounters{
+		Agent:     agent,
+		Timestamp: 1000,
+		IfIndex:   1,
+		IntName:   "xe-0/0/1",
+		IfSpeed:   10000000000,
+		InOctets:  100,
+		OutOctets: 200,
+		FCSErrors: 3,
+	}, ic)
+
+	ic = sfs.counterSampleToInterfaceCounters(agent, 0, 1000, &sflow.CounterSample{
+		GenericInterfaceCounters: &sflow.GenericInterfaceCounters{
+			IfIndex: 2,
+		},
+	})
+	assert.Equal(t, "2", ic.IntName)
+
+	ic = sfs.counterSampleToInterfaceCounters(agent, 3, 1000, &sflow.CounterSample{
+		GenericInterfaceCounters: &sflow.GenericInterfaceCounters{
+			IfIndex: 2,
+		},
+	})
+	assert.Equal(t, "3/2", ic.IntName)
+
+	assert.Nil(t, sfs.counterSampleToInterfaceCounters(agent, 0, 1000, &sflow.CounterSample{
+		EthernetCounters: &sflow.EthernetCounters{},
+	}))
+}
+
+func TestProcessCounterSamplesQueueFull(t *testing.T) {
+	sfs := newTestServer(&Config{})
+	sfs.counterOutput = make(chan []*counters.InterfaceCounters, 1)
+
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	samples := []*sflow.CounterSample{
+		{
+			GenericInterfaceCounters: &sflow.GenericInterfaceCounters{
+				IfIndex: 1,
+			},
+		},
+		{
+			GenericInterfaceCounters: &sflow.GenericInterfaceCounters{
+				IfIndex: 2,
+			},
+		},
+	}
+
+	sfs.processCounterSamples(agent, 0, samples)
+	sfs.processCounterSamples(agent, 0, samples)
+
+	assert.Len(t, sfs.counterOutput, 1)
+	assert.Equal(t, float64(2), testutil.ToFloat64(sfs.counterSamplesDropped))
+}
+
+func TestSetGatewayData(t *testing.T) {
+	tests := []struct {
+		name     string
+		egd      *sflow.ExtendedGatewayData
+		expected *flow.Flow
+	}{
+		{
+			name: "Remote destination",
+			egd: &sflow.ExtendedGatewayData{
+				AS:    65000,
+				SrcAS: 65001,
+				ASPath: []sflow.ASPathSegment{
+					{
+						Type: sflow.ASPathSegmentTypeSequence,
+						ASNs: []uint32{3257, 2914},
+					},
+					{
+						Type: sflow.ASPathSegmentTypeSet,
+						ASNs: []uint32{15169},
+					},
+				},
+				Communities: []uint32{65000<<16 | 100},
+			},
+			expected: &flow.Flow{
+				SrcAs:       65001,
+				DstAs:       15169,
+				NextAs:      3257,
+				ASPath:      []uint32{3257, 2914, 15169},
+				Communities: []uint32{65000<<16 | 100},
+			},
+		},
+		{
+			name: "Local destination",
+			egd: &sflow.ExtendedGatewayData{
+				AS:    65000,
+				SrcAS: 65001,
+			},
+			expected: &flow.Flow{
+				SrcAs:  65001,
+				DstAs:  65000,
+				ASPath: []uint32{},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		fl := &flow.Flow{}
+		setGatewayData(fl, test.egd)
+		assert.Equal(t, test.expected, fl, test.name)
+	}
+}
+
+// newTestServer creates a `SflowServer` with unregistered metrics
+func newTestServer(cfg *Config) *SflowServer {
+	newCounterVec := func(labelNames ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test"}, labelNames)
+	}
+
+	return &SflowServer{
+		cfg:                      cfg,
+		ifResolver:               &mockResolver{},
+		datagramSequences:        sequence.New(maxSequenceGap, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
+		sampleSequences:          sequence.New(maxSequenceGap, sequence.DefaultReorderTimeout, sequence.DefaultRatioInterval),
+		flowUnknownProtocol:      newCounterVec("agent", "header_protocol"),
+		flowMPLSDecodeErrors:     newCounterVec("agent"),
+		flowEthernetDecodeErrors: newCounterVec("agent"),
+		flowUnknownEtherType:     newCounterVec("agent"),
+		flowDot1qDecodeErrors:    newCounterVec("agent"),
+		flowIPv4DecodeErrors:     newCounterVec("agent"),
+		flowIPv6DecodeErrors:     newCounterVec("agent"),
+		flowTCPDecodeErros:       newCounterVec("agent"),
+		flowUDPDecodeErros:       newCounterVec("agent"),
+		flowICMPDecodeErrors:     newCounterVec("agent"),
+		flowTunnelDecodeErrors:   newCounterVec("agent"),
+		lostDatagrams:            newCounterVec("agent"),
+		lostSamples:              newCounterVec("agent"),
+		sequenceRestarts:         newCounterVec("agent"),
+		rejectedPackets:          newCounterVec("agent", "reason"),
+		counterSamplesReceived:   newCounterVec("agent"),
+		counterSamplesDropped:    prometheus.NewCounter(prometheus.CounterOpts{Name: "test"}),
+	}
+}
+
+// rawHeaderSflowPacket builds an sflow packet carrying a single raw packet header record
+func rawHeaderSflowPacket(headerProtocol uint8, header []byte) []byte {
+	recordLen := uint8(16 + len(header))
+	res := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent Address Type
+		10, 205, 19, 14, // Agent Address
+		0, 0, 0, 0, // Sub-AgentID
+		0, 0, 0, 222, // Sequence Number
+		0, 0, 0, 111, // SysUpTime
+		0, 0, 0, 1, // NumSamples
+
+		0, 0, 0, 1, // Enterprise/Type (Flow sample)
+		0, 0, 0, 40 + recordLen, // Sample length
+		0, 0, 0, 7, // Sequence Number
+		0, 0, 0, 3, // Source ID + Index
+		0, 0, 3, 232, // Sampling Rate
+		0, 0, 16, 0, // Sampling Pool
+		0, 0, 0, 0, // Dropped Packets
+		0, 0, 0, 3, // Input interface
+		0, 0, 0, 4, // Output interface
+		0, 0, 0, 1, // Flow Record count
+
+		0, 0, 0, 1, // Enterprise/Type (Raw packet header)
+		0, 0, 0, recordLen, // Flow Data Length
+		0, 0, 0, headerProtocol, // Header Protocol
+		0, 0, 5, 220, // Frame Length
+		0, 0, 0, 4, // Payload removed
+		0, 0, 0, uint8(len(header)), // Original packet length
+	}
+
+	return append(res, header...)
+}
+
+var ipv4UDP = []byte{
+	0x45, 0x00, 0x05, 0xd8, // Version, IHL, TOS, Total Length
+	0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+	0x40, 0x11, 0x00, 0x00, // TTL, Protocol, Header Checksum
+	192, 0, 2, 1, // Source Address
+	198, 51, 100, 2, // Destination Address
+	0x04, 0xd2, 0x00, 0x35, // Source Port, Destination Port
+	0x05, 0xc4, 0x00, 0x00, // Length, Checksum
+}
+
+var ipv6TCP = []byte{
+	0x60, 0x00, 0x00, 0x00, // Version, Traffic Class, Flow Label
+	0x05, 0xb4, 0x06, 0x40, // Payload Length, Next Header, Hop Limit
+	0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, // Source Address
+	0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, // Destination Address
+	0x00, 0x50, 0xc3, 0x50, // Source Port, Destination Port
+	0, 0, 0, 1, // Sequence Number
+	0, 0, 0, 0, // ACK Number
+	0x50, 0x10, 0xff, 0xff, // Data Offset, Flags, Window
+	0, 0, 0, 0, // Checksum, Urgent Pointer
+}
+
+func TestProcessRawPacketHeader(t *testing.T) {
+	tests := []struct {
+		name           string
+		headerProtocol uint8
+		header         []byte
+		wantOK         bool
+		expected       *flow.Flow
+	}{
+		{
+			name:           "IPv4",
+			headerProtocol: sflow.HeaderProtocolIPv4,
+			header:         ipv4UDP,
+			wantOK:         true,
+			expected: &flow.Flow{
+				Family:   4,
+				SrcAddr:  bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:  bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol: 17,
+				SrcPort:  1234,
+				DstPort:  53,
+			},
+		},
+		{
+			name:           "IPv6",
+			headerProtocol: sflow.HeaderProtocolIPv6,
+			header:         ipv6TCP,
+			wantOK:         true,
+			expected: &flow.Flow{
+				Family:   6,
+				SrcAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+				DstAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 2),
+				Protocol: 6,
+				TCPFlags: packet.TCPFlagACK,
+				SrcPort:  80,
+				DstPort:  50000,
+			},
+		},
+		{
+			name:           "MPLS",
+			headerProtocol: sflow.HeaderProtocolMPLS,
+			header: append([]byte{
+				0x00, 0x3e, 0x80, 0x40, // Label 1000, TTL 64
+				0x00, 0x01, 0x41, 0x3f, // Label 20, BoS, TTL 63
+			}, ipv4UDP...),
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:          4,
+				SrcAddr:         bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:         bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol:        17,
+				SrcPort:         1234,
+				DstPort:         53,
+				MPLSTopLabel:    1000,
+				MPLSBottomLabel: 20,
+			},
+		},
+		{
+			name:           "Ethernet MPLS",
+			headerProtocol: sflow.HeaderProtocolEthernet,
+			header: append([]byte{
+				0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+				0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+				0x88, 0x47, // EtherType
+				0x00, 0x3e, 0x81, 0x40, // Label 1000, BoS, TTL 64
+			}, ipv6TCP...),
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:       6,
+				SrcAddr:      bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+				DstAddr:      bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 2),
+				Protocol:     6,
+				TCPFlags:     packet.TCPFlagACK,
+				SrcPort:      80,
+				DstPort:      50000,
+				MPLSTopLabel: 1000,
+			},
+		},
+		{
+			name:           "ICMP",
+			headerProtocol: sflow.HeaderProtocolIPv4,
+			header: []byte{
+				0x45, 0x00, 0x00, 0x38, // Version, IHL, TOS, Total Length
+				0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+				0x40, 0x01, 0x00, 0x00, // TTL, Protocol, Header Checksum
+				192, 0, 2, 1, // Source Address
+				198, 51, 100, 2, // Destination Address
+				0x03, 0x01, 0x00, 0x00, // Type, Code, Checksum
+			},
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:   4,
+				SrcAddr:  bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:  bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol: 1,
+				ICMPType: 3,
+				ICMPCode: 1,
+			},
+		},
+		{
+			name:           "IPv6 with extension headers",
+			headerProtocol: sflow.HeaderProtocolIPv6,
+			header: append([]byte{
+				0x60, 0x00, 0x00, 0x00, // Version, Traffic Class, Flow Label
+				0x05, 0xb4, 0x00, 0x40, // Payload Length, Next Header (Hop-by-hop), Hop Limit
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, // Source Address
+				0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, // Destination Address
+				0x2c, 0x00, 0x01, 0x04, 0x00, 0x00, 0x00, 0x00, // Hop-by-hop
+				0x06, 0x00, 0x00, 0x01, 0x12, 0x34, 0x56, 0x78, // Fragment (first)
+			}, ipv6TCP[40:]...),
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:   6,
+				SrcAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+				DstAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 2),
+				Protocol: 6,
+				TCPFlags: packet.TCPFlagACK,
+				Fragment: true,
+				SrcPort:  80,
+				DstPort:  50000,
+			},
+		},
+		{
+			name:           "IPv4 non-first fragment",
+			headerProtocol: sflow.HeaderProtocolIPv4,
+			header: []byte{
+				0x45, 0x00, 0x05, 0xd8, // Version, IHL, TOS, Total Length
+				0x00, 0x01, 0x00, 0xb9, // Identification, Flags, Fragment Offset
+				0x40, 0x11, 0x00, 0x00, // TTL, Protocol, Header Checksum
+				192, 0, 2, 1, // Source Address
+				198, 51, 100, 2, // Destination Address
+				0xde, 0xad, 0xbe, 0xef, // Payload
+			},
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:   4,
+				SrcAddr:  bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:  bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol: 17,
+				Fragment: true,
+			},
+		},
+		{
+			name:           "IPv4 with options",
+			headerProtocol: sflow.HeaderProtocolIPv4,
+			header: append([]byte{
+				0x46, 0x00, 0x05, 0xd8, // Version, IHL, TOS, Total Length
+				0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+				0x40, 0x11, 0x00, 0x00, // TTL, Protocol, Header Checksum
+				192, 0, 2, 1, // Source Address
+				198, 51, 100, 2, // Destination Address
+				0x94, 0x04, 0x00, 0x00, // Router Alert option
+			}, ipv4UDP[20:]...),
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:   4,
+				SrcAddr:  bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:  bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol: 17,
+				SrcPort:  1234,
+				DstPort:  53,
+			},
+		},
+		{
+			name:           "Ethernet QinQ",
+			headerProtocol: sflow.HeaderProtocolEthernet,
+			header: append([]byte{
+				0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+				0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+				0x88, 0xa8, // EtherType (802.1ad)
+				0x00, 0x64, // TCI (VLAN 100)
+				0x81, 0x00, // EtherType (802.1Q)
+				0x20, 0xc8, // TCI (PCP 1, VLAN 200)
+				0x08, 0x00, // EtherType
+			}, ipv4UDP...),
+			wantOK: true,
+			expected: &flow.Flow{
+				Family:    4,
+				SrcAddr:   bnet.IPv4FromOctets(192, 0, 2, 1),
+				DstAddr:   bnet.IPv4FromOctets(198, 51, 100, 2),
+				Protocol:  17,
+				SrcPort:   1234,
+				DstPort:   53,
+				VLANOuter: 100,
+				VLANInner: 200,
+			},
+		},
+		{
+			name:           "Unsupported protocol",
+			headerProtocol: 2,
+			header:         ipv4UDP,
+			wantOK:         false,
+			expected:       &flow.Flow{},
+		},
+	}
+
+	for _, test := range tests {
+		sfs := newTestServer(&Config{})
+		p, err := sflow.Decode(rawHeaderSflowPacket(test.headerProtocol, test.header))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		fs := p.FlowSamples[0]
+		assert.Equal(t, uint32(test.headerProtocol), fs.RawPacketHeader.HeaderProtocol, test.name)
+
+		fl := &flow.Flow{}
+		ok := sfs.processRawPacketHeader("192.0.2.1", fs, fl)
+		assert.Equal(t, test.wantOK, ok, test.name)
+		assert.Equal(t, test.expected, fl, test.name)
+	}
+}
+
+func TestDecapsulation(t *testing.T) {
+	vxlan := append([]byte{
+		0x45, 0x00, 0x05, 0xd8, // Version, IHL, TOS, Total Length
+		0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+		0x40, 0x11, 0x00, 0x00, // TTL, Protocol, Header Checksum
+		10, 0, 0, 1, // Source Address
+		10, 0, 0, 2, // Destination Address
+		0xc3, 0x50, 0x12, 0xb5, // Source Port, Destination Port
+		0x05, 0xc4, 0x00, 0x00, // Length, Checksum
+		0x08, 0x00, 0x00, 0x00, // VXLAN Flags, Reserved
+		0x01, 0x86, 0xa0, 0x00, // VNI, Reserved
+		0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+		0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+		0x08, 0x00, // EtherType
+	}, ipv4UDP...)
+
+	vxlanDot1Q := append([]byte{
+		0x80, 0x71, 0x1f, 0x7f, 0x02, 0x95, // Destination MAC
+		0x20, 0x4e, 0x71, 0x04, 0x1c, 0xba, // Source MAC
+		0x81, 0x00, // EtherType (802.1Q)
+		0x00, 0x0a, // TCI (VLAN 10)
+		0x08, 0x00, // EtherType
+		0x45, 0x00, 0x05, 0xdc, // Version, IHL, TOS, Total Length
+		0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+		0x40, 0x11, 0x00, 0x00, // TTL, Protocol, Header Checksum
+		10, 0, 0, 1, // Source Address
+		10, 0, 0, 2, // Destination Address
+		0xc3, 0x50, 0x12, 0xb5, // Source Port, Destination Port
+		0x05, 0xc8, 0x00, 0x00, // Length, Checksum
+		0x08, 0x00, 0x00, 0x00, // VXLAN Flags, Reserved
+		0x01, 0x86, 0xa0, 0x00, // VNI, Reserved
+		0x80, 0x71, 0x1f, 0x7f, 0x02, 0x94, // Destination MAC
+		0x20, 0x4e, 0x71, 0x04, 0x1c, 0xb9, // Source MAC
+		0x81, 0x00, // EtherType (802.1Q)
+		0x01, 0x2c, // TCI (VLAN 300)
+		0x08, 0x00, // EtherType
+	}, ipv4UDP...)
+
+	gre := append([]byte{
+		0x45, 0x00, 0x05, 0xd8, // Version, IHL, TOS, Total Length
+		0x00, 0x01, 0x00, 0x00, // Identification, Flags, Fragment Offset
+		0x40, 0x2f, 0x00, 0x00, // TTL, Protocol, Header Checksum
+		10, 0, 0, 1, // Source Address
+		10, 0, 0, 2, // Destination Address
+		0x20, 0x00, 0x86, 0xdd, // GRE Flags, Protocol Type
+		0x00, 0x00, 0x00, 0x2a, // GRE Key
+	}, ipv6TCP...)
+
+	outerVXLAN := flow.FiveTuple{
+		SrcAddr:  bnet.IPv4FromOctets(10, 0, 0, 1),
+		DstAddr:  bnet.IPv4FromOctets(10, 0, 0, 2),
+		SrcPort:  50000,
+		DstPort:  4789,
+		Protocol: 17,
+	}
+
+	innerVXLAN := flow.FiveTuple{
+		SrcAddr:  bnet.IPv4FromOctets(192, 0, 2, 1),
+		DstAddr:  bnet.IPv4FromOctets(198, 51, 100, 2),
+		SrcPort:  1234,
+		DstPort:  53,
+		Protocol: 17,
+	}
+
+	outerGRE := flow.FiveTuple{
+		SrcAddr:  bnet.IPv4FromOctets(10, 0, 0, 1),
+		DstAddr:  bnet.IPv4FromOctets(10, 0, 0, 2),
+		Protocol: 47,
+	}
+
+	innerGRE := flow.FiveTuple{
+		SrcAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+		DstAddr:  bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 2),
+		SrcPort:  80,
+		DstPort:  50000,
+		Protocol: 6,
+	}
+
+	tests := []struct {
+		name           string
+		cfg            *Config
+		headerProtocol uint8
+		header         []byte
+		expected       *flow.Flow
+	}{
+		{
+			name:   "VXLAN disabled",
+			cfg:    &Config{},
+			header: vxlan,
+			expected: &flow.Flow{
+				Family:   4,
+				SrcAddr:  outerVXLAN.SrcAddr,
+				DstAddr:  outerVXLAN.DstAddr,
+				SrcPort:  outerVXLAN.SrcPort,
+				DstPort:  outerVXLAN.DstPort,
+				Protocol: outerVXLAN.Protocol,
+			},
+		},
+		{
+			name: "VXLAN inner primary",
+			cfg: &Config{
+				DecapsulateTunnels: true,
+				TunnelPrimaryInner: true,
+			},
+			header: vxlan,
+			expected: &flow.Flow{
+				Family:     4,
+				SrcAddr:    innerVXLAN.SrcAddr,
+				DstAddr:    innerVXLAN.DstAddr,
+				SrcPort:    innerVXLAN.SrcPort,
+				DstPort:    innerVXLAN.DstPort,
+				Protocol:   innerVXLAN.Protocol,
+				TunnelType: flow.TunnelTypeVXLAN,
+				TunnelKey:  100000,
+				Outer:      outerVXLAN,
+				Inner:      innerVXLAN,
+			},
+		},
+		{
+			name: "VXLAN with inner 802.1Q tag outer primary",
+			cfg: &Config{
+				DecapsulateTunnels: true,
+			},
+			headerProtocol: sflow.HeaderProtocolEthernet,
+			header:         vxlanDot1Q,
+			expected: &flow.Flow{
+				Family:     4,
+				SrcAddr:    outerVXLAN.SrcAddr,
+				DstAddr:    outerVXLAN.DstAddr,
+				SrcPort:    outerVXLAN.SrcPort,
+				DstPort:    outerVXLAN.DstPort,
+				Protocol:   outerVXLAN.Protocol,
+				VLANOuter:  10,
+				TunnelType: flow.TunnelTypeVXLAN,
+				TunnelKey:  100000,
+				Outer:      outerVXLAN,
+				Inner:      innerVXLAN,
+			},
+		},
+		{
+			name: "GRE outer primary",
+			cfg: &Config{
+				DecapsulateTunnels: true,
+			},
+			header: gre,
+			expected: &flow.Flow{
+				Family:     4,
+				SrcAddr:    outerGRE.SrcAddr,
+				DstAddr:    outerGRE.DstAddr,
+				Protocol:   outerGRE.Protocol,
+				TunnelType: flow.TunnelTypeGRE,
+				TunnelKey:  42,
+				Outer:      outerGRE,
+				Inner:      innerGRE,
+			},
+		},
+		{
+			name: "GRE inner primary",
+			cfg: &Config{
+				DecapsulateTunnels: true,
+				TunnelPrimaryInner: true,
+			},
+			header: gre,
+			expected: &flow.Flow{
+				Family:     6,
+				SrcAddr:    innerGRE.SrcAddr,
+				DstAddr:    innerGRE.DstAddr,
+				SrcPort:    innerGRE.SrcPort,
+				DstPort:    innerGRE.DstPort,
+				Protocol:   innerGRE.Protocol,
+				TCPFlags:   packet.TCPFlagACK,
+				TunnelType: flow.TunnelTypeGRE,
+				TunnelKey:  42,
+				Outer:      outerGRE,
+				Inner:      innerGRE,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		headerProtocol := test.headerProtocol
+		if headerProtocol == 0 {
+			headerProtocol = sflow.HeaderProtocolIPv4
+		}
+
+		sfs := newTestServer(test.cfg)
+		p, err := sflow.Decode(rawHeaderSflowPacket(headerProtocol, test.header))
+		if !assert.NoError(t, err, test.name) {
+			continue
+		}
+
+		fl := &flow.Flow{}
+		assert.True(t, sfs.processRawPacketHeader("192.0.2.1", p.FlowSamples[0], fl), test.name)
+		assert.Equal(t, test.expected, fl, test.name)
+	}
+}
+
+func TestSequenceTracking(t *testing.T) {
+	sfs := newTestServer(&Config{})
+	agent := bnet.IPv4FromOctets(192, 0, 2, 1)
+	now := time.Unix(1700000000, 0)
+
+	datagrams := []struct {
+		sequenceNumber uint32
+		offset         time.Duration
+		samples        []uint32
+	}{
+		{sequenceNumber: 1, samples: []uint32{10, 11}},
+		{sequenceNumber: 2, samples: []uint32{12}},
+		{sequenceNumber: 5, samples: []uint32{17, 18}},
+		{sequenceNumber: 3, samples: []uint32{13}},
+		{sequenceNumber: 6, offset: 2 * time.Second, samples: []uint32{19}},
+	}
+
+	lossRatio := float32(0)
+	for _, d := range datagrams {
+		ts := now.Add(d.offset)
+		sfs.trackDatagram(agent, &sflow.Header{SubAgentID: 1, SequenceNumber: d.sequenceNumber}, ts)
+		for _, seq := range d.samples {
+			lossRatio = sfs.trackFlowSample(agent, 1, &sflow.FlowSampleHeader{SourceIDClassIndex: 3, SequenceNumber: seq}, ts)
+		}
+	}
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(sfs.lostDatagrams.WithLabelValues("192.0.2.1")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(sfs.lostSamples.WithLabelValues("192.0.2.1")))
+	assert.Equal(t, float32(3)/float32(10), lossRatio)
+	assert.Equal(t, float64(0), testutil.ToFloat64(sfs.sequenceRestarts.WithLabelValues("192.0.2.1")))
+
+	sfs.trackDatagram(agent, &sflow.Header{SubAgentID: 1, SequenceNumber: 4000000}, now.Add(3*time.Second))
+	assert.Equal(t, float64(1), testutil.ToFloat64(sfs.sequenceRestarts.WithLabelValues("192.0.2.1")))
+}
+
+func TestAccept(t *testing.T) {
+	filter := agentfilter.New(agentfilter.Config{
+		KnownAgentsOnly: true,
+		RateLimit:       1,
+	})
+	filter.Add(bnet.IPv4FromOctets(10, 205, 19, 14))
+	sfs := newTestServer(&Config{
+		AgentFilter: filter,
+	})
+
+	now := time.Unix(1700000000, 0)
+	datagram := []byte{
+		0, 0, 0, 5, // Version
+		0, 0, 0, 1, // Agent address type = IPv4
+		10, 205, 19, 14, // Agent address
+	}
+
+	// Known by the agent address of the header
+	assert.True(t, sfs.accept(bnet.IPv4FromOctets(192, 0, 2, 1), datagram, now))
+	assert.False(t, sfs.accept(bnet.IPv4FromOctets(192, 0, 2, 1), datagram, now))
+	assert.Equal(t, float64(1), testutil.ToFloat64(sfs.rejectedPackets.WithLabelValues("10.205.19.14", agentfilter.ReasonRateLimited)))
+
+	datagram[11] = 15
+	assert.False(t, sfs.accept(bnet.IPv4FromOctets(192, 0, 2, 2), datagram, now))
+	assert.False(t, sfs.accept(bnet.IPv4FromOctets(192, 0, 2, 2), datagram[:6], now))
+	assert.Equal(t, float64(2), testutil.ToFloat64(sfs.rejectedPackets.WithLabelValues("", agentfilter.ReasonUnknownAgent)))
+}
+
+func TestAgentIdentity(t *testing.T) {
+	remote := bnet.IPv4FromOctets(192, 0, 2, 1)
+	tests := []struct {
+		name         string
+		cfg          *Config
+		agentAddress []byte
+		expected     bnet.IP
+	}{
+		{
+			name:         "source by default",
+			cfg:          &Config{},
+			agentAddress: []byte{10, 205, 19, 14},
+			expected:     remote,
+		},
+		{
+			name: "agent address",
+			cfg: &Config{
+				AgentIdentity: AgentIdentityAgentAddress,
+			},
+			agentAddress: []byte{10, 205, 19, 14},
+			expected:     bnet.IPv4FromOctets(10, 205, 19, 14),
+		},
+		{
+			name: "unspecified agent address",
+			cfg: &Config{
+				AgentIdentity: AgentIdentityAgentAddress,
+			},
+			agentAddress: []byte{0, 0, 0, 0},
+			expected:     remote,
+		},
+	}
+
+	for _, test := range tests {
+		sfs := newTestServer(test.cfg)
+		assert.Equal(t, test.expected, sfs.agentIdentity(remote, &sflow.Header{AgentAddress: test.agentAddress}), test.name)
+	}
+}
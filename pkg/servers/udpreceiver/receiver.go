@@ -0,0 +1,229 @@
+// Package udpreceiver reads datagrams from one or more SO_REUSEPORT UDP sockets in batches
+package udpreceiver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultBatchSize is the default number of datagrams read per system call
+	DefaultBatchSize = 64
+
+	// maxDatagramSize is the size of the receive buffers of datagrams
+	maxDatagramSize = 8960
+)
+
+// Config is the configuration of a `Receiver`
+type Config struct {
+	// ReceiveBuffer is the receive buffer size (SO_RCVBUF) of each socket in bytes. 0 keeps the system default.
+	ReceiveBuffer int
+
+	// BatchSize is the number of datagrams read per system call (recvmmsg). Defaults to DefaultBatchSize.
+	BatchSize int
+}
+
+// Handler processes a datagram received from remote. buf is reused once the handler returns.
+type Handler func(remote bnet.IP, buf []byte)
+
+// batchReader is implemented by ipv4.PacketConn and ipv6.PacketConn
+type batchReader interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+type socket struct {
+	conn      net.PacketConn
+	reader    batchReader
+	lastDrops uint32
+}
+
+// Receiver reads datagrams from a set of sockets bound to the same address and passes them to a handler.
+// Each socket is read by its own goroutine. The kernel balances datagrams across the sockets by source.
+type Receiver struct {
+	cfg         Config
+	sockets     []*socket
+	handler     Handler
+	socketDrops prometheus.Counter
+	wg          sync.WaitGroup
+}
+
+// New opens numSockets sockets listening on listen. Datagrams dropped by the kernel due to full
+// receive buffers are counted in socketDrops. Platforms not supporting SO_REUSEPORT use a single
+// socket read by numSockets goroutines.
+func New(listen string, numSockets int, cfg Config, handler Handler, socketDrops prometheus.Counter) (*Receiver, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+
+	if numSockets <= 0 {
+		numSockets = 1
+	}
+
+	r := &Receiver{
+		cfg:         cfg,
+		handler:     handler,
+		socketDrops: socketDrops,
+	}
+
+	for i := 0; i < numSockets; i++ {
+		if i > 0 && !reusePortSupported {
+			r.sockets = append(r.sockets, r.sockets[0])
+			continue
+		}
+
+		// Bind further sockets to the port chosen for the first one in case listen has port 0
+		if i > 0 {
+			listen = r.sockets[0].conn.LocalAddr().String()
+		}
+
+		s, err := r.listen(listen)
+		if err != nil {
+			r.close()
+			return nil, errors.Wrapf(err, "Unable to open socket %d", i)
+		}
+
+		r.sockets = append(r.sockets, s)
+	}
+
+	return r, nil
+}
+
+func (r *Receiver) listen(listen string) (*socket, error) {
+	lc := net.ListenConfig{
+		Control: control,
+	}
+
+	conn, err := lc.ListenPacket(context.Background(), "udp", listen)
+	if err != nil {
+		return nil, errors.Wrap(err, "ListenPacket failed")
+	}
+
+	udpConn := conn.(*net.UDPConn)
+	if r.cfg.ReceiveBuffer > 0 {
+		err := udpConn.SetReadBuffer(r.cfg.ReceiveBuffer)
+		if err != nil {
+			conn.Close()
+			return nil, errors.Wrap(err, "Unable to set receive buffer size")
+		}
+
+		checkReceiveBuffer(udpConn, r.cfg.ReceiveBuffer)
+	}
+
+	s := &socket{
+		conn:   conn,
+		reader: ipv4.NewPacketConn(conn),
+	}
+
+	if conn.LocalAddr().(*net.UDPAddr).IP.To4() == nil {
+		s.reader = ipv6.NewPacketConn(conn)
+	}
+
+	return s, nil
+}
+
+// LocalAddr gets the address the sockets are bound to
+func (r *Receiver) LocalAddr() net.Addr {
+	return r.sockets[0].conn.LocalAddr()
+}
+
+// Start starts reading from the sockets
+func (r *Receiver) Start() {
+	for _, s := range r.sockets {
+		r.wg.Add(1)
+		go func(s *socket) {
+			defer r.wg.Done()
+			err := r.reader(s)
+			if err != nil {
+				log.WithError(err).Error("UDP reader failed")
+			}
+		}(s)
+	}
+}
+
+// Stop closes the sockets and waits for the readers to finish
+func (r *Receiver) Stop() {
+	r.close()
+	r.wg.Wait()
+}
+
+func (r *Receiver) close() {
+	for _, s := range r.sockets {
+		s.conn.Close()
+	}
+}
+
+// reader reads batches of datagrams from s and hands them off to the handler
+func (r *Receiver) reader(s *socket) error {
+	msgs := make([]ipv4.Message, r.cfg.BatchSize)
+	for i := range msgs {
+		msgs[i].Buffers = [][]byte{make([]byte, maxDatagramSize)}
+		msgs[i].OOB = make([]byte, oobSize)
+	}
+
+	for {
+		n, err := s.reader.ReadBatch(msgs, 0)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "ReadBatch failed")
+		}
+
+		for _, m := range msgs[:n] {
+			r.updateDrops(s, m.OOB[:m.NN])
+
+			remote, err := remoteIP(m.Addr)
+			if err != nil {
+				log.WithError(err).Debug("Unable to get remote address")
+				continue
+			}
+
+			r.handler(remote, m.Buffers[0][:m.N])
+		}
+	}
+}
+
+// updateDrops accounts the drop counter of the socket carried in the control messages of a datagram
+func (r *Receiver) updateDrops(s *socket, oob []byte) {
+	drops, ok := parseDrops(oob)
+	if !ok {
+		return
+	}
+
+	// Readers sharing a socket may observe the counter going backwards. Such deltas are ignored.
+	delta := drops - atomic.SwapUint32(&s.lastDrops, drops)
+	if delta > 0 && delta < 1<<31 && r.socketDrops != nil {
+		r.socketDrops.Add(float64(delta))
+	}
+}
+
+// remoteIP converts the source address of a datagram into an IP
+func remoteIP(addr net.Addr) (bnet.IP, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return bnet.IP{}, errors.Errorf("Unexpected address type %T", addr)
+	}
+
+	ip := udpAddr.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	remote, err := bnet.IPFromBytes([]byte(ip))
+	if err != nil {
+		return bnet.IP{}, errors.Wrapf(err, "Unable to convert net.IP to bnet.IP: %q", udpAddr)
+	}
+
+	return remote, nil
+}
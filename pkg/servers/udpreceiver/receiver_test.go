@@ -0,0 +1,94 @@
+package udpreceiver
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func TestReceiver(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]bnet.IP)
+
+	r, err := New("127.0.0.1:0", 2, Config{BatchSize: 4}, func(remote bnet.IP, buf []byte) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[string(buf)] = remote
+	}, nil)
+	if err != nil {
+		t.Fatalf("Unable to create receiver: %v", err)
+	}
+
+	r.Start()
+
+	conn, err := net.Dial("udp", r.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	payloads := []string{"foo", "bar", "baz", "qux", "quux"}
+	for _, p := range payloads {
+		_, err := conn.Write([]byte(p))
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == len(payloads)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	r.Stop()
+
+	for _, p := range payloads {
+		assert.Equal(t, bnet.IPv4FromOctets(127, 0, 0, 1), received[p], p)
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     net.Addr
+		expected bnet.IP
+		wantFail bool
+	}{
+		{
+			name: "IPv4",
+			addr: &net.UDPAddr{
+				IP: net.IPv4(192, 0, 2, 1),
+			},
+			expected: bnet.IPv4FromOctets(192, 0, 2, 1),
+		},
+		{
+			name: "IPv6",
+			addr: &net.UDPAddr{
+				IP: net.ParseIP("2001:db8::1"),
+			},
+			expected: bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+		},
+		{
+			name:     "unexpected address type",
+			addr:     &net.TCPAddr{},
+			wantFail: true,
+		},
+	}
+
+	for _, test := range tests {
+		ip, err := remoteIP(test.addr)
+		if test.wantFail {
+			assert.Error(t, err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, ip, test.name)
+	}
+}
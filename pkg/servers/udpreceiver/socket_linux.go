@@ -0,0 +1,76 @@
+package udpreceiver
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const reusePortSupported = true
+
+// oobSize is the size of the control message buffer carrying the drop counter (SO_RXQ_OVFL)
+var oobSize = unix.CmsgSpace(4)
+
+// control enables SO_REUSEPORT and the reporting of the socket drop counter on a socket
+func control(network string, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+		if sockErr != nil {
+			return
+		}
+
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RXQ_OVFL, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
+
+// checkReceiveBuffer warns if the kernel capped the receive buffer size (net.core.rmem_max)
+func checkReceiveBuffer(conn *net.UDPConn, size int) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+
+	actual := 0
+	rc.Control(func(fd uintptr) {
+		// The kernel doubles the requested size to allow for bookkeeping overhead
+		actual, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+		actual /= 2
+	})
+
+	if err == nil && actual < size {
+		log.WithFields(log.Fields{
+			"requested": size,
+			"actual":    actual,
+		}).Warning("Receive buffer size capped by kernel. Consider raising net.core.rmem_max.")
+	}
+}
+
+// parseDrops gets the number of datagrams dropped by the socket from the control messages of a datagram
+func parseDrops(oob []byte) (uint32, bool) {
+	if len(oob) == 0 {
+		return 0, false
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, m := range msgs {
+		if m.Header.Level == unix.SOL_SOCKET && m.Header.Type == unix.SO_RXQ_OVFL && len(m.Data) >= 4 {
+			return binary.NativeEndian.Uint32(m.Data), true
+		}
+	}
+
+	return 0, false
+}
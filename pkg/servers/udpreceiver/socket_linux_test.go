@@ -0,0 +1,41 @@
+package udpreceiver
+
+import (
+	"encoding/binary"
+	"testing"
+	"unsafe"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func dropsControlMessage(drops uint32) []byte {
+	oob := make([]byte, unix.CmsgSpace(4))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	h.Level = unix.SOL_SOCKET
+	h.Type = unix.SO_RXQ_OVFL
+	h.SetLen(unix.CmsgLen(4))
+	binary.NativeEndian.PutUint32(oob[unix.CmsgLen(0):], drops)
+
+	return oob
+}
+
+func TestUpdateDrops(t *testing.T) {
+	drops := prometheus.NewCounter(prometheus.CounterOpts{Name: "drops"})
+	r := &Receiver{
+		socketDrops: drops,
+	}
+	s := &socket{}
+
+	r.updateDrops(s, nil)
+	r.updateDrops(s, dropsControlMessage(5))
+	r.updateDrops(s, dropsControlMessage(5))
+	r.updateDrops(s, dropsControlMessage(12))
+
+	// Counter observed going backwards by a reader sharing the socket
+	r.updateDrops(s, dropsControlMessage(10))
+
+	assert.Equal(t, float64(12), testutil.ToFloat64(drops))
+}
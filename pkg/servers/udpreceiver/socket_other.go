@@ -0,0 +1,23 @@
+//go:build !linux
+
+package udpreceiver
+
+import (
+	"net"
+	"syscall"
+)
+
+const reusePortSupported = false
+
+// oobSize is the size of the control message buffer. Drop counters are only supported on Linux.
+var oobSize = 0
+
+func control(network string, address string, c syscall.RawConn) error {
+	return nil
+}
+
+func checkReceiveBuffer(conn *net.UDPConn, size int) {}
+
+func parseDrops(oob []byte) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,381 @@
+package spool
+
+import (
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+// ip is the serializable form of an IP address. Addr is empty for the zero value.
+type ip struct {
+	Addr []byte
+}
+
+// prefix is the serializable form of a prefix. Addr is empty for the zero value.
+type prefix struct {
+	Addr   []byte
+	Pfxlen uint8
+}
+
+type fiveTuple struct {
+	SrcAddr  ip
+	DstAddr  ip
+	SrcPort  uint16
+	DstPort  uint16
+	Protocol uint8
+}
+
+// record is the serializable form of a flow. bnet types have no exported fields and can not be encoded directly.
+type record struct {
+	Agent           ip
+	SubAgentID      uint32
+	TOS             uint8
+	SrcPort         uint16
+	DstPort         uint16
+	SrcAs           uint32
+	DstAs           uint32
+	NextAs          uint32
+	IntIn           string
+	IntOut          string
+	VLANIn          uint16
+	VLANOut         uint16
+	VLANOuter       uint16
+	VLANInner       uint16
+	Packets         uint64
+	Protocol        uint8
+	TCPFlags        uint8
+	ICMPType        uint8
+	ICMPCode        uint8
+	Fragment        bool
+	Family          uint8
+	Timestamp       int64
+	Size            uint64
+	Samplerate      uint64
+	SrcAddr         ip
+	DstAddr         ip
+	NextHop         ip
+	SrcPfx          prefix
+	DstPfx          prefix
+	VRFIn           uint64
+	VRFOut          uint64
+	Application     string
+	VRFNameIn       string
+	VRFNameOut      string
+	ASPath          []uint32
+	Communities     []uint32
+	MPLSTopLabel    uint32
+	MPLSBottomLabel uint32
+	TunnelType      uint8
+	TunnelKey       uint32
+	Outer           fiveTuple
+	Inner           fiveTuple
+	LossRatio       float32
+}
+
+// batch is the content of a spool file. It holds either flows or interface counters.
+type batch struct {
+	Flows    []*record
+	Counters []*counterRecord
+}
+
+// counterRecord is the serializable form of interface counters
+type counterRecord struct {
+	Agent              ip
+	Timestamp          int64
+	IfIndex            uint32
+	IntName            string
+	IfType             uint32
+	IfSpeed            uint64
+	IfDirection        uint32
+	IfStatus           uint32
+	InOctets           uint64
+	InUcastPkts        uint32
+	InMulticastPkts    uint32
+	InBroadcastPkts    uint32
+	InDiscards         uint32
+	InErrors           uint32
+	InUnknownProtos    uint32
+	OutOctets          uint64
+	OutUcastPkts       uint32
+	OutMulticastPkts   uint32
+	OutBroadcastPkts   uint32
+	OutDiscards        uint32
+	OutErrors          uint32
+	AlignmentErrors    uint32
+	FCSErrors          uint32
+	SingleCollisions   uint32
+	MultipleCollisions uint32
+	LateCollisions     uint32
+	FrameTooLongs      uint32
+	SymbolErrors       uint32
+}
+
+func newIP(addr bnet.IP) ip {
+	if addr == (bnet.IP{}) {
+		return ip{}
+	}
+
+	return ip{
+		Addr: addr.Bytes(),
+	}
+}
+
+func (i ip) toIP() (bnet.IP, error) {
+	if len(i.Addr) == 0 {
+		return bnet.IP{}, nil
+	}
+
+	return bnet.IPFromBytes(i.Addr)
+}
+
+func newPrefix(pfx bnet.Prefix) prefix {
+	if pfx.Addr() == nil {
+		return prefix{}
+	}
+
+	return prefix{
+		Addr:   pfx.Addr().Bytes(),
+		Pfxlen: pfx.Pfxlen(),
+	}
+}
+
+func (p prefix) toPrefix() (bnet.Prefix, error) {
+	if len(p.Addr) == 0 {
+		return bnet.Prefix{}, nil
+	}
+
+	addr, err := bnet.IPFromBytes(p.Addr)
+	if err != nil {
+		return bnet.Prefix{}, err
+	}
+
+	return bnet.NewPfx(addr, p.Pfxlen), nil
+}
+
+func newFiveTuple(t flow.FiveTuple) fiveTuple {
+	return fiveTuple{
+		SrcAddr:  newIP(t.SrcAddr),
+		DstAddr:  newIP(t.DstAddr),
+		SrcPort:  t.SrcPort,
+		DstPort:  t.DstPort,
+		Protocol: t.Protocol,
+	}
+}
+
+func (t fiveTuple) toFiveTuple() (flow.FiveTuple, error) {
+	src, err := t.SrcAddr.toIP()
+	if err != nil {
+		return flow.FiveTuple{}, err
+	}
+
+	dst, err := t.DstAddr.toIP()
+	if err != nil {
+		return flow.FiveTuple{}, err
+	}
+
+	return flow.FiveTuple{
+		SrcAddr:  src,
+		DstAddr:  dst,
+		SrcPort:  t.SrcPort,
+		DstPort:  t.DstPort,
+		Protocol: t.Protocol,
+	}, nil
+}
+
+func newRecord(fl *flow.Flow) *record {
+	return &record{
+		Agent:           newIP(fl.Agent),
+		SubAgentID:      fl.SubAgentID,
+		TOS:             fl.TOS,
+		SrcPort:         fl.SrcPort,
+		DstPort:         fl.DstPort,
+		SrcAs:           fl.SrcAs,
+		DstAs:           fl.DstAs,
+		NextAs:          fl.NextAs,
+		IntIn:           fl.IntIn,
+		IntOut:          fl.IntOut,
+		VLANIn:          fl.VLANIn,
+		VLANOut:         fl.VLANOut,
+		VLANOuter:       fl.VLANOuter,
+		VLANInner:       fl.VLANInner,
+		Packets:         fl.Packets,
+		Protocol:        fl.Protocol,
+		TCPFlags:        fl.TCPFlags,
+		ICMPType:        fl.ICMPType,
+		ICMPCode:        fl.ICMPCode,
+		Fragment:        fl.Fragment,
+		Family:          fl.Family,
+		Timestamp:       fl.Timestamp,
+		Size:            fl.Size,
+		Samplerate:      fl.Samplerate,
+		SrcAddr:         newIP(fl.SrcAddr),
+		DstAddr:         newIP(fl.DstAddr),
+		NextHop:         newIP(fl.NextHop),
+		SrcPfx:          newPrefix(fl.SrcPfx),
+		DstPfx:          newPrefix(fl.DstPfx),
+		VRFIn:           fl.VRFIn,
+		VRFOut:          fl.VRFOut,
+		Application:     fl.Application,
+		VRFNameIn:       fl.VRFNameIn,
+		VRFNameOut:      fl.VRFNameOut,
+		ASPath:          fl.ASPath,
+		Communities:     fl.Communities,
+		MPLSTopLabel:    fl.MPLSTopLabel,
+		MPLSBottomLabel: fl.MPLSBottomLabel,
+		TunnelType:      fl.TunnelType,
+		TunnelKey:       fl.TunnelKey,
+		Outer:           newFiveTuple(fl.Outer),
+		Inner:           newFiveTuple(fl.Inner),
+		LossRatio:       fl.LossRatio,
+	}
+}
+
+func (r *record) toFlow() (*flow.Flow, error) {
+	fl := &flow.Flow{
+		SubAgentID:      r.SubAgentID,
+		TOS:             r.TOS,
+		SrcPort:         r.SrcPort,
+		DstPort:         r.DstPort,
+		SrcAs:           r.SrcAs,
+		DstAs:           r.DstAs,
+		NextAs:          r.NextAs,
+		IntIn:           r.IntIn,
+		IntOut:          r.IntOut,
+		VLANIn:          r.VLANIn,
+		VLANOut:         r.VLANOut,
+		VLANOuter:       r.VLANOuter,
+		VLANInner:       r.VLANInner,
+		Packets:         r.Packets,
+		Protocol:        r.Protocol,
+		TCPFlags:        r.TCPFlags,
+		ICMPType:        r.ICMPType,
+		ICMPCode:        r.ICMPCode,
+		Fragment:        r.Fragment,
+		Family:          r.Family,
+		Timestamp:       r.Timestamp,
+		Size:            r.Size,
+		Samplerate:      r.Samplerate,
+		VRFIn:           r.VRFIn,
+		VRFOut:          r.VRFOut,
+		Application:     r.Application,
+		VRFNameIn:       r.VRFNameIn,
+		VRFNameOut:      r.VRFNameOut,
+		ASPath:          r.ASPath,
+		Communities:     r.Communities,
+		MPLSTopLabel:    r.MPLSTopLabel,
+		MPLSBottomLabel: r.MPLSBottomLabel,
+		TunnelType:      r.TunnelType,
+		TunnelKey:       r.TunnelKey,
+		LossRatio:       r.LossRatio,
+	}
+
+	var err error
+	for _, x := range []struct {
+		from ip
+		to   *bnet.IP
+	}{
+		{r.Agent, &fl.Agent},
+		{r.SrcAddr, &fl.SrcAddr},
+		{r.DstAddr, &fl.DstAddr},
+		{r.NextHop, &fl.NextHop},
+	} {
+		*x.to, err = x.from.toIP()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fl.SrcPfx, err = r.SrcPfx.toPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	fl.DstPfx, err = r.DstPfx.toPrefix()
+	if err != nil {
+		return nil, err
+	}
+
+	fl.Outer, err = r.Outer.toFiveTuple()
+	if err != nil {
+		return nil, err
+	}
+
+	fl.Inner, err = r.Inner.toFiveTuple()
+	if err != nil {
+		return nil, err
+	}
+
+	return fl, nil
+}
+
+func newCounterRecord(ic *counters.InterfaceCounters) *counterRecord {
+	return &counterRecord{
+		Agent:              newIP(ic.Agent),
+		Timestamp:          ic.Timestamp,
+		IfIndex:            ic.IfIndex,
+		IntName:            ic.IntName,
+		IfType:             ic.IfType,
+		IfSpeed:            ic.IfSpeed,
+		IfDirection:        ic.IfDirection,
+		IfStatus:           ic.IfStatus,
+		InOctets:           ic.InOctets,
+		InUcastPkts:        ic.InUcastPkts,
+		InMulticastPkts:    ic.InMulticastPkts,
+		InBroadcastPkts:    ic.InBroadcastPkts,
+		InDiscards:         ic.InDiscards,
+		InErrors:           ic.InErrors,
+		InUnknownProtos:    ic.InUnknownProtos,
+		OutOctets:          ic.OutOctets,
+		OutUcastPkts:       ic.OutUcastPkts,
+		OutMulticastPkts:   ic.OutMulticastPkts,
+		OutBroadcastPkts:   ic.OutBroadcastPkts,
+		OutDiscards:        ic.OutDiscards,
+		OutErrors:          ic.OutErrors,
+		AlignmentErrors:    ic.AlignmentErrors,
+		FCSErrors:          ic.FCSErrors,
+		SingleCollisions:   ic.SingleCollisions,
+		MultipleCollisions: ic.MultipleCollisions,
+		LateCollisions:     ic.LateCollisions,
+		FrameTooLongs:      ic.FrameTooLongs,
+		SymbolErrors:       ic.SymbolErrors,
+	}
+}
+
+func (r *counterRecord) toInterfaceCounters() (*counters.InterfaceCounters, error) {
+	agent, err := r.Agent.toIP()
+	if err != nil {
+		return nil, err
+	}
+
+	return &counters.InterfaceCounters{
+		Agent:              agent,
+		Timestamp:          r.Timestamp,
+		IfIndex:            r.IfIndex,
+		IntName:            r.IntName,
+		IfType:             r.IfType,
+		IfSpeed:            r.IfSpeed,
+		IfDirection:        r.IfDirection,
+		IfStatus:           r.IfStatus,
+		InOctets:           r.InOctets,
+		InUcastPkts:        r.InUcastPkts,
+		InMulticastPkts:    r.InMulticastPkts,
+		InBroadcastPkts:    r.InBroadcastPkts,
+		InDiscards:         r.InDiscards,
+		InErrors:           r.InErrors,
+		InUnknownProtos:    r.InUnknownProtos,
+		OutOctets:          r.OutOctets,
+		OutUcastPkts:       r.OutUcastPkts,
+		OutMulticastPkts:   r.OutMulticastPkts,
+		OutBroadcastPkts:   r.OutBroadcastPkts,
+		OutDiscards:        r.OutDiscards,
+		OutErrors:          r.OutErrors,
+		AlignmentErrors:    r.AlignmentErrors,
+		FCSErrors:          r.FCSErrors,
+		SingleCollisions:   r.SingleCollisions,
+		MultipleCollisions: r.MultipleCollisions,
+		LateCollisions:     r.LateCollisions,
+		FrameTooLongs:      r.FrameTooLongs,
+		SymbolErrors:       r.SymbolErrors,
+	}, nil
+}
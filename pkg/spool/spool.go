@@ -0,0 +1,419 @@
+// Package spool buffers batches of flows and interface counters on disk while they can not be inserted into Clickhouse
+package spool
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const fileSuffix = ".spool"
+
+// tmpSuffix marks batches that are still being written
+const tmpSuffix = ".tmp"
+
+// Reasons for dropping spooled batches
+const (
+	reasonSize    = "size"
+	reasonAge     = "age"
+	reasonCorrupt = "corrupt"
+)
+
+// Config is the configuration of a `Spool`
+type Config struct {
+	// Dir is the directory batches are written to
+	Dir string
+
+	// MaxBytes is the maximum size of all spooled batches. The oldest batches are dropped once it's exceeded.
+	// 0 disables the limit.
+	MaxBytes int64
+
+	// MaxAge is the time after which spooled batches are dropped. 0 disables the limit.
+	MaxAge time.Duration
+}
+
+type file struct {
+	seq     uint64
+	size    int64
+	created time.Time
+}
+
+// Spool is an on disk FIFO queue of batches of flows or interface counters. Each batch is stored in its own file.
+type Spool struct {
+	cfg     Config
+	files   []*file
+	bytes   int64
+	nextSeq uint64
+	mu      sync.Mutex
+
+	bytesGauge       prometheus.Gauge
+	batchesGauge     prometheus.Gauge
+	spooledBatches   prometheus.Counter
+	replayedBatches  prometheus.Counter
+	replayedFlows    prometheus.Counter
+	replayedCounters prometheus.Counter
+	droppedBatches   *prometheus.CounterVec
+}
+
+// New creates a spool and restores the batches left in cfg.Dir
+func New(cfg Config, reg prometheus.Registerer) (*Spool, error) {
+	f := promauto.With(reg)
+	s := &Spool{
+		cfg: cfg,
+		bytesGauge: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "bytes",
+			Help:      "Size of the spooled batches",
+		}),
+		batchesGauge: f.NewGauge(prometheus.GaugeOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "batches",
+			Help:      "Number of spooled batches",
+		}),
+		spooledBatches: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "spooled_batches",
+			Help:      "Batches written to the spool",
+		}),
+		replayedBatches: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "replayed_batches",
+			Help:      "Spooled batches inserted successfully",
+		}),
+		replayedFlows: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "replayed_flows",
+			Help:      "Spooled flows inserted successfully",
+		}),
+		replayedCounters: f.NewCounter(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "replayed_interface_counters",
+			Help:      "Spooled interface counters inserted successfully",
+		}),
+		droppedBatches: f.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "flowhouse",
+			Subsystem: "spool",
+			Name:      "dropped_batches",
+			Help:      "Spooled batches dropped due to size or age limits or corruption",
+		}, []string{"reason"}),
+	}
+
+	err := os.MkdirAll(cfg.Dir, 0o750)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create spool directory")
+	}
+
+	err = s.restore()
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to restore spool")
+	}
+
+	if len(s.files) > 0 {
+		log.WithFields(log.Fields{
+			"batches": len(s.files),
+			"bytes":   s.bytes,
+		}).Info("Restored spooled batches")
+	}
+
+	return s, nil
+}
+
+// restore reads the list of spooled batches from disk. Batches left incomplete by a crash are removed.
+func (s *Spool) restore() error {
+	entries, err := os.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return errors.Wrap(err, "ReadDir failed")
+	}
+
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), fileSuffix+tmpSuffix) {
+			err := os.Remove(filepath.Join(s.cfg.Dir, e.Name()))
+			if err != nil {
+				return errors.Wrapf(err, "Unable to remove incomplete batch %q", e.Name())
+			}
+
+			continue
+		}
+
+		if e.IsDir() || !strings.HasSuffix(e.Name(), fileSuffix) {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(e.Name(), fileSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return errors.Wrapf(err, "Unable to stat %q", e.Name())
+		}
+
+		s.files = append(s.files, &file{
+			seq:     seq,
+			size:    info.Size(),
+			created: info.ModTime(),
+		})
+		s.bytes += info.Size()
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+
+	sort.Slice(s.files, func(i, j int) bool {
+		return s.files[i].seq < s.files[j].seq
+	})
+
+	s.updateGauges()
+	return nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%s", seq, fileSuffix))
+}
+
+func (s *Spool) updateGauges() {
+	s.bytesGauge.Set(float64(s.bytes))
+	s.batchesGauge.Set(float64(len(s.files)))
+}
+
+// Empty checks if there are no spooled batches
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.files) == 0
+}
+
+// Write appends a batch of flows to the spool
+func (s *Spool) Write(flows []*flow.Flow) error {
+	b := &batch{
+		Flows: make([]*record, 0, len(flows)),
+	}
+
+	for _, fl := range flows {
+		b.Flows = append(b.Flows, newRecord(fl))
+	}
+
+	return s.write(b)
+}
+
+// WriteCounters appends a batch of interface counters to the spool
+func (s *Spool) WriteCounters(ifCounters []*counters.InterfaceCounters) error {
+	b := &batch{
+		Counters: make([]*counterRecord, 0, len(ifCounters)),
+	}
+
+	for _, ic := range ifCounters {
+		b.Counters = append(b.Counters, newCounterRecord(ic))
+	}
+
+	return s.write(b)
+}
+
+func (s *Spool) write(b *batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seq := s.nextSeq
+	p := s.path(seq)
+	tmp := p + tmpSuffix
+
+	fh, err := os.Create(tmp)
+	if err != nil {
+		return errors.Wrap(err, "Unable to create file")
+	}
+
+	err = gob.NewEncoder(fh).Encode(b)
+	if err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "Unable to encode batch")
+	}
+
+	info, err := fh.Stat()
+	if err != nil {
+		fh.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "Stat failed")
+	}
+
+	err = fh.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "Close failed")
+	}
+
+	// Renaming makes sure only complete batches are replayed after a crash
+	err = os.Rename(tmp, p)
+	if err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "Rename failed")
+	}
+
+	s.nextSeq++
+	s.files = append(s.files, &file{
+		seq:     seq,
+		size:    info.Size(),
+		created: time.Now(),
+	})
+	s.bytes += info.Size()
+	s.spooledBatches.Inc()
+
+	s.expire(time.Now())
+	s.enforceMaxBytes()
+	s.updateGauges()
+	return nil
+}
+
+// enforceMaxBytes drops the oldest batches until the spool is within its size limit
+func (s *Spool) enforceMaxBytes() {
+	for s.cfg.MaxBytes > 0 && s.bytes > s.cfg.MaxBytes && len(s.files) > 0 {
+		s.drop(reasonSize)
+	}
+}
+
+// expire drops batches older than MaxAge
+func (s *Spool) expire(now time.Time) {
+	for s.cfg.MaxAge > 0 && len(s.files) > 0 && now.Sub(s.files[0].created) > s.cfg.MaxAge {
+		s.drop(reasonAge)
+	}
+}
+
+// drop removes the oldest batch
+func (s *Spool) drop(reason string) {
+	f := s.files[0]
+	log.WithFields(log.Fields{
+		"file":   s.path(f.seq),
+		"reason": reason,
+	}).Warning("Dropping spooled batch")
+
+	s.droppedBatches.WithLabelValues(reason).Inc()
+	s.remove()
+}
+
+// remove deletes the oldest batch
+func (s *Spool) remove() {
+	f := s.files[0]
+	err := os.Remove(s.path(f.seq))
+	if err != nil && !os.IsNotExist(err) {
+		log.WithError(err).Error("Unable to remove spooled batch")
+	}
+
+	s.files = s.files[1:]
+	s.bytes -= f.size
+}
+
+func (s *Spool) read(f *file) ([]*flow.Flow, []*counters.InterfaceCounters, error) {
+	fh, err := os.Open(s.path(f.seq))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to open file")
+	}
+	defer fh.Close()
+
+	var b batch
+	err = gob.NewDecoder(fh).Decode(&b)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Unable to decode batch")
+	}
+
+	flows := make([]*flow.Flow, 0, len(b.Flows))
+	for _, r := range b.Flows {
+		fl, err := r.toFlow()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to convert record")
+		}
+
+		flows = append(flows, fl)
+	}
+
+	ifCounters := make([]*counters.InterfaceCounters, 0, len(b.Counters))
+	for _, r := range b.Counters {
+		ic, err := r.toInterfaceCounters()
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Unable to convert counter record")
+		}
+
+		ifCounters = append(ifCounters, ic)
+	}
+
+	return flows, ifCounters, nil
+}
+
+// Replay inserts the spooled batches in the order they were written using insertFlows and
+// insertCounters. Batches are removed once inserted. Replaying stops at the first failed insert.
+// Inserts run without holding the lock, so batches can be written meanwhile. Replay must not
+// be called concurrently.
+func (s *Spool) Replay(insertFlows func(flows []*flow.Flow) error, insertCounters func(ifCounters []*counters.InterfaceCounters) error) error {
+	for {
+		f, flows, ifCounters := s.next()
+		if f == nil {
+			return nil
+		}
+
+		var err error
+		if len(flows) > 0 {
+			err = insertFlows(flows)
+		} else if len(ifCounters) > 0 {
+			err = insertCounters(ifCounters)
+		}
+
+		if err != nil {
+			return errors.Wrap(err, "Insert failed")
+		}
+
+		s.mu.Lock()
+		// The batch may have been dropped due to the size limit during the insert
+		if len(s.files) > 0 && s.files[0] == f {
+			s.remove()
+		}
+
+		s.replayedBatches.Inc()
+		s.replayedFlows.Add(float64(len(flows)))
+		s.replayedCounters.Add(float64(len(ifCounters)))
+		s.updateGauges()
+		s.mu.Unlock()
+	}
+}
+
+// next reads the oldest batch. Expired and corrupt batches are dropped. Returns a nil file if the spool is empty.
+func (s *Spool) next() (*file, []*flow.Flow, []*counters.InterfaceCounters) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.updateGauges()
+
+	s.expire(time.Now())
+	for len(s.files) > 0 {
+		f := s.files[0]
+		flows, ifCounters, err := s.read(f)
+		if err != nil {
+			log.WithError(err).Error("Unable to read spooled batch")
+			s.drop(reasonCorrupt)
+			continue
+		}
+
+		return f, flows, ifCounters
+	}
+
+	return nil, nil, nil
+}
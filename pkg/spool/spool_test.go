@@ -0,0 +1,271 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bio-routing/flowhouse/pkg/models/counters"
+	"github.com/bio-routing/flowhouse/pkg/models/flow"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	bnet "github.com/bio-routing/bio-rd/net"
+)
+
+func newTestSpool(t *testing.T, cfg Config) *Spool {
+	s, err := New(cfg, prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("Unable to create spool: %v", err)
+	}
+
+	return s
+}
+
+func noCounters(ifCounters []*counters.InterfaceCounters) error {
+	return errors.New("Unexpected interface counters")
+}
+
+func TestRecordRoundTrip(t *testing.T) {
+	fl := &flow.Flow{
+		Agent:           bnet.IPv4FromOctets(192, 0, 2, 1),
+		SubAgentID:      1,
+		TOS:             2,
+		SrcPort:         3,
+		DstPort:         4,
+		SrcAs:           5,
+		DstAs:           6,
+		NextAs:          7,
+		IntIn:           "et-0/0/0",
+		IntOut:          "et-0/0/1",
+		VLANIn:          8,
+		VLANOut:         9,
+		VLANOuter:       10,
+		VLANInner:       11,
+		Packets:         12,
+		Protocol:        13,
+		TCPFlags:        14,
+		ICMPType:        15,
+		ICMPCode:        16,
+		Fragment:        true,
+		Family:          6,
+		Timestamp:       1700000000,
+		Size:            17,
+		Samplerate:      18,
+		SrcAddr:         bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 1),
+		DstAddr:         bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 2),
+		NextHop:         bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 3),
+		SrcPfx:          bnet.NewPfx(bnet.IPv6FromBlocks(0x2001, 0xdb8, 0, 0, 0, 0, 0, 0), 48),
+		DstPfx:          bnet.NewPfx(bnet.IPv4FromOctets(198, 51, 100, 0), 24),
+		VRFIn:           19,
+		VRFOut:          20,
+		Application:     "http",
+		VRFNameIn:       "red",
+		VRFNameOut:      "blue",
+		ASPath:          []uint32{21, 22},
+		Communities:     []uint32{23},
+		MPLSTopLabel:    24,
+		MPLSBottomLabel: 25,
+		TunnelType:      flow.TunnelTypeVXLAN,
+		TunnelKey:       26,
+		Outer: flow.FiveTuple{
+			SrcAddr:  bnet.IPv4FromOctets(203, 0, 113, 1),
+			DstAddr:  bnet.IPv4FromOctets(203, 0, 113, 2),
+			SrcPort:  27,
+			DstPort:  4789,
+			Protocol: 17,
+		},
+		Inner: flow.FiveTuple{
+			SrcAddr:  bnet.IPv4FromOctets(10, 0, 0, 1),
+			DstAddr:  bnet.IPv4FromOctets(10, 0, 0, 2),
+			SrcPort:  28,
+			DstPort:  29,
+			Protocol: 6,
+		},
+		LossRatio: 0.5,
+	}
+
+	for _, f := range []*flow.Flow{fl, {}} {
+		ret, err := newRecord(f).toFlow()
+		assert.NoError(t, err)
+		assert.Equal(t, f, ret)
+	}
+}
+
+func TestCounterRecordRoundTrip(t *testing.T) {
+	ic := &counters.InterfaceCounters{
+		Agent:              bnet.IPv4FromOctets(192, 0, 2, 1),
+		Timestamp:          1700000000,
+		IfIndex:            1,
+		IntName:            "et-0/0/0",
+		IfType:             2,
+		IfSpeed:            3,
+		IfDirection:        4,
+		IfStatus:           5,
+		InOctets:           6,
+		InUcastPkts:        7,
+		InMulticastPkts:    8,
+		InBroadcastPkts:    9,
+		InDiscards:         10,
+		InErrors:           11,
+		InUnknownProtos:    12,
+		OutOctets:          13,
+		OutUcastPkts:       14,
+		OutMulticastPkts:   15,
+		OutBroadcastPkts:   16,
+		OutDiscards:        17,
+		OutErrors:          18,
+		AlignmentErrors:    19,
+		FCSErrors:          20,
+		SingleCollisions:   21,
+		MultipleCollisions: 22,
+		LateCollisions:     23,
+		FrameTooLongs:      24,
+		SymbolErrors:       25,
+	}
+
+	for _, c := range []*counters.InterfaceCounters{ic, {}} {
+		ret, err := newCounterRecord(c).toInterfaceCounters()
+		assert.NoError(t, err)
+		assert.Equal(t, c, ret)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSpool(t, Config{Dir: dir})
+
+	for i := int64(1); i <= 3; i++ {
+		err := s.Write([]*flow.Flow{{Timestamp: i}, {Timestamp: i}})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, float64(3), testutil.ToFloat64(s.batchesGauge))
+
+	// Batches are restored after a restart
+	s = newTestSpool(t, Config{Dir: dir})
+	assert.Len(t, s.files, 3)
+
+	var inserted []int64
+	fail := false
+	insert := func(flows []*flow.Flow) error {
+		if fail {
+			return errors.New("Connection refused")
+		}
+
+		inserted = append(inserted, flows[0].Timestamp)
+		fail = len(inserted) == 2
+		return nil
+	}
+
+	// Replay stops at the first failed insert
+	assert.Error(t, s.Replay(insert, noCounters))
+	assert.Equal(t, []int64{1, 2}, inserted)
+	assert.False(t, s.Empty())
+
+	fail = false
+	assert.NoError(t, s.Replay(insert, noCounters))
+	assert.Equal(t, []int64{1, 2, 3}, inserted)
+	assert.True(t, s.Empty())
+	assert.Equal(t, float64(3), testutil.ToFloat64(s.replayedBatches))
+	assert.Equal(t, float64(6), testutil.ToFloat64(s.replayedFlows))
+	assert.Equal(t, float64(0), testutil.ToFloat64(s.bytesGauge))
+}
+
+func TestWriteDuringReplay(t *testing.T) {
+	s := newTestSpool(t, Config{Dir: t.TempDir()})
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 1}}))
+
+	// Batches can be written while a spooled batch is being inserted
+	var inserted []int64
+	assert.NoError(t, s.Replay(func(flows []*flow.Flow) error {
+		inserted = append(inserted, flows[0].Timestamp)
+		if len(inserted) == 1 {
+			return s.Write([]*flow.Flow{{Timestamp: 2}})
+		}
+
+		return nil
+	}, noCounters))
+	assert.Equal(t, []int64{1, 2}, inserted)
+	assert.True(t, s.Empty())
+}
+
+func TestLimits(t *testing.T) {
+	s := newTestSpool(t, Config{Dir: t.TempDir()})
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 1}}))
+	size := s.bytes
+
+	// The oldest batches are dropped once the size limit is exceeded
+	s.cfg.MaxBytes = 2 * size
+	for i := int64(2); i <= 4; i++ {
+		assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: i}}))
+	}
+	assert.Len(t, s.files, 2)
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.droppedBatches.WithLabelValues(reasonSize)))
+
+	// Batches exceeding the age limit are dropped
+	s.cfg.MaxAge = time.Hour
+	s.files[0].created = time.Now().Add(-2 * time.Hour)
+
+	var inserted []int64
+	assert.NoError(t, s.Replay(func(flows []*flow.Flow) error {
+		inserted = append(inserted, flows[0].Timestamp)
+		return nil
+	}, noCounters))
+	assert.Equal(t, []int64{4}, inserted)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.droppedBatches.WithLabelValues(reasonAge)))
+}
+
+func TestCorruptBatch(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "00000000000000000001.spool"), []byte("garbage"), 0o640)
+	assert.NoError(t, err)
+
+	s := newTestSpool(t, Config{Dir: dir})
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 2}}))
+
+	var inserted []int64
+	assert.NoError(t, s.Replay(func(flows []*flow.Flow) error {
+		inserted = append(inserted, flows[0].Timestamp)
+		return nil
+	}, noCounters))
+	assert.Equal(t, []int64{2}, inserted)
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.droppedBatches.WithLabelValues(reasonCorrupt)))
+}
+
+func TestRestoreRemovesIncompleteBatches(t *testing.T) {
+	dir := t.TempDir()
+	s := newTestSpool(t, Config{Dir: dir})
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 1}}))
+
+	tmp := filepath.Join(dir, "00000000000000000002.spool.tmp")
+	err := os.WriteFile(tmp, []byte("partial"), 0o640)
+	assert.NoError(t, err)
+
+	s = newTestSpool(t, Config{Dir: dir})
+	assert.Len(t, s.files, 1)
+	_, err = os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReplayCounters(t *testing.T) {
+	s := newTestSpool(t, Config{Dir: t.TempDir()})
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 1}}))
+	assert.NoError(t, s.WriteCounters([]*counters.InterfaceCounters{{Timestamp: 2}, {Timestamp: 2}}))
+	assert.NoError(t, s.Write([]*flow.Flow{{Timestamp: 3}}))
+
+	var inserted []int64
+	assert.NoError(t, s.Replay(func(flows []*flow.Flow) error {
+		inserted = append(inserted, flows[0].Timestamp)
+		return nil
+	}, func(ifCounters []*counters.InterfaceCounters) error {
+		inserted = append(inserted, ifCounters[0].Timestamp)
+		return nil
+	}))
+
+	assert.Equal(t, []int64{1, 2, 3}, inserted)
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.replayedFlows))
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.replayedCounters))
+}